@@ -6,6 +6,40 @@ import (
 
 const (
 	CNOEURIScheme = "cnoe://"
+
+	// ProjectAnnotation on an application or application set specifies the ArgoCD project to generate for it.
+	// Packages with the same value share a project. Defaults to the name of the application.
+	ProjectAnnotation = "cnoe.io/project"
+	// ProjectNamespacesAnnotation lists additional namespaces, separated by commas, the package deploys to.
+	// It is required when the destination namespace is templated, and then lists the only namespaces permitted.
+	ProjectNamespacesAnnotation = "cnoe.io/project-namespaces"
+	// ProjectClustersAnnotation lists additional clusters, separated by commas, the package deploys to. Values containing
+	// :// are server URLs, others are cluster names. It is required when the destination cluster is templated, and then
+	// lists the only clusters permitted.
+	ProjectClustersAnnotation = "cnoe.io/project-clusters"
+	// ProjectClusterResourcesAnnotation lists cluster scoped kinds, separated by commas, the package deploys. e.g. rbac.authorization.k8s.io/ClusterRole
+	ProjectClusterResourcesAnnotation = "cnoe.io/project-cluster-resources"
+	// SecretsAnnotation on an application or application set lists, in YAML, the secrets idpbuilder generates for the package
+	// before the application is created. Existing secrets are left untouched. e.g.
+	//   - name: db-credentials
+	//     type: password
+	//   - name: my-app-tls
+	//     type: tls
+	//     dnsNames: [my-app.cnoe.localtest.me]
+	SecretsAnnotation = "cnoe.io/secrets"
+	// ImagesAnnotation on an application or application set lists, in YAML, the container images idpbuilder builds
+	// with the container runtime and pushes to the Gitea registry. Image fields referencing an image by name in the
+	// manifests of local sources are replaced with the pushed image digest. Contexts are relative to the application file. e.g.
+	//   - name: my-app
+	//     context: ../src
+	//     dockerfile: Dockerfile
+	// Only applies to packages in local directories. References are not replaced when history is mirrored.
+	ImagesAnnotation = "cnoe.io/images"
+
+	PackageSecretTypePassword = "password"
+	// PackageSecretTypeTLS secrets hold a certificate signed by the idpbuilder certificate authority.
+	PackageSecretTypeTLS = "tls"
+	PackageSecretTypeSSH = "ssh"
 )
 
 // +kubebuilder:object:root=true
@@ -32,6 +66,9 @@ type CustomPackageSpec struct {
 	// for example, https://gitea.cnoe.localtest.me:8443
 	GitServerURL           string          `json:"gitServerURL"`
 	GitServerAuthSecretRef SecretReference `json:"gitServerAuthSecretRef"`
+	// GitServerOrganization owns the repositories created in the git server. Defaults to the Gitea admin user.
+	// +kubebuilder:validation:Optional
+	GitServerOrganization string `json:"gitServerOrganization,omitempty"`
 	// InternalGitServeURL specifies the base URL for the git server accessible within the cluster.
 	// for example, http://my-gitea-http.gitea.svc.cluster.local:3000
 	InternalGitServeURL string               `json:"internalGitServeURL"`
@@ -39,6 +76,19 @@ type CustomPackageSpec struct {
 	// Replicate specifies whether to replicate remote or local contents to the local gitea server.
 	// +kubebuilder:default:=false
 	Replicate bool `json:"replicate"`
+	// MirrorHistory specifies whether to push the git history of local directories instead of a snapshot.
+	// +kubebuilder:validation:Optional
+	MirrorHistory bool `json:"mirrorHistory,omitempty"`
+	// Branch is the branch in the local git server to push contents to. See GitRepositorySpec for supported template fields.
+	// +kubebuilder:validation:Optional
+	Branch string `json:"branch,omitempty"`
+	// ScopedProject specifies whether to deploy the package with an ArgoCD project that only permits its own repositories,
+	// destinations, and declared cluster scoped kinds.
+	// +kubebuilder:validation:Optional
+	ScopedProject bool `json:"scopedProject,omitempty"`
+	// SyncPolicy replaces the sync policy of the application when set.
+	// +kubebuilder:validation:Optional
+	SyncPolicy *SyncPolicySpec `json:"syncPolicy,omitempty"`
 }
 
 // RemoteRepositorySpec specifies information about remote repositories.
@@ -65,6 +115,35 @@ type CustomPackageStatus struct {
 	// This only applies for a package that references local directories
 	Synced            bool        `json:"synced,omitempty"`
 	GitRepositoryRefs []ObjectRef `json:"gitRepositoryRefs,omitempty"`
+	// Project is what the package requires from its ArgoCD project. Set only when ScopedProject is true.
+	Project *ProjectPermissions `json:"project,omitempty"`
+	// Images are the images built for the package.
+	Images []BuiltImage `json:"images,omitempty"`
+}
+
+type BuiltImage struct {
+	// Name of the image in the ImagesAnnotation.
+	Name string `json:"name"`
+	// Image is the pushed image referenced by digest.
+	Image string `json:"image"`
+	// ContextHash is the hash of the build context the image was built from. Images are rebuilt when it changes.
+	ContextHash string `json:"contextHash"`
+}
+
+// ProjectPermissions is what is permitted in an ArgoCD project.
+type ProjectPermissions struct {
+	Name string `json:"name"`
+	// Namespace of the project. Defaults to the ArgoCD namespace.
+	Namespace        string               `json:"namespace,omitempty"`
+	SourceRepos      []string             `json:"sourceRepos,omitempty"`
+	Destinations     []ProjectDestination `json:"destinations,omitempty"`
+	ClusterResources []metav1.GroupKind   `json:"clusterResources,omitempty"`
+}
+
+type ProjectDestination struct {
+	Server    string `json:"server,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
 }
 
 type ObjectRef struct {
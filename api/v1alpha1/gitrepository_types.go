@@ -21,6 +21,32 @@ type GitRepositorySpec struct {
 	SecretRef SecretReference     `json:"secretRef"`
 	Source    GitRepositorySource `json:"source,omitempty"`
 	Provider  Provider            `json:"provider"`
+	// Branch is the branch to push contents to. Defaults to main.
+	// It may be a Go template with the following fields.
+	// .User: name of the user running idpbuilder. .SourceBranch: branch of the source directory or ref of the remote repository.
+	// for example, {{ .User }}/{{ .SourceBranch }}
+	// +kubebuilder:validation:Optional
+	Branch string `json:"branch,omitempty"`
+	// Repository configures the repository created in the git server. It has no effect on existing repositories.
+	// +kubebuilder:validation:Optional
+	Repository RepositorySettings `json:"repository,omitempty"`
+}
+
+type RepositorySettings struct {
+	// Name of the repository. Defaults to <namespace>-<name> of the GitRepository.
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
+	// Private specifies whether the repository is private. Repositories are public by default.
+	// ArgoCD is given the credentials in SecretRef to fetch private repositories.
+	// +kubebuilder:validation:Optional
+	Private bool `json:"private,omitempty"`
+	// +kubebuilder:validation:Optional
+	Description string `json:"description,omitempty"`
+	// +kubebuilder:validation:Optional
+	Topics []string `json:"topics,omitempty"`
+	// DefaultBranch of the repository. Contents are pushed to it when Branch is not set. Defaults to main.
+	// +kubebuilder:validation:Optional
+	DefaultBranch string `json:"defaultBranch,omitempty"`
 }
 
 type GitRepositorySource struct {
@@ -36,6 +62,20 @@ type GitRepositorySource struct {
 	// +kubebuilder:validation:Enum:=local;embedded;remote
 	// +kubebuilder:default:=embedded
 	Type string `json:"type"`
+	// MirrorHistory specifies whether to push commits that touched Path instead of a snapshot of Path.
+	// Only applies when Type is set to local and Path is in a git repository.
+	// Uncommitted changes and image replacements are pushed as a commit on top of the history.
+	// +kubebuilder:validation:Optional
+	MirrorHistory bool `json:"mirrorHistory,omitempty"`
+	// Images replaces image references in the manifests of local sources.
+	// +kubebuilder:validation:Optional
+	Images []ImageReplacement `json:"images,omitempty"`
+}
+
+// ImageReplacement replaces the values of image fields referencing Name, with or without a tag or digest, with Image.
+type ImageReplacement struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
 }
 
 type Provider struct {
@@ -47,7 +87,8 @@ type Provider struct {
 	// +kubebuilder:validation:Pattern=`^https?:\/\/.+$`
 	GitURL string `json:"gitURL"`
 	// InternalGitURL is the base URL of Git server accessible within the cluster only.
-	InternalGitURL   string `json:"internalGitURL"`
+	InternalGitURL string `json:"internalGitURL"`
+	// OrganizationName is the owner of the repository. For gitea, an organization is created if it is not the authenticated user.
 	OrganizationName string `json:"organizationName"`
 }
 
@@ -76,6 +117,9 @@ type GitRepositoryStatus struct {
 	// +kubebuilder:validation:Optional
 	Path   string `json:"path"`
 	Synced bool   `json:"synced"`
+	// Branch is the branch contents were pushed to.
+	// +kubebuilder:validation:Optional
+	Branch string `json:"branch,omitempty"`
 }
 
 // +kubebuilder:object:root=true
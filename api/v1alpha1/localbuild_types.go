@@ -17,6 +17,11 @@ const (
 	CLISecretLabelKey   = "cnoe.io/cli-secret"
 	CLISecretLabelValue = "true"
 	PackageNameLabelKey = "cnoe.io/package-name"
+	// BuildNameLabelKey is set on nodes of extra clusters created for a build. The value is the name of the build.
+	BuildNameLabelKey = "cnoe.io/build-name"
+	// CreatedByLabelKey is set on nodes of clusters idpbuilder creates. delete --all deletes clusters with this label.
+	CreatedByLabelKey   = "cnoe.io/created-by"
+	CreatedByLabelValue = "idpbuilder"
 
 	ArgoCDPackageName       = "argocd"
 	GiteaPackageName        = "gitea"
@@ -43,6 +48,182 @@ type PackageConfigsSpec struct {
 	CustomPackageUrls        []string                                  `json:"customPackageUrls,omitempty"`
 	// +kubebuilder:validation:Optional
 	CorePackageCustomization map[string]PackageCustomization `json:"packageCustomization,omitempty"`
+	// MirrorHistory specifies whether to push the git history of local custom packages instead of a snapshot.
+	// +kubebuilder:validation:Optional
+	MirrorHistory bool `json:"mirrorHistory,omitempty"`
+	// PackageBranch is the branch in the local git server that custom packages are pushed to and tracked from.
+	// +kubebuilder:validation:Optional
+	PackageBranch string `json:"packageBranch,omitempty"`
+	// ScopedProjects specifies whether to generate an ArgoCD project for each custom package instead of using the default project.
+	// +kubebuilder:validation:Optional
+	ScopedProjects bool `json:"scopedProjects,omitempty"`
+	// SyncPolicy is the sync policy for embedded applications and custom packages that do not specify one.
+	// +kubebuilder:validation:Optional
+	SyncPolicy *SyncPolicySpec `json:"syncPolicy,omitempty"`
+	// PackageSyncPolicies are sync policies keyed by application name. They take precedence over SyncPolicy and
+	// over the sync policy in custom package files.
+	// +kubebuilder:validation:Optional
+	PackageSyncPolicies map[string]SyncPolicySpec `json:"packageSyncPolicies,omitempty"`
+	// Accounts configures users of the core packages.
+	// +kubebuilder:validation:Optional
+	Accounts AccountsSpec `json:"accounts,omitempty"`
+}
+
+// GetSyncPolicy returns the sync policy for the named application. nil is returned if none is configured.
+func (p *PackageConfigsSpec) GetSyncPolicy(appName string) *SyncPolicySpec {
+	if s, ok := p.PackageSyncPolicies[appName]; ok {
+		return &s
+	}
+	return p.SyncPolicy
+}
+
+// SyncPolicySpec controls how ArgoCD syncs an application.
+type SyncPolicySpec struct {
+	// Manual disables automated sync. Prune and SelfHeal have no effect when set.
+	// +kubebuilder:validation:Optional
+	Manual bool `json:"manual,omitempty"`
+	// Prune specifies whether to delete resources that are no longer in git.
+	// +kubebuilder:validation:Optional
+	Prune bool `json:"prune,omitempty"`
+	// SelfHeal specifies whether to revert changes made in the cluster. Defaults to true.
+	// +kubebuilder:validation:Optional
+	SelfHeal *bool `json:"selfHeal,omitempty"`
+	// ServerSideApply specifies whether to apply resources with server side apply.
+	// +kubebuilder:validation:Optional
+	ServerSideApply bool `json:"serverSideApply,omitempty"`
+	// +kubebuilder:validation:Optional
+	Retry *SyncRetrySpec `json:"retry,omitempty"`
+	// IgnoreDifferences lists fields ArgoCD ignores when comparing and syncing resources.
+	// Useful for fields managed by operators in the cluster.
+	// +kubebuilder:validation:Optional
+	IgnoreDifferences []IgnoreDifference `json:"ignoreDifferences,omitempty"`
+}
+
+type SyncRetrySpec struct {
+	// Limit is the maximum number of attempts. A negative number means no limit.
+	Limit int64 `json:"limit,omitempty"`
+	// Backoff is the wait between attempts, e.g. 5s. It is multiplied by BackoffFactor on each attempt up to MaxBackoff.
+	Backoff       string `json:"backoff,omitempty"`
+	BackoffFactor *int64 `json:"backoffFactor,omitempty"`
+	MaxBackoff    string `json:"maxBackoff,omitempty"`
+}
+
+// IgnoreDifference selects resources and fields to ignore. See ArgoCD's ResourceIgnoreDifferences.
+type IgnoreDifference struct {
+	Group                 string   `json:"group,omitempty"`
+	Kind                  string   `json:"kind"`
+	Name                  string   `json:"name,omitempty"`
+	Namespace             string   `json:"namespace,omitempty"`
+	JSONPointers          []string `json:"jsonPointers,omitempty"`
+	JQPathExpressions     []string `json:"jqPathExpressions,omitempty"`
+	ManagedFieldsManagers []string `json:"managedFieldsManagers,omitempty"`
+}
+
+// Keys of the admin passwords in the secret referenced by AccountsSpec.SecretRef.
+const (
+	GiteaAdminPasswordKey  = "gitea.admin"
+	ArgoCDAdminPasswordKey = "argocd.admin"
+)
+
+// GiteaUserPasswordKey returns the key of the password of a Gitea user in the secret referenced by AccountsSpec.SecretRef.
+func GiteaUserPasswordKey(username string) string {
+	return "gitea.users." + username
+}
+
+// ArgoCDUserPasswordKey returns the key of the password of an ArgoCD user in the secret referenced by AccountsSpec.SecretRef.
+func ArgoCDUserPasswordKey(username string) string {
+	return "argocd.users." + username
+}
+
+// SSOUserPasswordKey returns the key of the password of an SSO user in the secret referenced by AccountsSpec.SecretRef.
+func SSOUserPasswordKey(username string) string {
+	return "sso.users." + username
+}
+
+// AccountsSpec configures users of Gitea and ArgoCD. Passwords are stored in the secret referenced by SecretRef.
+type AccountsSpec struct {
+	// SecretRef is the secret holding the passwords of the accounts.
+	// +kubebuilder:validation:Optional
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+	// +kubebuilder:validation:Optional
+	Gitea GiteaAccountsSpec `json:"gitea,omitempty"`
+	// +kubebuilder:validation:Optional
+	ArgoCD ArgoCDAccountsSpec `json:"argocd,omitempty"`
+	// +kubebuilder:validation:Optional
+	SSO SSOAccountsSpec `json:"sso,omitempty"`
+}
+
+// GiteaAccountsSpec configures users, organizations and teams of Gitea. Missing ones are created. Existing ones are not modified.
+type GiteaAccountsSpec struct {
+	// Admin is the admin user. It and its password are used when Gitea is first installed.
+	// Repositories created by idpbuilder are owned by the admin user. A random password is generated when none is set.
+	// +kubebuilder:validation:Optional
+	Admin AccountCredential `json:"admin,omitempty"`
+	// +kubebuilder:validation:Optional
+	Users []GiteaUser `json:"users,omitempty"`
+	// +kubebuilder:validation:Optional
+	Organizations []GiteaOrganization `json:"organizations,omitempty"`
+}
+
+type AccountCredential struct {
+	Username string `json:"username,omitempty"`
+}
+
+type GiteaUser struct {
+	Username string `json:"username"`
+	// Email defaults to <username>@<host>.
+	// +kubebuilder:validation:Optional
+	Email string `json:"email,omitempty"`
+	// Admin specifies whether the user is a site administrator.
+	// +kubebuilder:validation:Optional
+	Admin bool `json:"admin,omitempty"`
+}
+
+type GiteaOrganization struct {
+	Name string `json:"name"`
+	// +kubebuilder:validation:Optional
+	Teams []GiteaTeam `json:"teams,omitempty"`
+}
+
+type GiteaTeam struct {
+	Name string `json:"name"`
+	// Permission of the team on repositories of the organization. read, write or admin. Defaults to read.
+	// +kubebuilder:validation:Optional
+	Permission string `json:"permission,omitempty"`
+	// Members are usernames added to the team.
+	// +kubebuilder:validation:Optional
+	Members []string `json:"members,omitempty"`
+}
+
+// ArgoCDAccountsSpec configures local users of ArgoCD. The admin password is set once when ArgoCD is installed,
+// like the Gitea admin password, so rotated passwords are kept. The password generated by ArgoCD is kept when none is set.
+type ArgoCDAccountsSpec struct {
+	// +kubebuilder:validation:Optional
+	Users []ArgoCDUser `json:"users,omitempty"`
+}
+
+type ArgoCDUser struct {
+	Username string `json:"username"`
+	// Role assigned to the user in ArgoCD RBAC. e.g. role:admin. Defaults to role:readonly.
+	// +kubebuilder:validation:Optional
+	Role string `json:"role,omitempty"`
+}
+
+// SSOAccountsSpec configures static users of the identity provider.
+type SSOAccountsSpec struct {
+	// Users can log in to ArgoCD and Gitea when SSO is enabled in the build customization. A user with a random password is created when empty.
+	// +kubebuilder:validation:Optional
+	Users []SSOUser `json:"users,omitempty"`
+}
+
+type SSOUser struct {
+	Username string `json:"username"`
+	// Email is used to log in. Defaults to <username>@<host>.
+	// +kubebuilder:validation:Optional
+	Email string `json:"email,omitempty"`
+	// Admin assigns role:admin in ArgoCD. Other users are assigned role:readonly.
+	// +kubebuilder:validation:Optional
+	Admin bool `json:"admin,omitempty"`
 }
 
 // BuildCustomizationSpec fields cannot change once a cluster is created
@@ -53,6 +234,12 @@ type BuildCustomizationSpec struct {
 	Port           string `json:"port,omitempty"`
 	UsePathRouting bool   `json:"usePathRouting,omitempty"`
 	SelfSignedCert string `json:"selfSignedCert,omitempty"`
+	// IngressServiceType is the type of the ingress-nginx service, LoadBalancer or NodePort.
+	// When set, ingress-nginx does not bind host ports and is not pinned to the kind node labeled ingress-ready.
+	// It is set when idpbuilder runs against an existing cluster.
+	IngressServiceType string `json:"ingressServiceType,omitempty"`
+	// SSO runs the Dex server bundled with ArgoCD as identity provider of ArgoCD and Gitea.
+	SSO bool `json:"sso,omitempty"`
 }
 
 type LocalbuildSpec struct {
@@ -75,6 +262,7 @@ type LocalbuildStatus struct {
 	ArgoCD             ArgoCDStatus `json:"ArgoCD,omitempty"`
 	Nginx              NginxStatus  `json:"nginx,omitempty"`
 	Gitea              GiteaStatus  `json:"gitea,omitempty"`
+	SSO                SSOStatus    `json:"sso,omitempty"`
 }
 
 type GiteaStatus struct {
@@ -83,6 +271,8 @@ type GiteaStatus struct {
 	InternalURL              string `json:"internalURL,omitempty"`
 	AdminUserSecretName      string `json:"adminUserSecretNameecret,omitempty"`
 	AdminUserSecretNamespace string `json:"adminUserSecretNamespace,omitempty"`
+	// AdminUser is the name of the admin user. It owns repositories created by idpbuilder.
+	AdminUser string `json:"adminUser,omitempty"`
 }
 
 type ArgoCDStatus struct {
@@ -94,6 +284,12 @@ type NginxStatus struct {
 	Available bool `json:"available,omitempty"`
 }
 
+type SSOStatus struct {
+	Available bool `json:"available,omitempty"`
+	// IssuerURL is the URL of the OIDC issuer ArgoCD and Gitea log in with.
+	IssuerURL string `json:"issuerURL,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=localbuilds,scope=Cluster
@@ -21,9 +21,68 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccountCredential) DeepCopyInto(out *AccountCredential) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccountCredential.
+func (in *AccountCredential) DeepCopy() *AccountCredential {
+	if in == nil {
+		return nil
+	}
+	out := new(AccountCredential)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccountsSpec) DeepCopyInto(out *AccountsSpec) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	in.Gitea.DeepCopyInto(&out.Gitea)
+	in.ArgoCD.DeepCopyInto(&out.ArgoCD)
+	in.SSO.DeepCopyInto(&out.SSO)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccountsSpec.
+func (in *AccountsSpec) DeepCopy() *AccountsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AccountsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDAccountsSpec) DeepCopyInto(out *ArgoCDAccountsSpec) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]ArgoCDUser, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDAccountsSpec.
+func (in *ArgoCDAccountsSpec) DeepCopy() *ArgoCDAccountsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDAccountsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ArgoCDPackageSpec) DeepCopyInto(out *ArgoCDPackageSpec) {
 	*out = *in
@@ -54,6 +113,21 @@ func (in *ArgoCDStatus) DeepCopy() *ArgoCDStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDUser) DeepCopyInto(out *ArgoCDUser) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDUser.
+func (in *ArgoCDUser) DeepCopy() *ArgoCDUser {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ArgoPackageConfigSpec) DeepCopyInto(out *ArgoPackageConfigSpec) {
 	*out = *in
@@ -84,6 +158,21 @@ func (in *BuildCustomizationSpec) DeepCopy() *BuildCustomizationSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuiltImage) DeepCopyInto(out *BuiltImage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuiltImage.
+func (in *BuiltImage) DeepCopy() *BuiltImage {
+	if in == nil {
+		return nil
+	}
+	out := new(BuiltImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Commit) DeepCopyInto(out *Commit) {
 	*out = *in
@@ -104,7 +193,7 @@ func (in *CustomPackage) DeepCopyInto(out *CustomPackage) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -164,6 +253,11 @@ func (in *CustomPackageSpec) DeepCopyInto(out *CustomPackageSpec) {
 	out.ArgoCD = in.ArgoCD
 	out.GitServerAuthSecretRef = in.GitServerAuthSecretRef
 	out.RemoteRepository = in.RemoteRepository
+	if in.SyncPolicy != nil {
+		in, out := &in.SyncPolicy, &out.SyncPolicy
+		*out = new(SyncPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomPackageSpec.
@@ -184,6 +278,16 @@ func (in *CustomPackageStatus) DeepCopyInto(out *CustomPackageStatus) {
 		*out = make([]ObjectRef, len(*in))
 		copy(*out, *in)
 	}
+	if in.Project != nil {
+		in, out := &in.Project, &out.Project
+		*out = new(ProjectPermissions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]BuiltImage, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomPackageStatus.
@@ -216,7 +320,7 @@ func (in *GitRepository) DeepCopyInto(out *GitRepository) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	out.Status = in.Status
 }
 
@@ -274,6 +378,11 @@ func (in *GitRepositoryList) DeepCopyObject() runtime.Object {
 func (in *GitRepositorySource) DeepCopyInto(out *GitRepositorySource) {
 	*out = *in
 	out.RemoteRepository = in.RemoteRepository
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ImageReplacement, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepositorySource.
@@ -291,8 +400,9 @@ func (in *GitRepositorySpec) DeepCopyInto(out *GitRepositorySpec) {
 	*out = *in
 	out.Customization = in.Customization
 	out.SecretRef = in.SecretRef
-	out.Source = in.Source
+	in.Source.DeepCopyInto(&out.Source)
 	out.Provider = in.Provider
+	in.Repository.DeepCopyInto(&out.Repository)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepositorySpec.
@@ -321,6 +431,56 @@ func (in *GitRepositoryStatus) DeepCopy() *GitRepositoryStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaAccountsSpec) DeepCopyInto(out *GiteaAccountsSpec) {
+	*out = *in
+	out.Admin = in.Admin
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]GiteaUser, len(*in))
+		copy(*out, *in)
+	}
+	if in.Organizations != nil {
+		in, out := &in.Organizations, &out.Organizations
+		*out = make([]GiteaOrganization, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaAccountsSpec.
+func (in *GiteaAccountsSpec) DeepCopy() *GiteaAccountsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaAccountsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaOrganization) DeepCopyInto(out *GiteaOrganization) {
+	*out = *in
+	if in.Teams != nil {
+		in, out := &in.Teams, &out.Teams
+		*out = make([]GiteaTeam, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaOrganization.
+func (in *GiteaOrganization) DeepCopy() *GiteaOrganization {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaOrganization)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GiteaStatus) DeepCopyInto(out *GiteaStatus) {
 	*out = *in
@@ -336,6 +496,86 @@ func (in *GiteaStatus) DeepCopy() *GiteaStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaTeam) DeepCopyInto(out *GiteaTeam) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaTeam.
+func (in *GiteaTeam) DeepCopy() *GiteaTeam {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaTeam)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaUser) DeepCopyInto(out *GiteaUser) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaUser.
+func (in *GiteaUser) DeepCopy() *GiteaUser {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IgnoreDifference) DeepCopyInto(out *IgnoreDifference) {
+	*out = *in
+	if in.JSONPointers != nil {
+		in, out := &in.JSONPointers, &out.JSONPointers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.JQPathExpressions != nil {
+		in, out := &in.JQPathExpressions, &out.JQPathExpressions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ManagedFieldsManagers != nil {
+		in, out := &in.ManagedFieldsManagers, &out.ManagedFieldsManagers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IgnoreDifference.
+func (in *IgnoreDifference) DeepCopy() *IgnoreDifference {
+	if in == nil {
+		return nil
+	}
+	out := new(IgnoreDifference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageReplacement) DeepCopyInto(out *ImageReplacement) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageReplacement.
+func (in *ImageReplacement) DeepCopy() *ImageReplacement {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageReplacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Localbuild) DeepCopyInto(out *Localbuild) {
 	*out = *in
@@ -418,6 +658,7 @@ func (in *LocalbuildStatus) DeepCopyInto(out *LocalbuildStatus) {
 	out.ArgoCD = in.ArgoCD
 	out.Nginx = in.Nginx
 	out.Gitea = in.Gitea
+	out.SSO = in.SSO
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalbuildStatus.
@@ -482,6 +723,19 @@ func (in *PackageConfigsSpec) DeepCopyInto(out *PackageConfigsSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.SyncPolicy != nil {
+		in, out := &in.SyncPolicy, &out.SyncPolicy
+		*out = new(SyncPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PackageSyncPolicies != nil {
+		in, out := &in.PackageSyncPolicies, &out.PackageSyncPolicies
+		*out = make(map[string]SyncPolicySpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	in.Accounts.DeepCopyInto(&out.Accounts)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageConfigsSpec.
@@ -509,6 +763,51 @@ func (in *PackageCustomization) DeepCopy() *PackageCustomization {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectDestination) DeepCopyInto(out *ProjectDestination) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectDestination.
+func (in *ProjectDestination) DeepCopy() *ProjectDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectPermissions) DeepCopyInto(out *ProjectPermissions) {
+	*out = *in
+	if in.SourceRepos != nil {
+		in, out := &in.SourceRepos, &out.SourceRepos
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]ProjectDestination, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterResources != nil {
+		in, out := &in.ClusterResources, &out.ClusterResources
+		*out = make([]v1.GroupKind, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectPermissions.
+func (in *ProjectPermissions) DeepCopy() *ProjectPermissions {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectPermissions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Provider) DeepCopyInto(out *Provider) {
 	*out = *in
@@ -539,6 +838,76 @@ func (in *RemoteRepositorySpec) DeepCopy() *RemoteRepositorySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositorySettings) DeepCopyInto(out *RepositorySettings) {
+	*out = *in
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositorySettings.
+func (in *RepositorySettings) DeepCopy() *RepositorySettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositorySettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSOAccountsSpec) DeepCopyInto(out *SSOAccountsSpec) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]SSOUser, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSOAccountsSpec.
+func (in *SSOAccountsSpec) DeepCopy() *SSOAccountsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SSOAccountsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSOStatus) DeepCopyInto(out *SSOStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSOStatus.
+func (in *SSOStatus) DeepCopy() *SSOStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SSOStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSOUser) DeepCopyInto(out *SSOUser) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSOUser.
+func (in *SSOUser) DeepCopy() *SSOUser {
+	if in == nil {
+		return nil
+	}
+	out := new(SSOUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretReference) DeepCopyInto(out *SecretReference) {
 	*out = *in
@@ -553,3 +922,55 @@ func (in *SecretReference) DeepCopy() *SecretReference {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncPolicySpec) DeepCopyInto(out *SyncPolicySpec) {
+	*out = *in
+	if in.SelfHeal != nil {
+		in, out := &in.SelfHeal, &out.SelfHeal
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(SyncRetrySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IgnoreDifferences != nil {
+		in, out := &in.IgnoreDifferences, &out.IgnoreDifferences
+		*out = make([]IgnoreDifference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncPolicySpec.
+func (in *SyncPolicySpec) DeepCopy() *SyncPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncRetrySpec) DeepCopyInto(out *SyncRetrySpec) {
+	*out = *in
+	if in.BackoffFactor != nil {
+		in, out := &in.BackoffFactor, &out.BackoffFactor
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncRetrySpec.
+func (in *SyncRetrySpec) DeepCopy() *SyncRetrySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncRetrySpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,135 @@
+// The manager runs the GitRepository and CustomPackage controllers in a cluster set up by idpbuilder.
+// It keeps reconciling remote packages after the CLI exits. Local packages are still pushed by the CLI.
+// Private remote packages are cloned with the SSH key and netrc file given by flags, usually mounted from a Secret.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+const leaderElectionID = "idpbuilder-manager.cnoe.io"
+
+// +kubebuilder:rbac:groups=idpbuilder.cnoe.io,resources=localbuilds,verbs=get
+
+func main() {
+	var (
+		metricsAddr             string
+		probeAddr               string
+		enableLeaderElection    bool
+		leaderElectionNamespace string
+		buildName               string
+		sourceTypes             string
+		gitAuth                 util.GitAuthOptions
+	)
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to. 0 disables it.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election. Ensures only one active manager when running more than one replica.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "Namespace to create the leader election lease in. Defaults to the namespace of the pod.")
+	flag.StringVar(&buildName, "build-name", "localdev", "Name of the localbuild resource to read build customization from.")
+	flag.StringVar(&sourceTypes, "source-types", v1alpha1.SourceTypeRemote, "Comma separated source types of GitRepositories and CustomPackages to reconcile. local, remote, or embedded.")
+	flag.StringVar(&gitAuth.SSHPrivateKeyPath, "package-ssh-key", "", "Path to the SSH private key used to clone remote packages from SSH URLs. Ignored if the file does not exist.")
+	flag.StringVar(&gitAuth.NetrcPath, "package-netrc", "", "Path to the netrc file used to clone remote packages from HTTPS URLs. Ignored if the file does not exist.")
+	flag.StringVar(&helpers.LogLevel, "log-level", "info", helpers.LogLevelMsg)
+	flag.StringVar(&helpers.LogFormat, "log-format", "text", helpers.LogFormatMsg)
+	flag.Parse()
+
+	if err := run(metricsAddr, probeAddr, enableLeaderElection, leaderElectionNamespace, buildName, strings.Split(sourceTypes, ","), gitAuth); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(metricsAddr, probeAddr string, enableLeaderElection bool, leaderElectionNamespace, buildName string, sourceTypes []string, gitAuth util.GitAuthOptions) error {
+	if err := helpers.SetLogger(); err != nil {
+		return err
+	}
+	setupLog := ctrl.Log.WithName("setup")
+
+	if err := util.ValidateSourceTypes(sourceTypes); err != nil {
+		return err
+	}
+	// the secret holding credentials is optional. keys that are not in it are not mounted.
+	gitAuth.SSHPrivateKeyPath = existingFile(gitAuth.SSHPrivateKeyPath)
+	gitAuth.NetrcPath = existingFile(gitAuth.NetrcPath)
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("getting kube config: %w", err)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme: k8s.GetScheme(),
+		Metrics: server.Options{
+			BindAddress: metricsAddr,
+		},
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        leaderElectionID,
+		LeaderElectionNamespace: leaderElectionNamespace,
+	})
+	if err != nil {
+		return fmt.Errorf("creating controller manager: %w", err)
+	}
+
+	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return fmt.Errorf("adding health check: %w", err)
+	}
+	if err = mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		return fmt.Errorf("adding ready check: %w", err)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+
+	// the cache is not started yet. read directly from the API server.
+	cfg, err := getBuildCustomization(ctx, mgr.GetAPIReader(), buildName)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", fmt.Sprintf("%s-manager-", globals.ProjectName))
+	if err != nil {
+		return fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = controllers.SetupPackageControllers(mgr, cfg, tmpDir, util.NewRepoLock(), gitAuth, sourceTypes, nil)
+	if err != nil {
+		return err
+	}
+
+	setupLog.Info("starting manager", "build", buildName, "sourceTypes", sourceTypes, "leaderElection", enableLeaderElection,
+		"sshKey", gitAuth.SSHPrivateKeyPath, "netrc", gitAuth.NetrcPath)
+	return mgr.Start(ctx)
+}
+
+func getBuildCustomization(ctx context.Context, reader client.Reader, buildName string) (v1alpha1.BuildCustomizationSpec, error) {
+	localBuild := v1alpha1.Localbuild{}
+	err := reader.Get(ctx, client.ObjectKey{Name: buildName}, &localBuild)
+	if err != nil {
+		return v1alpha1.BuildCustomizationSpec{}, fmt.Errorf("getting localbuild %s: %w", buildName, err)
+	}
+	return localBuild.Spec.BuildCustomization, nil
+}
+
+// existingFile returns path if a file exists at it, and an empty string otherwise.
+func existingFile(path string) string {
+	if path == "" || !util.Exists(path) {
+		return ""
+	}
+	return path
+}
@@ -13,6 +13,11 @@ const (
 	SelfSignedCertCMKeyName  = "ca.crt"
 	DefaultSANWildcard       = "*.cnoe.localtest.me"
 	DefaultHostName          = "cnoe.localtest.me"
+
+	// KindConfigCMName is the ConfigMap in KindConfigCMNamespace the rendered kind config is saved to for diagnostics.
+	KindConfigCMName      = "idpbuilder-kind-config"
+	KindConfigCMNamespace = "kube-system"
+	KindConfigCMKeyName   = "kind.yaml"
 )
 
 func GetProjectNamespace(name string) string {
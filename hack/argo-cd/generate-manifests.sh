@@ -6,5 +6,8 @@ INGRESS_YAML="pkg/controllers/localbuild/resources/argo/ingress.yaml"
 echo "# UCP ARGO INSTALL RESOURCES" > ${INSTALL_YAML}
 echo "# This file is auto-generated with 'hack/argo-cd/generate-manifests.sh'" >> ${INSTALL_YAML}
 kustomize build ./hack/argo-cd/ >> ${INSTALL_YAML}
+# dex is only needed for SSO. kustomize does not accept a template for the integer replicas field.
+sed -i.bak '/^  name: argocd-dex-server$/{n;n;s/^  replicas: 0$/  replicas: {{ if .SSO }}1{{ else }}0{{ end }}/;}' ${INSTALL_YAML}
+rm -f "${INSTALL_YAML}.bak"
 
 cat ./hack/argo-cd/ingress.yaml.tmpl > ${INGRESS_YAML}
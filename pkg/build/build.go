@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/controllers"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/localbuild"
 	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	containerruntime "github.com/cnoe-io/idpbuilder/pkg/runtime"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -26,17 +31,38 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// accountsSecretName is the secret in the project namespace holding the passwords from the accounts file.
+const accountsSecretName = "idpbuilder-accounts"
+
 type Build struct {
 	name                 string
 	cfg                  v1alpha1.BuildCustomizationSpec
 	kindConfigPath       string
 	kubeConfigPath       string
+	kubeContext          string
+	existingCluster      bool
 	kubeVersion          string
 	extraPortsMapping    string
+	extraClusters        []string
+	extraKindClusters    map[string]*kind.Cluster
+	kindConfig           []byte
 	customPackageDirs    []string
 	customPackageUrls    []string
 	packageCustomization map[string]v1alpha1.PackageCustomization
 	exitOnSync           bool
+	waitForHealthy       bool
+	healthyTimeout       time.Duration
+	mirrorHistory        bool
+	packageBranch        string
+	scopedProjects       bool
+	syncPolicy           *v1alpha1.SyncPolicySpec
+	packageSyncPolicies  map[string]v1alpha1.SyncPolicySpec
+	accounts             v1alpha1.AccountsSpec
+	accountPasswords     map[string][]byte
+	gitAuth              util.GitAuthOptions
+	sourceTypes          []string
+	repoCacheDir         string
+	repoCacheMaxSize     int64
 	scheme               *runtime.Scheme
 	CancelFunc           context.CancelFunc
 }
@@ -46,12 +72,28 @@ type NewBuildOptions struct {
 	TemplateData         v1alpha1.BuildCustomizationSpec
 	KindConfigPath       string
 	KubeConfigPath       string
+	KubeContext          string
+	ExistingCluster      bool
 	KubeVersion          string
 	ExtraPortsMapping    string
+	ExtraClusters        []string
 	CustomPackageDirs    []string
 	CustomPackageUrls    []string
 	PackageCustomization map[string]v1alpha1.PackageCustomization
 	ExitOnSync           bool
+	WaitForHealthy       bool
+	HealthyTimeout       time.Duration
+	MirrorHistory        bool
+	PackageBranch        string
+	ScopedProjects       bool
+	SyncPolicy           *v1alpha1.SyncPolicySpec
+	PackageSyncPolicies  map[string]v1alpha1.SyncPolicySpec
+	Accounts             v1alpha1.AccountsSpec
+	AccountPasswords     map[string][]byte
+	GitAuth              util.GitAuthOptions
+	SourceTypes          []string
+	RepoCacheDir         string
+	RepoCacheMaxSize     int64
 	Scheme               *runtime.Scheme
 	CancelFunc           context.CancelFunc
 }
@@ -61,12 +103,28 @@ func NewBuild(opts NewBuildOptions) *Build {
 		name:                 opts.Name,
 		kindConfigPath:       opts.KindConfigPath,
 		kubeConfigPath:       opts.KubeConfigPath,
+		kubeContext:          opts.KubeContext,
+		existingCluster:      opts.ExistingCluster,
 		kubeVersion:          opts.KubeVersion,
 		extraPortsMapping:    opts.ExtraPortsMapping,
+		extraClusters:        opts.ExtraClusters,
 		customPackageDirs:    opts.CustomPackageDirs,
 		customPackageUrls:    opts.CustomPackageUrls,
 		packageCustomization: opts.PackageCustomization,
 		exitOnSync:           opts.ExitOnSync,
+		waitForHealthy:       opts.WaitForHealthy,
+		healthyTimeout:       opts.HealthyTimeout,
+		mirrorHistory:        opts.MirrorHistory,
+		packageBranch:        opts.PackageBranch,
+		scopedProjects:       opts.ScopedProjects,
+		syncPolicy:           opts.SyncPolicy,
+		packageSyncPolicies:  opts.PackageSyncPolicies,
+		accounts:             opts.Accounts,
+		accountPasswords:     opts.AccountPasswords,
+		gitAuth:              opts.GitAuth,
+		sourceTypes:          opts.SourceTypes,
+		repoCacheDir:         opts.RepoCacheDir,
+		repoCacheMaxSize:     opts.RepoCacheMaxSize,
 		scheme:               opts.Scheme,
 		cfg:                  opts.TemplateData,
 		CancelFunc:           opts.CancelFunc,
@@ -74,6 +132,11 @@ func NewBuild(opts NewBuildOptions) *Build {
 }
 
 func (b *Build) ReconcileKindCluster(ctx context.Context, recreateCluster bool) error {
+	if err := containerruntime.CheckAvailable(ctx); err != nil {
+		setupLog.Error(err, "Error checking container runtime")
+		return err
+	}
+
 	// Initialize Kind Cluster
 	cluster, err := kind.NewCluster(b.name, b.kubeVersion, b.kubeConfigPath, b.kindConfigPath, b.extraPortsMapping, b.cfg)
 	if err != nil {
@@ -87,6 +150,18 @@ func (b *Build) ReconcileKindCluster(ctx context.Context, recreateCluster bool)
 		return err
 	}
 
+	b.kindConfig, err = cluster.Config()
+	if err != nil {
+		setupLog.Error(err, "Error rendering kind config")
+		return err
+	}
+
+	// Extra clusters are exported first so the current context ends up pointing to the main cluster
+	if err := b.reconcileExtraClusters(ctx, recreateCluster); err != nil {
+		setupLog.Error(err, "Error starting extra kind clusters")
+		return err
+	}
+
 	// Create Kube Config for Kind cluster
 	if err := cluster.ExportKubeConfig(b.name, false); err != nil {
 		setupLog.Error(err, "Error exporting kubeconfig from kind cluster")
@@ -95,10 +170,61 @@ func (b *Build) ReconcileKindCluster(ctx context.Context, recreateCluster bool)
 	return nil
 }
 
+// saveKindConfig stores the rendered kind config in the cluster so it can be collected by debug dump.
+func (b *Build) saveKindConfig(ctx context.Context, kubeClient client.Client) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      globals.KindConfigCMName,
+			Namespace: globals.KindConfigCMNamespace,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, kubeClient, cm, func() error {
+		cm.Data = map[string]string{globals.KindConfigCMKeyName: string(b.kindConfig)}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("saving kind config: %w", err)
+	}
+	return nil
+}
+
+// saveAccountPasswords stores the passwords of the accounts in a secret and references it from the accounts.
+// Passwords are not stored in the Localbuild, which any user allowed to read it can see.
+func (b *Build) saveAccountPasswords(ctx context.Context, kubeClient client.Client) error {
+	if len(b.accountPasswords) == 0 {
+		return nil
+	}
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: globals.GetProjectNamespace(b.name)}}
+	_, err := controllerutil.CreateOrUpdate(ctx, kubeClient, ns, func() error { return nil })
+	if err != nil {
+		return fmt.Errorf("creating namespace %s: %w", ns.Name, err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      accountsSecretName,
+			Namespace: ns.Name,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, kubeClient, secret, func() error {
+		secret.Data = b.accountPasswords
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("saving account passwords: %w", err)
+	}
+	b.accounts.SecretRef = &v1alpha1.SecretReference{Name: secret.Name, Namespace: secret.Namespace}
+	return nil
+}
+
 func (b *Build) GetKubeConfig() (*rest.Config, error) {
-	kubeConfig, err := clientcmd.BuildConfigFromFlags("", b.kubeConfigPath)
+	kubeConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: b.kubeConfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: b.kubeContext},
+	).ClientConfig()
 	if err != nil {
-		setupLog.Error(err, "Error building kubeconfig from kind cluster")
+		setupLog.Error(err, "Error building kubeconfig")
 		return nil, err
 	}
 	return kubeConfig, nil
@@ -122,8 +248,42 @@ func (b *Build) ReconcileCRDs(ctx context.Context, kubeClient client.Client) err
 	return nil
 }
 
+// getRepoDir returns the directory repositories are cloned to and a function to clean it up on exit.
+func (b *Build) getRepoDir() (string, func(), error) {
+	if b.repoCacheDir == "" {
+		dir, err := os.MkdirTemp("", fmt.Sprintf("%s-%s-", globals.ProjectName, b.name))
+		if err != nil {
+			return "", nil, fmt.Errorf("creating temp dir: %w", err)
+		}
+		// delete leaves the directory alone while this process holds the lock
+		l, err := util.LockDir(dir)
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", nil, err
+		}
+		return dir, func() {
+			os.RemoveAll(dir)
+			l.Unlock()
+		}, nil
+	}
+
+	err := os.MkdirAll(b.repoCacheDir, 0755)
+	if err != nil {
+		return "", nil, fmt.Errorf("creating cache dir %s: %w", b.repoCacheDir, err)
+	}
+
+	removed, err := util.PruneRepoCache(b.repoCacheDir, b.repoCacheMaxSize)
+	if err != nil {
+		return "", nil, fmt.Errorf("pruning cache dir %s: %w", b.repoCacheDir, err)
+	}
+	for i := range removed {
+		setupLog.V(1).Info("Evicted repository from cache", "dir", removed[i].Path, "size", removed[i].Size)
+	}
+	return b.repoCacheDir, func() {}, nil
+}
+
 func (b *Build) RunControllers(ctx context.Context, mgr manager.Manager, exitCh chan error, tmpDir string) error {
-	return controllers.RunControllers(ctx, mgr, exitCh, b.CancelFunc, b.exitOnSync, b.cfg, tmpDir)
+	return controllers.RunControllers(ctx, mgr, exitCh, b.CancelFunc, b.exitOnSync, b.waitForHealthy, b.cfg, tmpDir, b.gitAuth, b.sourceTypes)
 }
 
 func (b *Build) isCompatible(ctx context.Context, kubeClient client.Client) (bool, error) {
@@ -159,9 +319,13 @@ func (b *Build) isCompatible(ctx context.Context, kubeClient client.Client) (boo
 func (b *Build) Run(ctx context.Context, recreateCluster bool) error {
 	managerExit := make(chan error)
 
-	setupLog.Info("Creating kind cluster")
-	if err := b.ReconcileKindCluster(ctx, recreateCluster); err != nil {
-		return err
+	if b.existingCluster {
+		setupLog.Info("Using existing cluster", "kubeconfig", b.kubeConfigPath, "context", b.kubeContext)
+	} else {
+		setupLog.Info("Creating kind cluster")
+		if err := b.ReconcileKindCluster(ctx, recreateCluster); err != nil {
+			return err
+		}
 	}
 
 	setupLog.V(1).Info("Getting Kube config")
@@ -181,6 +345,26 @@ func (b *Build) Run(ctx context.Context, recreateCluster bool) error {
 		return err
 	}
 
+	if b.kindConfig != nil {
+		setupLog.V(1).Info("Saving kind config")
+		if err := b.saveKindConfig(ctx, kubeClient); err != nil {
+			return err
+		}
+	}
+
+	setupLog.V(1).Info("Saving account passwords")
+	if err := b.saveAccountPasswords(ctx, kubeClient); err != nil {
+		return err
+	}
+
+	if len(b.extraKindClusters) > 0 {
+		setupLog.Info("Registering extra clusters with ArgoCD")
+		if err := b.registerExtraClusters(ctx, kubeClient); err != nil {
+			setupLog.Error(err, "Error registering extra clusters")
+			return err
+		}
+	}
+
 	setupLog.V(1).Info("Creating controller manager")
 	// Create controller manager
 	mgr, err := ctrl.NewManager(kubeConfig, ctrl.Options{
@@ -194,18 +378,21 @@ func (b *Build) Run(ctx context.Context, recreateCluster bool) error {
 		return err
 	}
 
-	dir, err := os.MkdirTemp("", fmt.Sprintf("%s-%s-", globals.ProjectName, b.name))
+	dir, cleanup, err := b.getRepoDir()
 	if err != nil {
-		setupLog.Error(err, "creating temp dir")
+		setupLog.Error(err, "creating directory for cloning repositories")
 		return err
 	}
-	defer os.RemoveAll(dir)
-	setupLog.V(1).Info("Created temp directory for cloning repositories", "dir", dir)
-
-	setupLog.Info("Setting up CoreDNS")
-	err = setupCoreDNS(ctx, kubeClient, b.scheme, b.cfg)
-	if err != nil {
-		return err
+	defer cleanup()
+	setupLog.V(1).Info("Using directory for cloning repositories", "dir", dir)
+
+	// CoreDNS of existing clusters is not ours to replace
+	if !b.existingCluster {
+		setupLog.Info("Setting up CoreDNS")
+		err = setupCoreDNS(ctx, kubeClient, b.scheme, b.cfg)
+		if err != nil {
+			return err
+		}
 	}
 
 	setupLog.Info("Setting up TLS certificate")
@@ -257,6 +444,12 @@ func (b *Build) Run(ctx context.Context, recreateCluster bool) error {
 				CustomPackageDirs:        b.customPackageDirs,
 				CustomPackageUrls:        b.customPackageUrls,
 				CorePackageCustomization: b.packageCustomization,
+				MirrorHistory:            b.mirrorHistory,
+				PackageBranch:            b.packageBranch,
+				ScopedProjects:           b.scopedProjects,
+				SyncPolicy:               b.syncPolicy,
+				PackageSyncPolicies:      b.packageSyncPolicies,
+				Accounts:                 b.accounts,
 			},
 		}
 
@@ -266,9 +459,30 @@ func (b *Build) Run(ctx context.Context, recreateCluster bool) error {
 		return fmt.Errorf("creating localbuild resource: %w", err)
 	}
 
-	err = <-managerExit
-	close(managerExit)
-	return err
+	var timeout <-chan time.Time
+	if b.exitOnSync && b.waitForHealthy && b.healthyTimeout > 0 {
+		timeout = time.After(b.healthyTimeout)
+	}
+
+	select {
+	case err = <-managerExit:
+		close(managerExit)
+		return err
+	case <-timeout:
+		unhealthy, uErr := localbuild.GetUnhealthyApplications(ctx, kubeClient)
+		b.CancelFunc()
+		<-managerExit
+		close(managerExit)
+		if uErr != nil {
+			return fmt.Errorf("timed out after %s waiting for ArgoCD applications to be healthy. failed listing them: %w", b.healthyTimeout, uErr)
+		}
+
+		report := make([]string, 0, len(unhealthy))
+		for i := range unhealthy {
+			report = append(report, unhealthy[i].String())
+		}
+		return fmt.Errorf("timed out after %s waiting for ArgoCD applications to be synced and healthy:\n%s", b.healthyTimeout, strings.Join(report, "\n"))
+	}
 }
 
 func isBuildCustomizationSpecEqual(s1, s2 v1alpha1.BuildCustomizationSpec) bool {
@@ -278,5 +492,7 @@ func isBuildCustomizationSpecEqual(s1, s2 v1alpha1.BuildCustomizationSpec) bool
 		s1.IngressHost == s2.IngressHost &&
 		s1.Port == s2.Port &&
 		s1.UsePathRouting == s2.UsePathRouting &&
+		s1.IngressServiceType == s2.IngressServiceType &&
+		s1.SSO == s2.SSO &&
 		s1.SelfSignedCert == s2.SelfSignedCert
 }
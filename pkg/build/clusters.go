@@ -0,0 +1,129 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	ArgoCDClusterSecretTypeLabelKey   = "argocd.argoproj.io/secret-type"
+	ArgoCDClusterSecretTypeLabelValue = "cluster"
+	argoCDClusterSecretPrefix         = "cluster-"
+)
+
+// argoCDClusterConfig is the config field of ArgoCD cluster secrets.
+type argoCDClusterConfig struct {
+	TLSClientConfig argoCDTLSClientConfig `json:"tlsClientConfig"`
+}
+
+type argoCDTLSClientConfig struct {
+	CAData   []byte `json:"caData,omitempty"`
+	CertData []byte `json:"certData,omitempty"`
+	KeyData  []byte `json:"keyData,omitempty"`
+}
+
+// reconcileExtraClusters creates the extra kind clusters and exports their kubeconfig.
+func (b *Build) reconcileExtraClusters(ctx context.Context, recreateCluster bool) error {
+	b.extraKindClusters = make(map[string]*kind.Cluster, len(b.extraClusters))
+	for _, name := range b.extraClusters {
+		cluster, err := kind.NewWorkloadCluster(b.name, name, b.kubeVersion, b.kubeConfigPath, b.cfg)
+		if err != nil {
+			return fmt.Errorf("creating extra cluster %s: %w", name, err)
+		}
+		if err = cluster.Reconcile(ctx, recreateCluster); err != nil {
+			return fmt.Errorf("starting extra cluster %s: %w", name, err)
+		}
+		if err = cluster.ResolveRegistryHost(ctx); err != nil {
+			return fmt.Errorf("configuring registry of extra cluster %s: %w", name, err)
+		}
+		if err = cluster.ExportKubeConfig(cluster.Name(), false); err != nil {
+			return fmt.Errorf("exporting kubeconfig of extra cluster %s: %w", name, err)
+		}
+		b.extraKindClusters[name] = cluster
+	}
+	return nil
+}
+
+// registerExtraClusters adds the extra clusters to ArgoCD so applications can target them by name.
+func (b *Build) registerExtraClusters(ctx context.Context, kubeClient client.Client) error {
+	if len(b.extraKindClusters) == 0 {
+		return nil
+	}
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: globals.ArgoCDNamespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, kubeClient, ns, func() error { return nil })
+	if err != nil {
+		return fmt.Errorf("creating namespace %s: %w", globals.ArgoCDNamespace, err)
+	}
+
+	for name, cluster := range b.extraKindClusters {
+		kubeConfig, kErr := cluster.KubeConfig(true)
+		if kErr != nil {
+			return fmt.Errorf("getting internal kubeconfig of extra cluster %s: %w", name, kErr)
+		}
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      argoCDClusterSecretPrefix + name,
+				Namespace: globals.ArgoCDNamespace,
+			},
+		}
+		_, kErr = controllerutil.CreateOrUpdate(ctx, kubeClient, secret, func() error {
+			return setArgoCDClusterSecret(secret, name, kubeConfig)
+		})
+		if kErr != nil {
+			return fmt.Errorf("registering extra cluster %s with ArgoCD: %w", name, kErr)
+		}
+	}
+	return nil
+}
+
+func setArgoCDClusterSecret(secret *corev1.Secret, name, kubeConfig string) error {
+	cfg, err := clientcmd.Load([]byte(kubeConfig))
+	if err != nil {
+		return fmt.Errorf("parsing kubeconfig: %w", err)
+	}
+	kubeContext, ok := cfg.Contexts[cfg.CurrentContext]
+	if !ok {
+		return fmt.Errorf("context %s not found in kubeconfig", cfg.CurrentContext)
+	}
+	cluster, ok := cfg.Clusters[kubeContext.Cluster]
+	if !ok {
+		return fmt.Errorf("cluster %s not found in kubeconfig", kubeContext.Cluster)
+	}
+	user, ok := cfg.AuthInfos[kubeContext.AuthInfo]
+	if !ok {
+		return fmt.Errorf("user %s not found in kubeconfig", kubeContext.AuthInfo)
+	}
+
+	config, err := json.Marshal(argoCDClusterConfig{
+		TLSClientConfig: argoCDTLSClientConfig{
+			CAData:   cluster.CertificateAuthorityData,
+			CertData: user.ClientCertificateData,
+			KeyData:  user.ClientKeyData,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshalling cluster config: %w", err)
+	}
+
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[ArgoCDClusterSecretTypeLabelKey] = ArgoCDClusterSecretTypeLabelValue
+	secret.Data = map[string][]byte{
+		"name":   []byte(name),
+		"server": []byte(cluster.Server),
+		"config": config,
+	}
+	return nil
+}
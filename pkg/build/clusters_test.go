@@ -0,0 +1,48 @@
+package build
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetArgoCDClusterSecret(t *testing.T) {
+	kubeConfig := `
+apiVersion: v1
+kind: Config
+current-context: kind-localdev-staging
+clusters:
+- name: kind-localdev-staging
+  cluster:
+    server: https://localdev-staging-control-plane:6443
+    certificate-authority-data: Y2E=
+contexts:
+- name: kind-localdev-staging
+  context:
+    cluster: kind-localdev-staging
+    user: kind-localdev-staging
+users:
+- name: kind-localdev-staging
+  user:
+    client-certificate-data: Y2VydA==
+    client-key-data: a2V5
+`
+	secret := &corev1.Secret{}
+	err := setArgoCDClusterSecret(secret, "staging", kubeConfig)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ArgoCDClusterSecretTypeLabelValue, secret.Labels[ArgoCDClusterSecretTypeLabelKey])
+	assert.Equal(t, "staging", string(secret.Data["name"]))
+	assert.Equal(t, "https://localdev-staging-control-plane:6443", string(secret.Data["server"]))
+
+	cfg := argoCDClusterConfig{}
+	assert.NoError(t, json.Unmarshal(secret.Data["config"], &cfg))
+	assert.Equal(t, []byte("ca"), cfg.TLSClientConfig.CAData)
+	assert.Equal(t, []byte("cert"), cfg.TLSClientConfig.CertData)
+	assert.Equal(t, []byte("key"), cfg.TLSClientConfig.KeyData)
+
+	err = setArgoCDClusterSecret(secret, "staging", "current-context: missing")
+	assert.Error(t, err)
+}
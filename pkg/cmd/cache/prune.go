@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var (
+	// Flags
+	maxSize string
+)
+
+var PruneCmd = &cobra.Command{
+	Use:     "prune",
+	Short:   "Remove cached repositories",
+	Long:    "Remove least recently used repositories from the cache until the cache is at most --max-size. All repositories are removed by default.",
+	RunE:    pruneE,
+	PreRunE: prePruneE,
+}
+
+func init() {
+	PruneCmd.Flags().StringVar(&maxSize, "max-size", "0", "Size to shrink the cache to. e.g. 500Mi, 2Gi")
+}
+
+func prePruneE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func pruneE(cmd *cobra.Command, args []string) error {
+	size, err := resource.ParseQuantity(maxSize)
+	if err != nil {
+		return fmt.Errorf("parsing max size %s: %w", maxSize, err)
+	}
+
+	dir, err := util.DefaultRepoCacheDir()
+	if err != nil {
+		return err
+	}
+
+	removed, err := util.PruneRepoCache(dir, size.Value())
+	if err != nil {
+		return err
+	}
+
+	var freed int64
+	for i := range removed {
+		helpers.CmdLogger.V(1).Info("removed cached repository", "dir", removed[i].Path, "size", removed[i].Size)
+		freed += removed[i].Size
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "removed %d repositories from %s, freed %s\n", len(removed), dir, resource.NewQuantity(freed, resource.BinarySI).String())
+	return nil
+}
@@ -0,0 +1,22 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var CacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local repository cache",
+	Long:  ``,
+	RunE:  cacheE,
+}
+
+func init() {
+	CacheCmd.AddCommand(PruneCmd)
+}
+
+func cacheE(cmd *cobra.Command, args []string) error {
+	return fmt.Errorf("specify subcommand")
+}
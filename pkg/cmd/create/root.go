@@ -5,14 +5,20 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/build"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/util/homedir"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -24,6 +30,11 @@ var (
 	kubeVersion               string
 	extraPortsMapping         string
 	kindConfigPath            string
+	extraClusters             []string
+	kubeConfigFlag            string
+	kubeContext               string
+	ingressServiceType        string
+	inClusterManager          bool
 	extraPackages             []string
 	packageCustomizationFiles []string
 	noExit                    bool
@@ -32,6 +43,17 @@ var (
 	ingressHost               string
 	port                      string
 	pathRouting               bool
+	sso                       bool
+	packageSSHKeyPath         string
+	noCache                   bool
+	cacheMaxSize              string
+	mirrorHistory             bool
+	packageBranch             string
+	scopedProjects            bool
+	syncPolicyFile            string
+	accountsFile              string
+	waitForHealthy            bool
+	healthyTimeout            time.Duration
 )
 
 var CreateCmd = &cobra.Command{
@@ -49,6 +71,13 @@ func init() {
 	CreateCmd.PersistentFlags().StringVar(&kubeVersion, "kube-version", "v1.30.0", "Version of the kind kubernetes cluster to create.")
 	CreateCmd.PersistentFlags().StringVar(&extraPortsMapping, "extra-ports", "", "List of extra ports to expose on the docker container and kubernetes cluster as nodePort (e.g. \"22:32222,9090:39090,etc\").")
 	CreateCmd.PersistentFlags().StringVar(&kindConfigPath, "kind-config", "", "Path of the kind config file to be used instead of the default.")
+	CreateCmd.PersistentFlags().StringSliceVar(&extraClusters, "extra-clusters", []string{}, "Names of additional kind clusters to create and register with ArgoCD as destinations, e.g. staging,prod. "+
+		"Packages can target them with destination.name. Kind cluster names are prefixed with the build name.")
+
+	CreateCmd.PersistentFlags().StringVar(&kubeConfigFlag, "kubeconfig", "", "Path of the kubeconfig of an existing cluster to install into instead of creating a kind cluster.")
+	CreateCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "Context in the kubeconfig of an existing cluster to install into instead of creating a kind cluster. Uses ~/.kube/config when --kubeconfig is not set.")
+	CreateCmd.PersistentFlags().StringVar(&ingressServiceType, "ingress-service-type", "LoadBalancer", "Type of the ingress-nginx service when installing into an existing cluster. LoadBalancer or NodePort. "+
+		"The service listens on --port and --host must resolve to its address.")
 
 	// in-cluster resources related flags
 	CreateCmd.PersistentFlags().StringVar(&host, "host", globals.DefaultHostName, "Host name to access resources in this cluster.")
@@ -56,9 +85,25 @@ func init() {
 	CreateCmd.PersistentFlags().StringVar(&protocol, "protocol", "https", "Protocol to use to access web UIs. http or https.")
 	CreateCmd.PersistentFlags().StringVar(&port, "port", "8443", "Port number under which idpBuilder tools are accessible.")
 	CreateCmd.PersistentFlags().BoolVar(&pathRouting, "use-path-routing", false, "When set to true, web UIs are exposed under single domain name.")
+	CreateCmd.PersistentFlags().BoolVar(&sso, "sso", false, "When set, ArgoCD and Gitea log in with the Dex server bundled with ArgoCD. Users are read from the sso section of --accounts-file. "+
+		"A user with a random password is created when none are given.")
 	CreateCmd.Flags().StringSliceVarP(&extraPackages, "package", "p", []string{}, "Paths to locations containing custom packages")
+	CreateCmd.Flags().StringVar(&packageSSHKeyPath, "package-ssh-key", "", "Path to the SSH private key used to clone remote packages from SSH URLs. ssh-agent is used when not specified. HTTPS URLs use credentials from git credential helpers or netrc.")
+	CreateCmd.Flags().BoolVar(&mirrorHistory, "mirror-history", false, "When set, commits that touched local packages are pushed to the in-cluster git server instead of a snapshot. Uncommitted changes are pushed as a commit on top.")
+	CreateCmd.Flags().StringVar(&packageBranch, "package-branch", "", "Branch in the in-cluster git server to push custom packages to. ArgoCD applications track this branch. "+
+		"Supports Go templates with .User and .SourceBranch, e.g. \"{{ .User }}/{{ .SourceBranch }}\". Defaults to main.")
+	CreateCmd.Flags().BoolVar(&scopedProjects, "scoped-projects", false, "When set, each custom package is deployed with its own ArgoCD project that only permits its repositories and destination namespaces. "+
+		"Use the cnoe.io/project annotation to share a project, and cnoe.io/project-namespaces and cnoe.io/project-cluster-resources annotations to permit more.")
+	CreateCmd.Flags().StringVar(&syncPolicyFile, "sync-policy-file", "", "Path to a YAML file with ArgoCD sync policies. The default policy applies to embedded applications and custom packages without one. "+
+		"Policies under packages are keyed by application name and take precedence.")
+	CreateCmd.Flags().StringVar(&accountsFile, "accounts-file", "", "Path to a YAML file with the admin credentials and additional users of Gitea and ArgoCD, Gitea organizations and teams, and SSO users.")
 	CreateCmd.Flags().StringSliceVarP(&packageCustomizationFiles, "package-custom-file", "c", []string{}, "Name of the package and the path to file to customize the package with. e.g. argocd:/tmp/argocd.yaml")
 	// idpbuilder related flags
+	CreateCmd.Flags().BoolVar(&noCache, "no-cache", false, "When set, repositories are cloned to a temporary directory instead of the cache directory.")
+	CreateCmd.Flags().StringVar(&cacheMaxSize, "cache-max-size", resource.NewQuantity(util.DefaultRepoCacheMaxSize, resource.BinarySI).String(), "Maximum size of the repository cache. Least recently used repositories are removed when the cache grows larger than this. e.g. 500Mi, 2Gi")
+	CreateCmd.Flags().BoolVar(&waitForHealthy, "wait-for-healthy", false, "When set with --no-exit=false, idpbuilder exits only after all ArgoCD applications are synced and healthy.")
+	CreateCmd.Flags().DurationVar(&healthyTimeout, "healthy-timeout", 10*time.Minute, "How long to wait for ArgoCD applications to become synced and healthy when --wait-for-healthy is set. Unhealthy resources are printed on timeout.")
+	CreateCmd.Flags().BoolVar(&inClusterManager, "in-cluster-manager", false, "Set when the idpbuilder manager is deployed in the cluster. Only local and embedded sources are reconciled by the CLI. Remote packages are left to the manager. See docs/in-cluster-manager.md for credentials of private remote packages.")
 	CreateCmd.Flags().BoolVarP(&noExit, "no-exit", "n", true, "When set, idpbuilder will not exit after all packages are synced. Useful for continuously syncing local directories.")
 }
 
@@ -70,7 +115,11 @@ func create(cmd *cobra.Command, args []string) error {
 	ctx, ctxCancel := context.WithCancel(ctrl.SetupSignalHandler())
 	defer ctxCancel()
 
+	existingCluster := kubeConfigFlag != "" || kubeContext != ""
 	kubeConfigPath := filepath.Join(homedir.HomeDir(), ".kube", "config")
+	if kubeConfigFlag != "" {
+		kubeConfigPath = kubeConfigFlag
+	}
 
 	protocol = strings.ToLower(protocol)
 	host = strings.ToLower(host)
@@ -83,6 +132,15 @@ func create(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	var svcType string
+	if existingCluster {
+		err = validateExistingCluster(cmd)
+		if err != nil {
+			return err
+		}
+		svcType = ingressServiceType
+	}
+
 	var absDirPaths []string
 	var remotePaths []string
 
@@ -104,6 +162,41 @@ func create(cmd *cobra.Command, args []string) error {
 		o[c.Name] = c
 	}
 
+	var syncPolicies helpers.SyncPolicyFile
+	if syncPolicyFile != "" {
+		syncPolicies, err = helpers.ReadSyncPolicyFile(syncPolicyFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	var accounts v1alpha1.AccountsSpec
+	var accountPasswords map[string][]byte
+	if accountsFile != "" {
+		accounts, accountPasswords, err = helpers.ReadAccountsFile(accountsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	maxSize, err := resource.ParseQuantity(cacheMaxSize)
+	if err != nil {
+		return fmt.Errorf("parsing cache max size %s: %w", cacheMaxSize, err)
+	}
+
+	var cacheDir string
+	if !noCache {
+		cacheDir, err = util.DefaultRepoCacheDir()
+		if err != nil {
+			helpers.CmdLogger.Info("repository cache not available. using a temporary directory", "err", err)
+		}
+	}
+
+	var sourceTypes []string
+	if inClusterManager {
+		sourceTypes = []string{v1alpha1.SourceTypeLocal, v1alpha1.SourceTypeEmbedded}
+	}
+
 	exitOnSync := true
 	if cmd.Flags().Changed("no-exit") {
 		exitOnSync = !noExit
@@ -113,21 +206,41 @@ func create(cmd *cobra.Command, args []string) error {
 		Name:              buildName,
 		KubeVersion:       kubeVersion,
 		KubeConfigPath:    kubeConfigPath,
+		KubeContext:       kubeContext,
+		ExistingCluster:   existingCluster,
 		KindConfigPath:    kindConfigPath,
 		ExtraPortsMapping: extraPortsMapping,
+		ExtraClusters:     extraClusters,
 
 		TemplateData: v1alpha1.BuildCustomizationSpec{
-			Protocol:       protocol,
-			Host:           host,
-			IngressHost:    ingressHost,
-			Port:           port,
-			UsePathRouting: pathRouting,
+			Protocol:           protocol,
+			Host:               host,
+			IngressHost:        ingressHost,
+			Port:               port,
+			UsePathRouting:     pathRouting,
+			IngressServiceType: svcType,
+			SSO:                sso,
 		},
 
 		CustomPackageDirs:    absDirPaths,
 		CustomPackageUrls:    remotePaths,
 		ExitOnSync:           exitOnSync,
+		WaitForHealthy:       waitForHealthy,
+		HealthyTimeout:       healthyTimeout,
+		MirrorHistory:        mirrorHistory,
+		PackageBranch:        packageBranch,
+		ScopedProjects:       scopedProjects,
+		SyncPolicy:           syncPolicies.Default,
+		PackageSyncPolicies:  syncPolicies.Packages,
+		Accounts:             accounts,
+		AccountPasswords:     accountPasswords,
 		PackageCustomization: o,
+		GitAuth: util.GitAuthOptions{
+			SSHPrivateKeyPath: packageSSHKeyPath,
+		},
+		SourceTypes:      sourceTypes,
+		RepoCacheDir:     cacheDir,
+		RepoCacheMaxSize: maxSize.Value(),
 
 		Scheme:     k8s.GetScheme(),
 		CancelFunc: ctxCancel,
@@ -171,10 +284,56 @@ func validate() error {
 		}
 	}
 
+	err = validateExtraClusters(extraClusters)
+	if err != nil {
+		return err
+	}
+
 	_, _, err = helpers.ParsePackageStrings(extraPackages)
 	return err
 }
 
+// validateExistingCluster rejects flags that only apply to kind clusters created by idpbuilder.
+func validateExistingCluster(cmd *cobra.Command) error {
+	for _, f := range []string{"recreate", "kube-version", "kind-config", "extra-ports", "extra-clusters"} {
+		if cmd.Flags().Changed(f) {
+			return fmt.Errorf("--%s cannot be used with an existing cluster", f)
+		}
+	}
+
+	switch ingressServiceType {
+	case string(corev1.ServiceTypeLoadBalancer):
+		return nil
+	case string(corev1.ServiceTypeNodePort):
+		// the node port is the port in gitea and argocd URLs
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 30000 || p > 32767 {
+			return fmt.Errorf("port %s must be in the node port range 30000-32767 when the ingress service type is NodePort", port)
+		}
+		return nil
+	default:
+		return fmt.Errorf("ingress service type %s is not supported. must be LoadBalancer or NodePort", ingressServiceType)
+	}
+}
+
+func validateExtraClusters(names []string) error {
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return fmt.Errorf("invalid extra cluster name %s: %s", name, strings.Join(errs, ", "))
+		}
+		// in-cluster is the name ArgoCD uses for the cluster it runs in
+		if name == "in-cluster" {
+			return fmt.Errorf("invalid extra cluster name %s: reserved by ArgoCD", name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("extra cluster %s specified more than once", name)
+		}
+		seen[name] = struct{}{}
+	}
+	return nil
+}
+
 func getPackageCustomFile(input string) (v1alpha1.PackageCustomization, error) {
 	// the format should be `<package-name>:<path-to-file>`
 	s := strings.Split(input, ":")
@@ -0,0 +1,106 @@
+package credentialhelper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/pkg/build"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/get"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/util/homedir"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var CredentialHelperCmd = &cobra.Command{
+	Use:   "credential-helper",
+	Short: "git credential helper for the in-cluster Gitea",
+	Long: `Supplies the Gitea admin credential to git. Configure it with:
+  git config --global credential.https://gitea.cnoe.localtest.me:8443.helper '!idpbuilder credential-helper'`,
+}
+
+var getCmd = &cobra.Command{
+	Use:          "get",
+	Short:        "print the credential for the host read from standard input",
+	RunE:         getE,
+	SilenceUsage: true,
+}
+
+// git also calls store and erase. credentials are managed by idpbuilder so there is nothing to do.
+var noopCmds = []*cobra.Command{
+	{Use: "store", Hidden: true, RunE: noopE},
+	{Use: "erase", Hidden: true, RunE: noopE},
+}
+
+func init() {
+	CredentialHelperCmd.AddCommand(getCmd)
+	CredentialHelperCmd.AddCommand(noopCmds...)
+}
+
+func noopE(cmd *cobra.Command, args []string) error {
+	_, err := io.Copy(io.Discard, cmd.InOrStdin())
+	return err
+}
+
+func getE(cmd *cobra.Command, args []string) error {
+	ctx, ctxCancel := context.WithCancel(ctrl.SetupSignalHandler())
+	defer ctxCancel()
+
+	b := build.NewBuild(build.NewBuildOptions{
+		KubeConfigPath: filepath.Join(homedir.HomeDir(), ".kube", "config"),
+		Scheme:         k8s.GetScheme(),
+		CancelFunc:     ctxCancel,
+	})
+
+	kubeConfig, err := b.GetKubeConfig()
+	if err != nil {
+		return fmt.Errorf("getting kube config: %w", err)
+	}
+	kubeClient, err := b.GetKubeClient(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("getting kube client: %w", err)
+	}
+
+	cred, err := get.GetGiteaCredential(ctx, kubeClient)
+	if err != nil {
+		return err
+	}
+	return writeCredential(cmd.InOrStdin(), os.Stdout, cred)
+}
+
+// writeCredential reads a git credential request and answers it if it is for the Gitea host.
+// Nothing is written for other hosts so git can try the next helper.
+func writeCredential(in io.Reader, out io.Writer, cred get.GiteaCredential) error {
+	request, err := readRequest(in)
+	if err != nil {
+		return fmt.Errorf("reading credential request: %w", err)
+	}
+	if request["host"] != cred.Host {
+		return nil
+	}
+	_, err = fmt.Fprintf(out, "username=%s\npassword=%s\n", cred.Username, cred.Password)
+	return err
+}
+
+// readRequest parses key=value lines until a blank line or end of input.
+func readRequest(in io.Reader) (map[string]string, error) {
+	request := map[string]string{}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		request[k] = v
+	}
+	return request, scanner.Err()
+}
@@ -0,0 +1,27 @@
+package credentialhelper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/get"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCredential(t *testing.T) {
+	cred := get.GiteaCredential{Host: "gitea.cnoe.localtest.me:8443", Username: "giteaAdmin", Password: "abc"}
+
+	out := &bytes.Buffer{}
+	err := writeCredential(strings.NewReader("protocol=https\nhost=gitea.cnoe.localtest.me:8443\n\n"), out, cred)
+	assert.NoError(t, err)
+	assert.Equal(t, "username=giteaAdmin\npassword=abc\n", out.String())
+
+	out.Reset()
+	err = writeCredential(strings.NewReader("protocol=https\nhost=github.com\n"), out, cred)
+	assert.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	err = writeCredential(strings.NewReader("invalid\n"), out, cred)
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package debug
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// archive writes files to a gzipped tarball under a top level directory.
+type archive struct {
+	root string
+	gz   *gzip.Writer
+	tw   *tar.Writer
+	now  time.Time
+}
+
+func newArchive(w io.Writer, root string) *archive {
+	gz := gzip.NewWriter(w)
+	return &archive{
+		root: root,
+		gz:   gz,
+		tw:   tar.NewWriter(gz),
+		now:  time.Now(),
+	}
+}
+
+func (a *archive) addFile(name string, data []byte) error {
+	err := a.tw.WriteHeader(&tar.Header{
+		Name:    path.Join(a.root, name),
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: a.now,
+	})
+	if err != nil {
+		return fmt.Errorf("writing header for %s: %w", name, err)
+	}
+	_, err = a.tw.Write(data)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// addDir adds regular files under dir to the archive under prefix.
+func (a *archive) addDir(dir, prefix string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", p, err)
+		}
+		return a.addFile(path.Join(prefix, filepath.ToSlash(rel)), data)
+	})
+}
+
+func (a *archive) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
@@ -0,0 +1,384 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/version"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/localbuild"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	redactedValue = "REDACTED"
+	// podLogLimitBytes limits the size of each container log in the dump.
+	podLogLimitBytes = 10 * 1024 * 1024
+	giteaNamespace   = "gitea"
+)
+
+var (
+	// Flags
+	name       string
+	outputPath string
+)
+
+// resources dumped with their status. files are named after the resource.
+var dumpResources = []schema.GroupVersionKind{
+	v1alpha1.GroupVersion.WithKind("Localbuild"),
+	v1alpha1.GroupVersion.WithKind("GitRepository"),
+	v1alpha1.GroupVersion.WithKind("CustomPackage"),
+	{Group: "argoproj.io", Version: "v1alpha1", Kind: "Application"},
+	{Group: "argoproj.io", Version: "v1alpha1", Kind: "ApplicationSet"},
+	{Group: "argoproj.io", Version: "v1alpha1", Kind: "AppProject"},
+}
+
+// namespaces of core packages whose pod logs and secrets are collected.
+var dumpNamespaces = []string{globals.ArgoCDNamespace, giteaNamespace, globals.NginxNamespace}
+
+var DumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Write a tarball with logs, resources, and configuration of an IDP cluster",
+	Long: "Write a tarball with kind node logs, idpbuilder and ArgoCD resources with their status and events, " +
+		"core package pod logs, the rendered kind config, and version information. Secret values, account passwords, " +
+		"and helm values are redacted. " +
+		"Attach it to bug reports.",
+	RunE:    dumpE,
+	PreRunE: preDumpE,
+}
+
+func init() {
+	DumpCmd.Flags().StringVar(&name, "name", "localdev", "Name of the kind cluster to collect diagnostics from.")
+	DumpCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Path of the tarball to write. Defaults to idpbuilder-dump-<name>-<time>.tar.gz in the current directory.")
+}
+
+func preDumpE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+// dumper collects diagnostics into an archive. Failures are recorded in the archive instead of stopping the dump.
+type dumper struct {
+	archive    *archive
+	kubeClient client.Client
+	clientset  kubernetes.Interface
+	errs       []string
+}
+
+func dumpE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if outputPath == "" {
+		outputPath = fmt.Sprintf("%s-dump-%s-%s.tar.gz", globals.ProjectName, name, time.Now().Format("20060102-150405"))
+	}
+
+	provider, err := kind.NewProvider(ctx)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", outputPath, err)
+	}
+	defer f.Close()
+
+	d := &dumper{archive: newArchive(f, strings.TrimSuffix(path.Base(outputPath), ".tar.gz"))}
+	d.collect("version", d.dumpVersion)
+	d.collect("kind node logs", func() error { return d.dumpKindLogs(provider) })
+
+	restConfig, err := kind.GetRESTConfig(provider, name)
+	if err != nil {
+		d.recordError("cluster access", err)
+	} else {
+		d.kubeClient, err = client.New(restConfig, client.Options{Scheme: k8s.GetScheme()})
+		if err != nil {
+			return fmt.Errorf("creating kube client: %w", err)
+		}
+		d.clientset, err = kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("creating kube clientset: %w", err)
+		}
+
+		for _, gvk := range dumpResources {
+			d.collect(gvk.Kind, func() error { return d.dumpResources(ctx, gvk) })
+		}
+		d.collect("events", func() error { return d.dumpEvents(ctx) })
+		d.collect("argocd application status", func() error { return d.dumpApplicationStatus(ctx) })
+		d.collect("kind config", func() error { return d.dumpKindConfig(ctx) })
+		d.collect("secrets", func() error { return d.dumpSecrets(ctx) })
+		for _, ns := range dumpNamespaces {
+			d.collect("pod logs in "+ns, func() error { return d.dumpPodLogs(ctx, ns) })
+		}
+	}
+
+	if len(d.errs) > 0 {
+		if err = d.archive.addFile("errors.txt", []byte(strings.Join(d.errs, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+	if err = d.archive.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", outputPath, err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "wrote diagnostics to %s\n", outputPath)
+	return nil
+}
+
+func (d *dumper) collect(what string, f func() error) {
+	helpers.CmdLogger.V(1).Info("collecting", "what", what)
+	if err := f(); err != nil {
+		d.recordError(what, err)
+	}
+}
+
+func (d *dumper) recordError(what string, err error) {
+	helpers.CmdLogger.Info("failed collecting diagnostics", "what", what, "err", err)
+	d.errs = append(d.errs, fmt.Sprintf("%s: %s", what, err))
+}
+
+func (d *dumper) dumpVersion() error {
+	info, err := version.JSONInfo()
+	if err != nil {
+		return err
+	}
+	return d.archive.addFile("version.json", []byte(info+"\n"))
+}
+
+func (d *dumper) dumpKindLogs(provider *cluster.Provider) error {
+	dir, err := os.MkdirTemp("", fmt.Sprintf("%s-dump-", globals.ProjectName))
+	if err != nil {
+		return fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = provider.CollectLogs(name, dir); err != nil {
+		return fmt.Errorf("collecting logs of %s: %w", name, err)
+	}
+	return d.archive.addDir(dir, "kind-logs")
+}
+
+func (d *dumper) dumpResources(ctx context.Context, gvk schema.GroupVersionKind) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := d.kubeClient.List(ctx, list); err != nil {
+		return fmt.Errorf("listing %s: %w", gvk.Kind, err)
+	}
+
+	for i := range list.Items {
+		list.Items[i].SetManagedFields(nil)
+		redactObject(&list.Items[i])
+	}
+	out, err := yaml.Marshal(list.Items)
+	if err != nil {
+		return fmt.Errorf("marshalling %s: %w", gvk.Kind, err)
+	}
+	return d.archive.addFile(path.Join("resources", strings.ToLower(gvk.Kind)+".yaml"), out)
+}
+
+// dumpEvents writes events about idpbuilder and ArgoCD resources, oldest first.
+func (d *dumper) dumpEvents(ctx context.Context) error {
+	events := &corev1.EventList{}
+	if err := d.kubeClient.List(ctx, events); err != nil {
+		return fmt.Errorf("listing events: %w", err)
+	}
+
+	out := filterEvents(events.Items, v1alpha1.GroupVersion.Group, "argoproj.io")
+	sort.SliceStable(out, func(i, j int) bool {
+		return eventTime(out[i]).Before(eventTime(out[j]))
+	})
+
+	b := strings.Builder{}
+	for _, e := range out {
+		fmt.Fprintf(&b, "%s %s %s %s %s/%s: %s\n", eventTime(e).Format(time.RFC3339), e.Type, e.Reason,
+			e.InvolvedObject.Kind, e.InvolvedObject.Namespace, e.InvolvedObject.Name, e.Message)
+	}
+	return d.archive.addFile("events.txt", []byte(b.String()))
+}
+
+func (d *dumper) dumpApplicationStatus(ctx context.Context) error {
+	apps, err := localbuild.GetUnhealthyApplications(ctx, d.kubeClient)
+	if err != nil {
+		return err
+	}
+
+	b := strings.Builder{}
+	if len(apps) == 0 {
+		b.WriteString("all applications are synced and healthy\n")
+	}
+	for i := range apps {
+		b.WriteString(apps[i].String() + "\n")
+	}
+	return d.archive.addFile("argocd-unhealthy-applications.txt", []byte(b.String()))
+}
+
+func (d *dumper) dumpKindConfig(ctx context.Context) error {
+	cm := &corev1.ConfigMap{}
+	err := d.kubeClient.Get(ctx, client.ObjectKey{Name: globals.KindConfigCMName, Namespace: globals.KindConfigCMNamespace}, cm)
+	if err != nil {
+		return fmt.Errorf("getting configmap %s: %w", globals.KindConfigCMName, err)
+	}
+	return d.archive.addFile("kind-config.yaml", []byte(cm.Data[globals.KindConfigCMKeyName]))
+}
+
+// dumpSecrets writes secrets of core packages and secrets created for packages with their values redacted.
+func (d *dumper) dumpSecrets(ctx context.Context) error {
+	secrets := make([]corev1.Secret, 0)
+	for _, ns := range dumpNamespaces {
+		list := &corev1.SecretList{}
+		if err := d.kubeClient.List(ctx, list, client.InNamespace(ns)); err != nil {
+			return fmt.Errorf("listing secrets in %s: %w", ns, err)
+		}
+		secrets = append(secrets, list.Items...)
+	}
+
+	list := &corev1.SecretList{}
+	if err := d.kubeClient.List(ctx, list, client.MatchingLabels{v1alpha1.CLISecretLabelKey: v1alpha1.CLISecretLabelValue}); err != nil {
+		return fmt.Errorf("listing secrets: %w", err)
+	}
+	secrets = append(secrets, list.Items...)
+
+	out, err := yaml.Marshal(redactSecrets(secrets))
+	if err != nil {
+		return fmt.Errorf("marshalling secrets: %w", err)
+	}
+	return d.archive.addFile("secrets.yaml", out)
+}
+
+func (d *dumper) dumpPodLogs(ctx context.Context, ns string) error {
+	pods := &corev1.PodList{}
+	if err := d.kubeClient.List(ctx, pods, client.InNamespace(ns)); err != nil {
+		return fmt.Errorf("listing pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		containers := make([]corev1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+		containers = append(containers, pod.Spec.InitContainers...)
+		containers = append(containers, pod.Spec.Containers...)
+		for _, c := range containers {
+			logs, err := d.podLogs(ctx, pod, c.Name)
+			if err != nil {
+				d.recordError(fmt.Sprintf("logs of %s/%s/%s", ns, pod.Name, c.Name), err)
+				continue
+			}
+			if err = d.archive.addFile(path.Join("pod-logs", ns, pod.Name, c.Name+".log"), logs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *dumper) podLogs(ctx context.Context, pod corev1.Pod, container string) ([]byte, error) {
+	limit := int64(podLogLimitBytes)
+	req := d.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  container,
+		LimitBytes: &limit,
+	})
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	return io.ReadAll(stream)
+}
+
+// filterEvents returns events about objects in the given API groups.
+func filterEvents(events []corev1.Event, groups ...string) []corev1.Event {
+	out := make([]corev1.Event, 0)
+	for _, e := range events {
+		gv, err := schema.ParseGroupVersion(e.InvolvedObject.APIVersion)
+		if err != nil {
+			continue
+		}
+		for _, g := range groups {
+			if gv.Group == g {
+				out = append(out, e)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func eventTime(e corev1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+// redactObject replaces values that may hold credentials in resources other than secrets.
+// Passwords of accounts in Localbuild specs and helm values of applications are redacted wherever they appear.
+func redactObject(obj *unstructured.Unstructured) {
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[corev1.LastAppliedConfigAnnotation]; ok {
+		delete(annotations, corev1.LastAppliedConfigAnnotation)
+		obj.SetAnnotations(annotations)
+	}
+	redactFields(obj.Object, false)
+}
+
+func redactFields(v any, inHelm bool) {
+	switch val := v.(type) {
+	case map[string]any:
+		for k := range val {
+			switch {
+			case k == "password" && val[k] != "":
+				val[k] = redactedValue
+			case inHelm && (k == "values" || k == "valuesObject"):
+				val[k] = redactedValue
+			default:
+				redactFields(val[k], k == "helm")
+			}
+		}
+	case []any:
+		for i := range val {
+			redactFields(val[i], false)
+		}
+	}
+}
+
+// redactSecrets returns copies of secrets with values replaced. Keys are kept to show what each secret contains.
+func redactSecrets(secrets []corev1.Secret) []corev1.Secret {
+	out := make([]corev1.Secret, 0, len(secrets))
+	seen := make(map[client.ObjectKey]struct{}, len(secrets))
+	for i := range secrets {
+		s := secrets[i].DeepCopy()
+		key := client.ObjectKeyFromObject(s)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		s.SetManagedFields(nil)
+		// the last applied configuration annotation contains the values
+		delete(s.Annotations, corev1.LastAppliedConfigAnnotation)
+		for k := range s.Data {
+			s.Data[k] = []byte(redactedValue)
+		}
+		for k := range s.StringData {
+			s.StringData[k] = redactedValue
+		}
+		out = append(out, *s)
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	s := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "gitea-credential",
+			Namespace:   "gitea",
+			Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: "password", "keep": "me"},
+		},
+		Data:       map[string][]byte{"password": []byte("secret")},
+		StringData: map[string]string{"token": "secret"},
+	}
+
+	out := redactSecrets([]corev1.Secret{s, s})
+	require.Len(t, out, 1)
+	assert.Equal(t, []byte(redactedValue), out[0].Data["password"])
+	assert.Equal(t, redactedValue, out[0].StringData["token"])
+	assert.Equal(t, map[string]string{"keep": "me"}, out[0].Annotations)
+	// input is not modified
+	assert.Equal(t, []byte("secret"), s.Data["password"])
+}
+
+func TestRedactObject(t *testing.T) {
+	manifest := `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: app
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: "{}"
+spec:
+  sources:
+  - repoURL: https://charts.example.com
+    helm:
+      releaseName: app
+      values: |
+        token: secret
+      valuesObject:
+        token: secret
+  packageConfigs:
+    accounts:
+      argocd:
+        admin:
+          username: admin
+          password: secret
+`
+	obj := &unstructured.Unstructured{}
+	require.NoError(t, yaml.Unmarshal([]byte(manifest), &obj.Object))
+	redactObject(obj)
+
+	assert.NotContains(t, obj.GetAnnotations(), corev1.LastAppliedConfigAnnotation)
+	helm, _, _ := unstructured.NestedMap(obj.Object["spec"].(map[string]any)["sources"].([]any)[0].(map[string]any), "helm")
+	assert.Equal(t, map[string]any{"releaseName": "app", "values": redactedValue, "valuesObject": redactedValue}, helm)
+	pass, _, _ := unstructured.NestedString(obj.Object, "spec", "packageConfigs", "accounts", "argocd", "admin", "password")
+	assert.Equal(t, redactedValue, pass)
+	user, _, _ := unstructured.NestedString(obj.Object, "spec", "packageConfigs", "accounts", "argocd", "admin", "username")
+	assert.Equal(t, "admin", user)
+}
+
+func TestFilterEvents(t *testing.T) {
+	event := func(apiVersion, name string, ts time.Time) corev1.Event {
+		return corev1.Event{
+			InvolvedObject: corev1.ObjectReference{APIVersion: apiVersion, Name: name},
+			LastTimestamp:  metav1.NewTime(ts),
+		}
+	}
+	now := time.Now()
+	out := filterEvents([]corev1.Event{
+		event("idpbuilder.cnoe.io/v1alpha1", "pkg", now),
+		event("v1", "pod", now),
+		event("argoproj.io/v1alpha1", "app", now),
+	}, "idpbuilder.cnoe.io", "argoproj.io")
+
+	require.Len(t, out, 2)
+	assert.Equal(t, "pkg", out[0].InvolvedObject.Name)
+	assert.Equal(t, "app", out[1].InvolvedObject.Name)
+	assert.Equal(t, now.Unix(), eventTime(out[0]).Unix())
+}
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node", "kubelet.log"), []byte("kubelet"), 0644))
+
+	buf := &bytes.Buffer{}
+	a := newArchive(buf, "dump")
+	require.NoError(t, a.addFile("version.json", []byte("{}")))
+	require.NoError(t, a.addDir(dir, "kind-logs"))
+	require.NoError(t, a.Close())
+
+	gz, err := gzip.NewReader(buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	files := map[string]string{}
+	for {
+		h, rErr := tr.Next()
+		if rErr == io.EOF {
+			break
+		}
+		require.NoError(t, rErr)
+		b, rErr := io.ReadAll(tr)
+		require.NoError(t, rErr)
+		files[h.Name] = string(b)
+	}
+	assert.Equal(t, map[string]string{
+		"dump/version.json":               "{}",
+		"dump/kind-logs/node/kubelet.log": "kubelet",
+	}, files)
+}
@@ -1,44 +1,277 @@
 package delete
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/cnoe-io/idpbuilder/pkg/runtime"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/util/homedir"
 	"sigs.k8s.io/kind/pkg/cluster"
 )
 
 var (
 	// Flags
-	name string
+	name   string
+	all    bool
+	dryRun bool
 )
 
 var DeleteCmd = &cobra.Command{
-	Use:     "delete",
-	Short:   "Delete an IDP cluster",
-	Long:    ``,
+	Use:   "delete",
+	Short: "Delete an IDP cluster",
+	Long: `Delete the kind cluster, its extra clusters and their kubeconfig entries.
+Temporary directories of the build left behind by runs that did not exit cleanly are removed too.`,
 	RunE:    deleteE,
 	PreRunE: preDeleteE,
 }
 
 func init() {
 	DeleteCmd.PersistentFlags().StringVar(&name, "name", "localdev", "Name of the kind cluster to be deleted.")
+	DeleteCmd.Flags().BoolVar(&all, "all", false, "Delete all clusters created by idpbuilder, all idpbuilder temporary directories and the repository cache. "+
+		"Clusters whose nodes cannot be checked are reported and left alone.")
+	DeleteCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print what would be deleted without deleting anything.")
 }
 
 func preDeleteE(cmd *cobra.Command, args []string) error {
+	if all && cmd.Flags().Changed("name") {
+		return fmt.Errorf("--name and --all cannot be set together")
+	}
 	return helpers.SetLogger()
 }
 
+// hostResource is something idpbuilder created on the host.
+type hostResource struct {
+	kind   string
+	name   string
+	remove func() error
+	// skipped is set when the resource may belong to idpbuilder but cannot be checked. It is reported and left alone.
+	skipped error
+}
+
 func deleteE(cmd *cobra.Command, args []string) error {
-	logger := helpers.CmdLogger
-	logger.Info("deleting cluster", "clusterName", name)
-	detectOpt, err := cluster.DetectNodeProvider()
+	ctx := cmd.Context()
+	provider, err := kind.NewProvider(ctx)
+	if err != nil {
+		return err
+	}
+
+	var resources []hostResource
+	if all {
+		rt, rErr := runtime.DetectRuntime()
+		if rErr != nil {
+			return rErr
+		}
+		resources, err = allResources(ctx, rt, provider)
+	} else {
+		resources, err = buildResources(ctx, provider, name)
+	}
+	if err != nil {
+		return err
+	}
+	return removeResources(cmd.OutOrStdout(), resources, dryRun)
+}
+
+// buildResources returns the clusters of the named build and what they left on the host.
+func buildResources(ctx context.Context, provider *cluster.Provider, buildName string) ([]hostResource, error) {
+	clusters, err := provider.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	names := []string{buildName}
+	for _, c := range clusters {
+		if !strings.HasPrefix(c, buildName+"-") {
+			continue
+		}
+		b, bErr := kind.GetBuildName(ctx, provider, c)
+		if bErr != nil {
+			helpers.CmdLogger.V(1).Info("cannot query cluster", "cluster", c, "err", bErr)
+			continue
+		}
+		if b == buildName {
+			names = append(names, c)
+		}
+	}
+
+	resources, err := clusterResources(provider, kubeConfigPath(), clusters, names)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := tempDirs(os.TempDir(), regexp.MustCompile(fmt.Sprintf(`^%s-%s-\d+$`, globals.ProjectName, regexp.QuoteMeta(buildName))))
+	if err != nil {
+		return nil, err
+	}
+	return append(resources, dirs...), nil
+}
+
+// allResources returns every cluster created by idpbuilder, all temporary directories and the repository cache.
+func allResources(ctx context.Context, rt runtime.IRuntime, provider *cluster.Provider) ([]hostResource, error) {
+	clusters, err := provider.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	names, unchecked := createdClusters(ctx, rt, provider, clusters)
+	resources, err := clusterResources(provider, kubeConfigPath(), clusters, names)
+	if err != nil {
+		return nil, err
+	}
+	resources = append(resources, unchecked...)
+	dirs, err := tempDirs(os.TempDir(), regexp.MustCompile(fmt.Sprintf(`^%s-.+-\d+$`, globals.ProjectName)))
+	if err != nil {
+		return nil, err
+	}
+	resources = append(resources, dirs...)
+
+	cacheDir, err := util.DefaultRepoCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	if util.Exists(cacheDir) {
+		resources = append(resources, hostResource{
+			kind:   "repository cache",
+			name:   cacheDir,
+			remove: func() error { return clearRepoCache(cacheDir) },
+		})
+	}
+	return resources, nil
+}
+
+// createdClusters returns the names of clusters created by idpbuilder and the clusters that cannot be checked.
+func createdClusters(ctx context.Context, rt runtime.IRuntime, provider kind.IProvider, clusters []string) ([]string, []hostResource) {
+	var names []string
+	var unchecked []hostResource
+	for _, c := range clusters {
+		ok, err := kind.IsCreatedByIdpbuilder(ctx, rt, provider, c)
+		if err != nil {
+			unchecked = append(unchecked, hostResource{
+				kind:    "cluster",
+				name:    c,
+				skipped: fmt.Errorf("cannot check if idpbuilder created it: %w", err),
+			})
+			continue
+		}
+		if ok {
+			names = append(names, c)
+		}
+	}
+	return names, unchecked
+}
+
+// clearRepoCache removes the cached repositories. Repositories in use by running idpbuilder processes are kept.
+func clearRepoCache(dir string) error {
+	kept, err := util.ClearRepoCache(dir)
 	if err != nil {
 		return err
 	}
-	provider := cluster.NewProvider(detectOpt)
-	if err := provider.Delete(name, ""); err != nil {
-		return fmt.Errorf("failed to delete cluster %s: %w", name, err)
+	for i := range kept {
+		helpers.CmdLogger.Info("keeping cached repository in use by another idpbuilder process", "dir", kept[i].Path)
+	}
+	return nil
+}
+
+// clusterResources returns the named clusters that exist and the kubeconfig entries of the named clusters.
+func clusterResources(provider kind.IProvider, kubeConfigPath string, existing, names []string) ([]hostResource, error) {
+	resources := make([]hostResource, 0, len(names)*2)
+	for i := range names {
+		n := names[i]
+		for _, c := range existing {
+			if c != n {
+				continue
+			}
+			resources = append(resources, hostResource{
+				kind:   "cluster",
+				name:   n,
+				remove: func() error { return provider.Delete(n, kubeConfigPath) },
+			})
+		}
+
+		ok, err := kind.HasKubeConfig(kubeConfigPath, n)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			resources = append(resources, hostResource{
+				kind:   "kubeconfig entry",
+				name:   fmt.Sprintf("%s in %s", n, kubeConfigPath),
+				remove: func() error { return kind.RemoveKubeConfig(kubeConfigPath, n) },
+			})
+		}
+	}
+	return resources, nil
+}
+
+// kubeConfigPath is where create exports kubeconfig entries to.
+func kubeConfigPath() string {
+	return filepath.Join(homedir.HomeDir(), ".kube", "config")
+}
+
+// tempDirs returns directories in parent whose names match pattern.
+// Directories in use by running idpbuilder processes are reported and left alone.
+func tempDirs(parent string, pattern *regexp.Regexp) ([]hostResource, error) {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", parent, err)
+	}
+
+	var resources []hostResource
+	for i := range entries {
+		if !entries[i].IsDir() || !pattern.MatchString(entries[i].Name()) {
+			continue
+		}
+		dir := filepath.Join(parent, entries[i].Name())
+		inUse, err := util.IsDirInUse(dir)
+		if err != nil {
+			return nil, err
+		}
+		if inUse {
+			resources = append(resources, hostResource{
+				kind:    "temporary directory",
+				name:    dir,
+				skipped: errors.New("in use by a running idpbuilder process"),
+			})
+			continue
+		}
+		resources = append(resources, hostResource{
+			kind:   "temporary directory",
+			name:   dir,
+			remove: func() error { return os.RemoveAll(dir) },
+		})
+	}
+	return resources, nil
+}
+
+func removeResources(out io.Writer, resources []hostResource, dryRun bool) error {
+	if len(resources) == 0 {
+		fmt.Fprintln(out, "nothing to delete")
+		return nil
+	}
+
+	for i := range resources {
+		r := resources[i]
+		if r.skipped != nil {
+			fmt.Fprintf(out, "skipping %s %s: %v\n", r.kind, r.name, r.skipped)
+			continue
+		}
+		if dryRun {
+			fmt.Fprintf(out, "would delete %s %s\n", r.kind, r.name)
+			continue
+		}
+		if err := r.remove(); err != nil {
+			return fmt.Errorf("failed to delete %s %s: %w", r.kind, r.name, err)
+		}
+		fmt.Fprintf(out, "deleted %s %s\n", r.kind, r.name)
 	}
 	return nil
 }
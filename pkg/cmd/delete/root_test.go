@@ -0,0 +1,105 @@
+package delete
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type fakeProvider struct {
+	kind.IProvider
+	mock.Mock
+}
+
+func (f *fakeProvider) Delete(name, kubeConfigPath string) error {
+	return f.Called(name, kubeConfigPath).Error(0)
+}
+
+func TestTempDirs(t *testing.T) {
+	parent := t.TempDir()
+	for _, d := range []string{"idpbuilder-localdev-123", "idpbuilder-localdev-staging-456", "idpbuilder-dump-789", "idpbuilder-localdev-abc", "other-localdev-123"} {
+		require.NoError(t, os.Mkdir(filepath.Join(parent, d), 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(parent, "idpbuilder-localdev-999"), nil, 0644))
+
+	resources, err := tempDirs(parent, regexp.MustCompile(`^idpbuilder-localdev-\d+$`))
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, filepath.Join(parent, "idpbuilder-localdev-123"), resources[0].name)
+
+	resources, err = tempDirs(parent, regexp.MustCompile(`^idpbuilder-.+-\d+$`))
+	require.NoError(t, err)
+	assert.Len(t, resources, 3)
+
+	// directories of running builds are left alone
+	l, err := util.LockDir(filepath.Join(parent, "idpbuilder-localdev-123"))
+	require.NoError(t, err)
+	defer l.Unlock()
+	resources, err = tempDirs(parent, regexp.MustCompile(`^idpbuilder-localdev-\d+$`))
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	out := bytes.Buffer{}
+	require.NoError(t, removeResources(&out, resources, false))
+	assert.Equal(t, "skipping temporary directory "+filepath.Join(parent, "idpbuilder-localdev-123")+": in use by a running idpbuilder process\n", out.String())
+	assert.DirExists(t, filepath.Join(parent, "idpbuilder-localdev-123"))
+}
+
+func TestClusterResources(t *testing.T) {
+	kubeConfigPath := filepath.Join(t.TempDir(), "config")
+	cfg := clientcmdapi.NewConfig()
+	cfg.Contexts["kind-localdev"] = &clientcmdapi.Context{Cluster: "kind-localdev"}
+	cfg.Contexts["kind-stale"] = &clientcmdapi.Context{Cluster: "kind-stale"}
+	require.NoError(t, clientcmd.WriteToFile(*cfg, kubeConfigPath))
+
+	provider := new(fakeProvider)
+	provider.On("Delete", "localdev", kubeConfigPath).Return(nil)
+
+	resources, err := clusterResources(provider, kubeConfigPath, []string{"localdev", "unrelated"}, []string{"localdev", "stale"})
+	require.NoError(t, err)
+
+	out := bytes.Buffer{}
+	require.NoError(t, removeResources(&out, resources, true))
+	assert.Equal(t, "would delete cluster localdev\n"+
+		"would delete kubeconfig entry localdev in "+kubeConfigPath+"\n"+
+		"would delete kubeconfig entry stale in "+kubeConfigPath+"\n", out.String())
+	provider.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	out.Reset()
+	require.NoError(t, removeResources(&out, resources, false))
+	provider.AssertExpectations(t)
+	remaining, err := clientcmd.LoadFromFile(kubeConfigPath)
+	require.NoError(t, err)
+	assert.Empty(t, remaining.Contexts)
+}
+
+func TestRemoveResourcesNothing(t *testing.T) {
+	out := bytes.Buffer{}
+	require.NoError(t, removeResources(&out, nil, false))
+	assert.Equal(t, "nothing to delete\n", out.String())
+}
+
+func TestRemoveResourcesSkipped(t *testing.T) {
+	resources := []hostResource{{
+		kind:    "cluster",
+		name:    "stopped",
+		skipped: errors.New("cannot check if idpbuilder created it: container not found"),
+		remove:  func() error { return errors.New("must not be called") },
+	}}
+
+	for _, dryRun := range []bool{true, false} {
+		out := bytes.Buffer{}
+		require.NoError(t, removeResources(&out, resources, dryRun))
+		assert.Equal(t, "skipping cluster stopped: cannot check if idpbuilder created it: container not found\n", out.String())
+	}
+}
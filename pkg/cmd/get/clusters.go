@@ -1,11 +1,26 @@
 package get
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/build"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
 	"github.com/spf13/cobra"
-	"sigs.k8s.io/kind/pkg/cluster"
+	v1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	clusterTemplatePath = "templates/clusters.tmpl"
+	inClusterName       = "in-cluster"
+	inClusterServer     = "https://kubernetes.default.svc"
 )
 
 var ClustersCmd = &cobra.Command{
@@ -16,19 +31,90 @@ var ClustersCmd = &cobra.Command{
 	PreRunE: preClustersE,
 }
 
+type ClusterTemplateData struct {
+	Name string `json:"name"`
+	// BuildName is set when the cluster is an extra cluster of another build.
+	BuildName    string               `json:"buildName,omitempty"`
+	Destinations []ClusterDestination `json:"destinations,omitempty"`
+}
+
+// ClusterDestination is a cluster registered with ArgoCD.
+type ClusterDestination struct {
+	Name   string `json:"name"`
+	Server string `json:"server"`
+}
+
 func preClustersE(cmd *cobra.Command, args []string) error {
 	return helpers.SetLogger()
 }
 
 func list(cmd *cobra.Command, args []string) error {
-	provider := cluster.NewProvider(cluster.ProviderWithDocker())
+	ctx, ctxCancel := context.WithCancel(ctrl.SetupSignalHandler())
+	defer ctxCancel()
+
+	provider, err := kind.NewProvider(ctx)
+	if err != nil {
+		return err
+	}
 	clusters, err := provider.List()
 	if err != nil {
 		return fmt.Errorf("failed to list clusters: %w", err)
 	}
 
-	for _, c := range clusters {
-		fmt.Println(c)
+	return printClusters(ctx, os.Stdout, provider, clusters, outputFormat)
+}
+
+func printClusters(ctx context.Context, outWriter io.Writer, provider kind.IProvider, clusters []string, format string) error {
+	clustersToPrint := make([]any, 0, len(clusters))
+	for _, name := range clusters {
+		data := ClusterTemplateData{Name: name}
+
+		// clusters that are not running cannot be queried. still list them by name.
+		kubeClient, err := kind.GetKubeClient(provider, name)
+		if err != nil {
+			helpers.CmdLogger.V(1).Info("cannot query cluster", "cluster", name, "err", err)
+			clustersToPrint = append(clustersToPrint, data)
+			continue
+		}
+
+		data.BuildName, err = kind.GetBuildName(ctx, provider, name)
+		if err != nil {
+			helpers.CmdLogger.V(1).Info("cannot query cluster", "cluster", name, "err", err)
+			clustersToPrint = append(clustersToPrint, data)
+			continue
+		}
+
+		if data.BuildName == "" {
+			data.Destinations, err = getArgoCDDestinations(ctx, kubeClient)
+			if err != nil {
+				helpers.CmdLogger.V(1).Info("cannot list ArgoCD clusters", "cluster", name, "err", err)
+			}
+		}
+		clustersToPrint = append(clustersToPrint, data)
+	}
+
+	return printOutput(clusterTemplatePath, outWriter, clustersToPrint, format)
+}
+
+// getArgoCDDestinations returns the clusters ArgoCD can deploy to, including the cluster it runs in.
+func getArgoCDDestinations(ctx context.Context, kubeClient client.Client) ([]ClusterDestination, error) {
+	secrets := v1.SecretList{}
+	err := kubeClient.List(ctx, &secrets, client.InNamespace(globals.ArgoCDNamespace),
+		client.MatchingLabels{build.ArgoCDClusterSecretTypeLabelKey: build.ArgoCDClusterSecretTypeLabelValue})
+	if err != nil {
+		return nil, fmt.Errorf("listing cluster secrets: %w", err)
+	}
+
+	out := []ClusterDestination{{Name: inClusterName, Server: inClusterServer}}
+	for i := range secrets.Items {
+		s := secrets.Items[i]
+		out = append(out, ClusterDestination{
+			Name:   string(s.Data["name"]),
+			Server: string(s.Data["server"]),
+		})
 	}
-	return nil
+	sort.Slice(out[1:], func(i, j int) bool {
+		return out[i+1].Name < out[j+1].Name
+	})
+	return out, nil
 }
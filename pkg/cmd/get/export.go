@@ -0,0 +1,156 @@
+package get
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	outputEnv          = "env"
+	outputNetrc        = "netrc"
+	outputDockerConfig = "docker-config"
+
+	giteaNamespace = "gitea"
+	giteaKindHost  = "gitea.cnoe.localtest.me"
+)
+
+// GiteaCredential is the admin credential of the Gitea instance and the host it is served on.
+type GiteaCredential struct {
+	Host     string
+	Username string
+	Password string
+}
+
+type dockerConfig struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+type dockerAuth struct {
+	Auth string `json:"auth"`
+}
+
+// printSecrets writes the secrets in the requested format.
+func printSecrets(ctx context.Context, outWriter io.Writer, kubeClient client.Client, data []any, format string) error {
+	switch format {
+	case outputEnv:
+		return printEnv(outWriter, data)
+	case outputNetrc, outputDockerConfig:
+		cred, err := giteaCredentialFromData(ctx, kubeClient, data)
+		if err != nil {
+			return err
+		}
+		if format == outputNetrc {
+			return printNetrc(outWriter, cred)
+		}
+		return printDockerConfig(outWriter, cred)
+	default:
+		return printOutput(secretTemplatePath, outWriter, data, format)
+	}
+}
+
+// printEnv writes one NAME_KEY="value" line per secret key.
+func printEnv(outWriter io.Writer, data []any) error {
+	for i := range data {
+		d, ok := data[i].(TemplateData)
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(d.Data))
+		for k := range d.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_, err := fmt.Fprintf(outWriter, "%s=%s\n", envName(d.Name, k), strconv.Quote(d.Data[k]))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func envName(parts ...string) string {
+	name := strings.ToUpper(strings.Join(parts, "_"))
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func printNetrc(outWriter io.Writer, cred GiteaCredential) error {
+	hostname, _, _ := strings.Cut(cred.Host, ":")
+	_, err := fmt.Fprintf(outWriter, "machine %s\nlogin %s\npassword %s\n", hostname, cred.Username, cred.Password)
+	return err
+}
+
+func printDockerConfig(outWriter io.Writer, cred GiteaCredential) error {
+	cfg := dockerConfig{
+		Auths: map[string]dockerAuth{
+			cred.Host: {Auth: base64.StdEncoding.EncodeToString([]byte(cred.Username + ":" + cred.Password))},
+		},
+	}
+	enc := json.NewEncoder(outWriter)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg)
+}
+
+// giteaCredentialFromData returns the Gitea credential if it is among the selected secrets.
+func giteaCredentialFromData(ctx context.Context, kubeClient client.Client, data []any) (GiteaCredential, error) {
+	for i := range data {
+		d, ok := data[i].(TemplateData)
+		if !ok || d.Name != giteaAdminSecretName || d.Namespace != giteaNamespace {
+			continue
+		}
+		host, err := getGiteaHost(ctx, kubeClient)
+		if err != nil {
+			return GiteaCredential{}, err
+		}
+		return GiteaCredential{Host: host, Username: d.Data["username"], Password: d.Data["password"]}, nil
+	}
+	return GiteaCredential{}, fmt.Errorf("gitea credential not found. include the gitea package")
+}
+
+// GetGiteaCredential returns the Gitea admin credential and the host Gitea is reachable on.
+func GetGiteaCredential(ctx context.Context, kubeClient client.Client) (GiteaCredential, error) {
+	secret, err := getCorePackageSecret(ctx, kubeClient, giteaNamespace, giteaAdminSecretName)
+	if err != nil {
+		return GiteaCredential{}, fmt.Errorf("getting gitea credential: %w", err)
+	}
+	return giteaCredentialFromData(ctx, kubeClient, []any{secretToTemplateData(secret)})
+}
+
+// getGiteaHost returns the host and port Gitea is reachable on from the machine running idpbuilder.
+// kind clusters map the port to localhost. existing clusters are reached through the ingress service,
+// which the host name gitea is configured with must resolve to.
+func getGiteaHost(ctx context.Context, kubeClient client.Client) (string, error) {
+	builds := v1alpha1.LocalbuildList{}
+	if err := kubeClient.List(ctx, &builds); err != nil {
+		return "", fmt.Errorf("listing localbuilds: %w", err)
+	}
+	for i := range builds.Items {
+		config := builds.Items[i].Spec.BuildCustomization
+		if config.Host == "" || config.Port == "" {
+			continue
+		}
+		if config.IngressServiceType == "" {
+			return giteaKindHost + ":" + config.Port, nil
+		}
+		if config.UsePathRouting {
+			return config.Host + ":" + config.Port, nil
+		}
+		return "gitea." + config.Host + ":" + config.Port, nil
+	}
+	return "", fmt.Errorf("gitea host not found in localbuilds")
+}
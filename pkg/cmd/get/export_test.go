@@ -0,0 +1,102 @@
+package get
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPrintSecretsExportFormats(t *testing.T) {
+	ctx := context.Background()
+	data := []any{
+		TemplateData{
+			Name:      giteaAdminSecretName,
+			Namespace: giteaNamespace,
+			Data:      map[string]string{"username": "giteaAdmin", "password": `p"ss`},
+		},
+		TemplateData{
+			Name:      "my-app.db",
+			Namespace: "my-app",
+			Data:      map[string]string{"password": "abc"},
+		},
+	}
+
+	fClient := localbuildClient(ctx, v1alpha1.BuildCustomizationSpec{Protocol: "https", Host: "cnoe.localtest.me", Port: "8443"})
+
+	cases := map[string]string{
+		outputEnv: `GITEA_CREDENTIAL_PASSWORD="p\"ss"
+GITEA_CREDENTIAL_USERNAME="giteaAdmin"
+MY_APP_DB_PASSWORD="abc"
+`,
+		outputNetrc: `machine gitea.cnoe.localtest.me
+login giteaAdmin
+password p"ss
+`,
+		outputDockerConfig: `{
+  "auths": {
+    "gitea.cnoe.localtest.me:8443": {
+      "auth": "Z2l0ZWFBZG1pbjpwInNz"
+    }
+  }
+}
+`,
+	}
+
+	for format, expected := range cases {
+		buffer := &bytes.Buffer{}
+		err := printSecrets(ctx, buffer, fClient, data, format)
+		assert.NoError(t, err, format)
+		assert.Equal(t, expected, buffer.String(), format)
+	}
+
+	err := printSecrets(ctx, &bytes.Buffer{}, fClient, data[1:], outputNetrc)
+	assert.Error(t, err)
+}
+
+func localbuildClient(ctx context.Context, config v1alpha1.BuildCustomizationSpec) *fakeKubeClient {
+	fClient := new(fakeKubeClient)
+	fClient.On("List", ctx, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*v1alpha1.LocalbuildList)
+		arg.Items = []v1alpha1.Localbuild{{
+			Spec: v1alpha1.LocalbuildSpec{BuildCustomization: config},
+			Status: v1alpha1.LocalbuildStatus{
+				Gitea: v1alpha1.GiteaStatus{InternalURL: "http://my-gitea-http.gitea.svc.cluster.local:3000"},
+			},
+		}}
+	}).Return(nil)
+	return fClient
+}
+
+func TestGetGiteaHost(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		config   v1alpha1.BuildCustomizationSpec
+		expected string
+	}{
+		{
+			config:   v1alpha1.BuildCustomizationSpec{Host: "cnoe.localtest.me", Port: "8443"},
+			expected: "gitea.cnoe.localtest.me:8443",
+		},
+		{
+			config:   v1alpha1.BuildCustomizationSpec{Host: "idp.example.com", Port: "443", IngressServiceType: "LoadBalancer"},
+			expected: "gitea.idp.example.com:443",
+		},
+		{
+			config:   v1alpha1.BuildCustomizationSpec{Host: "idp.example.com", Port: "443", IngressServiceType: "NodePort", UsePathRouting: true},
+			expected: "idp.example.com:443",
+		},
+	}
+
+	for _, c := range cases {
+		host, err := getGiteaHost(ctx, localbuildClient(ctx, c.config))
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, host)
+	}
+
+	_, err := getGiteaHost(ctx, localbuildClient(ctx, v1alpha1.BuildCustomizationSpec{}))
+	assert.Error(t, err)
+}
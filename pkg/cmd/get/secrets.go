@@ -113,7 +113,7 @@ func printAllPackageSecrets(ctx context.Context, outWriter io.Writer, kubeClient
 		fmt.Println("no secrets found")
 		return nil
 	}
-	return printOutput(secretTemplatePath, outWriter, secretsToPrint, format)
+	return printSecrets(ctx, outWriter, kubeClient, secretsToPrint, format)
 }
 
 func printPackageSecrets(ctx context.Context, outWriter io.Writer, kubeClient client.Client, format string) error {
@@ -154,7 +154,7 @@ func printPackageSecrets(ctx context.Context, outWriter io.Writer, kubeClient cl
 		}
 	}
 
-	return printOutput(secretTemplatePath, outWriter, secretsToPrint, format)
+	return printSecrets(ctx, outWriter, kubeClient, secretsToPrint, format)
 }
 
 func renderTemplate(templatePath string, outWriter io.Writer, data []any) error {
@@ -0,0 +1,161 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+const argoCDAdminUsername = "admin"
+
+var giteaTeamPermissions = map[string]struct{}{"": {}, "read": {}, "write": {}, "admin": {}}
+
+// accountsFile is the format of the file given to the --accounts-file flag. It is the accounts spec with passwords,
+// which are stored in a secret instead of the Localbuild.
+type accountsFile struct {
+	Gitea struct {
+		Admin         accountCredentialFile        `json:"admin,omitempty"`
+		Users         []giteaUserFile              `json:"users,omitempty"`
+		Organizations []v1alpha1.GiteaOrganization `json:"organizations,omitempty"`
+	} `json:"gitea,omitempty"`
+	ArgoCD struct {
+		AdminPassword string           `json:"adminPassword,omitempty"`
+		Users         []argoCDUserFile `json:"users,omitempty"`
+	} `json:"argocd,omitempty"`
+	SSO struct {
+		Users []ssoUserFile `json:"users,omitempty"`
+	} `json:"sso,omitempty"`
+}
+
+type accountCredentialFile struct {
+	v1alpha1.AccountCredential
+	Password string `json:"password,omitempty"`
+}
+
+type giteaUserFile struct {
+	v1alpha1.GiteaUser
+	Password string `json:"password"`
+}
+
+type argoCDUserFile struct {
+	v1alpha1.ArgoCDUser
+	Password string `json:"password"`
+}
+
+type ssoUserFile struct {
+	v1alpha1.SSOUser
+	Password string `json:"password"`
+}
+
+// ReadAccountsFile reads the file given to the --accounts-file flag.
+// It returns the accounts without passwords and the passwords keyed as expected in the secret referenced by the accounts.
+func ReadAccountsFile(path string) (v1alpha1.AccountsSpec, map[string][]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return v1alpha1.AccountsSpec{}, nil, fmt.Errorf("reading accounts file %s: %w", path, err)
+	}
+
+	f := accountsFile{}
+	err = yaml.UnmarshalStrict(b, &f)
+	if err != nil {
+		return v1alpha1.AccountsSpec{}, nil, fmt.Errorf("parsing accounts file %s: %w", path, err)
+	}
+
+	if err = validateAccounts(f); err != nil {
+		return v1alpha1.AccountsSpec{}, nil, fmt.Errorf("invalid accounts file %s: %w", path, err)
+	}
+
+	a, passwords := splitPasswords(f)
+	return a, passwords, nil
+}
+
+func splitPasswords(f accountsFile) (v1alpha1.AccountsSpec, map[string][]byte) {
+	passwords := map[string][]byte{}
+	a := v1alpha1.AccountsSpec{
+		Gitea: v1alpha1.GiteaAccountsSpec{
+			Admin:         f.Gitea.Admin.AccountCredential,
+			Organizations: f.Gitea.Organizations,
+		},
+	}
+	if f.Gitea.Admin.Password != "" {
+		passwords[v1alpha1.GiteaAdminPasswordKey] = []byte(f.Gitea.Admin.Password)
+	}
+	for _, u := range f.Gitea.Users {
+		a.Gitea.Users = append(a.Gitea.Users, u.GiteaUser)
+		passwords[v1alpha1.GiteaUserPasswordKey(u.Username)] = []byte(u.Password)
+	}
+
+	if f.ArgoCD.AdminPassword != "" {
+		passwords[v1alpha1.ArgoCDAdminPasswordKey] = []byte(f.ArgoCD.AdminPassword)
+	}
+	for _, u := range f.ArgoCD.Users {
+		a.ArgoCD.Users = append(a.ArgoCD.Users, u.ArgoCDUser)
+		passwords[v1alpha1.ArgoCDUserPasswordKey(u.Username)] = []byte(u.Password)
+	}
+
+	for _, u := range f.SSO.Users {
+		a.SSO.Users = append(a.SSO.Users, u.SSOUser)
+		passwords[v1alpha1.SSOUserPasswordKey(u.Username)] = []byte(u.Password)
+	}
+	return a, passwords
+}
+
+func validateAccounts(f accountsFile) error {
+	for _, u := range f.Gitea.Users {
+		if u.Username == "" || u.Password == "" {
+			return fmt.Errorf("gitea users must have a username and password")
+		}
+		if err := validatePasswordKey(v1alpha1.GiteaUserPasswordKey(u.Username)); err != nil {
+			return fmt.Errorf("gitea user %s: %w", u.Username, err)
+		}
+	}
+	for _, o := range f.Gitea.Organizations {
+		if o.Name == "" {
+			return fmt.Errorf("gitea organizations must have a name")
+		}
+		for _, t := range o.Teams {
+			if t.Name == "" {
+				return fmt.Errorf("teams in gitea organization %s must have a name", o.Name)
+			}
+			if _, ok := giteaTeamPermissions[t.Permission]; !ok {
+				return fmt.Errorf("team %s in gitea organization %s has invalid permission %s. read, write or admin", t.Name, o.Name, t.Permission)
+			}
+		}
+	}
+	for _, u := range f.ArgoCD.Users {
+		if u.Username == "" || u.Password == "" {
+			return fmt.Errorf("argocd users must have a username and password")
+		}
+		if u.Username == argoCDAdminUsername {
+			return fmt.Errorf("use adminPassword to set the password of the argocd admin user")
+		}
+		if err := validatePasswordKey(v1alpha1.ArgoCDUserPasswordKey(u.Username)); err != nil {
+			return fmt.Errorf("argocd user %s: %w", u.Username, err)
+		}
+	}
+	usernames := make(map[string]struct{}, len(f.SSO.Users))
+	for _, u := range f.SSO.Users {
+		if u.Username == "" || u.Password == "" {
+			return fmt.Errorf("sso users must have a username and password")
+		}
+		if _, ok := usernames[u.Username]; ok {
+			return fmt.Errorf("sso user %s is specified more than once", u.Username)
+		}
+		usernames[u.Username] = struct{}{}
+		if err := validatePasswordKey(v1alpha1.SSOUserPasswordKey(u.Username)); err != nil {
+			return fmt.Errorf("sso user %s: %w", u.Username, err)
+		}
+	}
+	return nil
+}
+
+// validatePasswordKey checks the username can be used in the key of its password in a secret.
+func validatePasswordKey(key string) error {
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return fmt.Errorf("username cannot be used as a secret key: %v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadAccountsFile(t *testing.T) {
+	a, passwords, err := ReadAccountsFile("test-data/accounts.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, v1alpha1.AccountsSpec{
+		Gitea: v1alpha1.GiteaAccountsSpec{
+			Admin: v1alpha1.AccountCredential{Username: "platform-admin"},
+			Users: []v1alpha1.GiteaUser{
+				{Username: "dev"},
+				{Username: "ops", Admin: true},
+			},
+			Organizations: []v1alpha1.GiteaOrganization{
+				{Name: "platform", Teams: []v1alpha1.GiteaTeam{{Name: "developers", Permission: "write", Members: []string{"dev"}}}},
+			},
+		},
+		ArgoCD: v1alpha1.ArgoCDAccountsSpec{
+			Users: []v1alpha1.ArgoCDUser{
+				{Username: "ops", Role: "role:admin"},
+				{Username: "viewer"},
+			},
+		},
+		SSO: v1alpha1.SSOAccountsSpec{
+			Users: []v1alpha1.SSOUser{
+				{Username: "dev", Email: "dev@example.com"},
+				{Username: "ops", Admin: true},
+			},
+		},
+	}, a)
+	// passwords are not in the spec
+	assert.Equal(t, map[string][]byte{
+		v1alpha1.GiteaAdminPasswordKey:           []byte("changeme123"),
+		v1alpha1.GiteaUserPasswordKey("dev"):     []byte("dev-password"),
+		v1alpha1.GiteaUserPasswordKey("ops"):     []byte("ops-password"),
+		v1alpha1.ArgoCDAdminPasswordKey:          []byte("changeme123"),
+		v1alpha1.ArgoCDUserPasswordKey("ops"):    []byte("ops-password"),
+		v1alpha1.ArgoCDUserPasswordKey("viewer"): []byte("viewer-password"),
+		v1alpha1.SSOUserPasswordKey("dev"):       []byte("dev-password"),
+		v1alpha1.SSOUserPasswordKey("ops"):       []byte("ops-password"),
+	}, passwords)
+
+	_, _, err = ReadAccountsFile("test-data/accounts-invalid.yaml")
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
@@ -14,22 +15,64 @@ import (
 
 var (
 	LogLevel         string
-	LogLevelMsg      = "Set the log verbosity. Supported values are: debug, info, warn, and error."
+	LogLevelMsg      = "Set the log verbosity. Supported values are: debug, info, warn, and error. Levels of components can be set with component=level, e.g. info,gitrepository=debug. Components are controllers (localbuild, gitrepository, custompackage), logger names such as setup, and klog."
 	CmdLogger        logr.Logger
 	ColoredOutput    bool
 	ColoredOutputMsg = "Enable colored log messages."
+	LogFormat        string
+	LogFormatMsg     = "Set the log format. Supported values are: text and json. json writes one object per line for both idpbuilder and Kubernetes client logs."
+)
+
+var (
+	LogFile              string
+	LogFileMsg           = "Write logs to this file instead of stderr. The file is rotated when it grows larger than --log-file-max-size."
+	LogFileMaxSize       int
+	LogFileMaxSizeMsg    = "Maximum size of the log file in megabytes before it is rotated."
+	LogFileMaxBackups    int
+	LogFileMaxBackupsMsg = "Maximum number of rotated log files to keep."
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+	// klogComponent sets the level of logs from Kubernetes client libraries.
+	klogComponent = "klog"
 )
 
 func SetLogger() error {
-	l, err := getSlogLevel(LogLevel)
+	l, componentLevels, err := parseLogLevels(LogLevel)
 	if err != nil {
 		return err
 	}
 
-	slogger := slog.New(logger.NewHandler(os.Stderr, logger.Options{Level: l, Colored: ColoredOutput}))
-	kslogger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: getKlogLevel(l)}))
-	logger := logr.FromSlogHandler(slogger.Handler())
-	klogger := logr.FromSlogHandler(kslogger.Handler())
+	kLevel := getKlogLevel(l)
+	if v, ok := componentLevels[klogComponent]; ok {
+		kLevel = v.Level()
+	}
+
+	var out io.Writer = os.Stderr
+	if LogFile != "" {
+		out, err = logger.NewRotatingFile(LogFile, int64(LogFileMaxSize)*1024*1024, LogFileMaxBackups)
+		if err != nil {
+			return err
+		}
+	}
+
+	var handler, kHandler slog.Handler
+	switch strings.ToLower(LogFormat) {
+	case "", logFormatText:
+		// colors are only useful in a terminal
+		handler = logger.NewHandler(out, logger.Options{Level: l, Colored: ColoredOutput && LogFile == "", ComponentLevels: componentLevels})
+		kHandler = slog.NewTextHandler(out, &slog.HandlerOptions{Level: kLevel})
+	case logFormatJSON:
+		handler = logger.NewHandler(out, logger.Options{Level: l, JSON: true, ComponentLevels: componentLevels})
+		kHandler = logger.NewHandler(out, logger.Options{Level: kLevel, JSON: true})
+	default:
+		return fmt.Errorf("%s is not a valid log format", LogFormat)
+	}
+
+	logger := logr.FromSlogHandler(handler)
+	klogger := logr.FromSlogHandler(kHandler)
 
 	klog.SetLogger(klogger)
 	ctrl.SetLogger(logger)
@@ -37,6 +80,38 @@ func SetLogger() error {
 	return nil
 }
 
+// parseLogLevels parses a comma separated list of levels. An entry without a component sets the default level, which is info if not set.
+func parseLogLevels(s string) (slog.Level, map[string]slog.Leveler, error) {
+	defaultLevel := slog.LevelInfo
+	componentLevels := make(map[string]slog.Leveler)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		component, level, found := strings.Cut(entry, "=")
+		if !found {
+			l, err := getSlogLevel(entry)
+			if err != nil {
+				return slog.LevelInfo, nil, err
+			}
+			defaultLevel = l
+			continue
+		}
+
+		component = strings.ToLower(strings.TrimSpace(component))
+		if component == "" {
+			return slog.LevelInfo, nil, fmt.Errorf("%s is missing a component name", entry)
+		}
+		l, err := getSlogLevel(strings.TrimSpace(level))
+		if err != nil {
+			return slog.LevelInfo, nil, err
+		}
+		componentLevels[component] = l
+	}
+	return defaultLevel, componentLevels, nil
+}
+
 func getSlogLevel(s string) (slog.Level, error) {
 	switch strings.ToLower(s) {
 	case "debug":
@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogLevels(t *testing.T) {
+	l, c, err := parseLogLevels("debug")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, l)
+	assert.Empty(t, c)
+
+	l, c, err = parseLogLevels("gitrepository=debug, LocalBuild=error,warn")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, l)
+	assert.Equal(t, map[string]slog.Leveler{"gitrepository": slog.LevelDebug, "localbuild": slog.LevelError}, c)
+
+	l, _, err = parseLogLevels("klog=debug")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelInfo, l)
+
+	_, _, err = parseLogLevels("gitrepository=verbose")
+	assert.Error(t, err)
+	_, _, err = parseLogLevels("=debug")
+	assert.Error(t, err)
+}
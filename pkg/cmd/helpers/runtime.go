@@ -0,0 +1,5 @@
+package helpers
+
+var (
+	RuntimeMsg = "Container runtime to manage clusters with. Supported values are: docker, podman, and finch. Defaults to the value of KIND_EXPERIMENTAL_PROVIDER, then the first runtime found in PATH."
+)
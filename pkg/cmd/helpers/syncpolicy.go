@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// SyncPolicyFile is the format of the file given to the --sync-policy-file flag.
+type SyncPolicyFile struct {
+	// Default applies to embedded applications and custom packages that do not specify a sync policy.
+	Default *v1alpha1.SyncPolicySpec `json:"default,omitempty"`
+	// Packages are keyed by application name. e.g. argocd, gitea, nginx, or the name of a custom package application.
+	Packages map[string]v1alpha1.SyncPolicySpec `json:"packages,omitempty"`
+}
+
+func ReadSyncPolicyFile(path string) (SyncPolicyFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return SyncPolicyFile{}, fmt.Errorf("reading sync policy file %s: %w", path, err)
+	}
+
+	f := SyncPolicyFile{}
+	err = yaml.UnmarshalStrict(b, &f)
+	if err != nil {
+		return SyncPolicyFile{}, fmt.Errorf("parsing sync policy file %s: %w", path, err)
+	}
+
+	if f.Default != nil {
+		if vErr := validateSyncPolicy(*f.Default); vErr != nil {
+			return SyncPolicyFile{}, fmt.Errorf("invalid default sync policy in %s: %w", path, vErr)
+		}
+	}
+	for name := range f.Packages {
+		if vErr := validateSyncPolicy(f.Packages[name]); vErr != nil {
+			return SyncPolicyFile{}, fmt.Errorf("invalid sync policy for %s in %s: %w", name, path, vErr)
+		}
+	}
+	return f, nil
+}
+
+func validateSyncPolicy(p v1alpha1.SyncPolicySpec) error {
+	if p.Retry != nil {
+		for _, d := range []string{p.Retry.Backoff, p.Retry.MaxBackoff} {
+			if d == "" {
+				continue
+			}
+			if _, err := time.ParseDuration(d); err != nil {
+				return fmt.Errorf("invalid retry duration %s: %w", d, err)
+			}
+		}
+	}
+	for i := range p.IgnoreDifferences {
+		if p.IgnoreDifferences[i].Kind == "" {
+			return fmt.Errorf("kind must be specified in ignoreDifferences")
+		}
+	}
+	return nil
+}
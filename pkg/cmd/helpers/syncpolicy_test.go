@@ -0,0 +1,40 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSyncPolicyFile(t *testing.T) {
+	f, err := ReadSyncPolicyFile("test-data/sync-policy.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, SyncPolicyFile{
+		Default: &v1alpha1.SyncPolicySpec{
+			Prune: true,
+			Retry: &v1alpha1.SyncRetrySpec{Limit: 5, Backoff: "5s", MaxBackoff: "3m"},
+		},
+		Packages: map[string]v1alpha1.SyncPolicySpec{
+			"my-app": {
+				Manual:          true,
+				ServerSideApply: true,
+				IgnoreDifferences: []v1alpha1.IgnoreDifference{
+					{Group: "apps", Kind: "Deployment", JSONPointers: []string{"/spec/replicas"}},
+				},
+			},
+		},
+	}, f)
+
+	cases := map[string]string{
+		"invalidDuration": "test-data/sync-policy-invalid.yaml",
+		"unknownFields":   "test-data/valid.yaml",
+		"notExist":        "test-data/does-not-exist.yaml",
+	}
+	for k := range cases {
+		_, err = ReadSyncPolicyFile(cases[k])
+		if err == nil {
+			t.Fatalf("%s expected error but did not receive error", k)
+		}
+	}
+}
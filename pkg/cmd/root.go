@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/cache"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/create"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/credentialhelper"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/debug"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/delete"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/get"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/secrets"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/version"
+	"github.com/cnoe-io/idpbuilder/pkg/runtime"
 	"github.com/spf13/cobra"
 )
 
@@ -21,10 +26,19 @@ var rootCmd = &cobra.Command{
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&helpers.LogLevel, "log-level", "l", "info", helpers.LogLevelMsg)
 	rootCmd.PersistentFlags().BoolVar(&helpers.ColoredOutput, "color", false, helpers.ColoredOutputMsg)
+	rootCmd.PersistentFlags().StringVar(&helpers.LogFormat, "log-format", "text", helpers.LogFormatMsg)
+	rootCmd.PersistentFlags().StringVar(&helpers.LogFile, "log-file", "", helpers.LogFileMsg)
+	rootCmd.PersistentFlags().IntVar(&helpers.LogFileMaxSize, "log-file-max-size", 100, helpers.LogFileMaxSizeMsg)
+	rootCmd.PersistentFlags().IntVar(&helpers.LogFileMaxBackups, "log-file-max-backups", 3, helpers.LogFileMaxBackupsMsg)
+	rootCmd.PersistentFlags().StringVar(&runtime.Selected, "runtime", "", helpers.RuntimeMsg)
 	rootCmd.AddCommand(create.CreateCmd)
 	rootCmd.AddCommand(get.GetCmd)
 	rootCmd.AddCommand(delete.DeleteCmd)
 	rootCmd.AddCommand(version.VersionCmd)
+	rootCmd.AddCommand(cache.CacheCmd)
+	rootCmd.AddCommand(debug.DebugCmd)
+	rootCmd.AddCommand(credentialhelper.CredentialHelperCmd)
+	rootCmd.AddCommand(secrets.SecretsCmd)
 }
 
 func Execute() {
@@ -0,0 +1,22 @@
+package secrets
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var SecretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "manage secrets of core packages",
+	Long:  ``,
+	RunE:  secretsE,
+}
+
+func init() {
+	SecretsCmd.AddCommand(RotateCmd)
+}
+
+func secretsE(cmd *cobra.Command, args []string) error {
+	return fmt.Errorf("specify subcommand")
+}
@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/build"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/localbuild"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/util/homedir"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type rotateFunc func(ctx context.Context, kubeClient client.Client, config v1alpha1.BuildCustomizationSpec) error
+
+// core packages whose admin credentials can be rotated
+var rotators = map[string]rotateFunc{
+	v1alpha1.ArgoCDPackageName: localbuild.RotateArgoCDAdminPassword,
+	v1alpha1.GiteaPackageName:  localbuild.RotateGiteaAdminCredential,
+}
+
+var RotateCmd = &cobra.Command{
+	Use:          "rotate",
+	Short:        "rotate admin credentials of core packages",
+	Long:         `Changes the admin passwords of core packages through their APIs and updates the secrets shown by get secrets.`,
+	PreRunE:      preRotateE,
+	RunE:         rotateE,
+	SilenceUsage: true,
+}
+
+var packages []string
+
+func init() {
+	RotateCmd.Flags().StringSliceVarP(&packages, "packages", "p", []string{v1alpha1.ArgoCDPackageName, v1alpha1.GiteaPackageName}, "names of core packages to rotate credentials for.")
+}
+
+func preRotateE(cmd *cobra.Command, args []string) error {
+	for _, p := range packages {
+		if _, ok := rotators[p]; !ok {
+			return fmt.Errorf("rotating credentials of package %s is not supported", p)
+		}
+	}
+	return helpers.SetLogger()
+}
+
+func rotateE(cmd *cobra.Command, args []string) error {
+	ctx, ctxCancel := context.WithCancel(ctrl.SetupSignalHandler())
+	defer ctxCancel()
+
+	b := build.NewBuild(build.NewBuildOptions{
+		KubeConfigPath: filepath.Join(homedir.HomeDir(), ".kube", "config"),
+		Scheme:         k8s.GetScheme(),
+		CancelFunc:     ctxCancel,
+	})
+	kubeConfig, err := b.GetKubeConfig()
+	if err != nil {
+		return fmt.Errorf("getting kube config: %w", err)
+	}
+	kubeClient, err := b.GetKubeClient(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("getting kube client: %w", err)
+	}
+
+	config, err := getBuildCustomization(ctx, kubeClient)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range packages {
+		helpers.CmdLogger.Info("rotating admin credential", "package", p)
+		if err := rotators[p](ctx, kubeClient, config); err != nil {
+			return fmt.Errorf("rotating %s admin credential: %w", p, err)
+		}
+	}
+	return nil
+}
+
+func getBuildCustomization(ctx context.Context, kubeClient client.Client) (v1alpha1.BuildCustomizationSpec, error) {
+	builds := v1alpha1.LocalbuildList{}
+	if err := kubeClient.List(ctx, &builds); err != nil {
+		return v1alpha1.BuildCustomizationSpec{}, fmt.Errorf("listing localbuilds: %w", err)
+	}
+	if len(builds.Items) == 0 {
+		return v1alpha1.BuildCustomizationSpec{}, fmt.Errorf("no localbuild found in the cluster")
+	}
+	return builds.Items[0].Spec.BuildCustomization, nil
+}
@@ -13,6 +13,8 @@ import (
 	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/resources/localbuild"
+	containerruntime "github.com/cnoe-io/idpbuilder/pkg/runtime"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,8 +37,23 @@ type Reconciler struct {
 	Config   v1alpha1.BuildCustomizationSpec
 	TempDir  string
 	RepoMap  *util.RepoMap
+	// GitAuth is used to authenticate against remote package repositories.
+	GitAuth util.GitAuthOptions
+	// SourceTypes limits reconciliation to packages with these source types, local or remote. All packages are reconciled when empty.
+	SourceTypes []string
+	// ContainerRuntime builds the images packages declare. Packages declaring images fail to reconcile when nil.
+	ContainerRuntime containerruntime.IRuntime
 }
 
+// +kubebuilder:rbac:groups=idpbuilder.cnoe.io,resources=custompackages,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=idpbuilder.cnoe.io,resources=custompackages/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=idpbuilder.cnoe.io,resources=custompackages/finalizers,verbs=update
+// +kubebuilder:rbac:groups=idpbuilder.cnoe.io,resources=gitrepositories,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=argoproj.io,resources=applications;applicationsets;appprojects,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;create
+
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
@@ -46,6 +63,11 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !pkg.DeletionTimestamp.IsZero() {
+		logger.V(1).Info("removing custom package from its project", "name", req.Name, "namespace", req.Namespace)
+		return ctrl.Result{}, r.finalizeProject(ctx, &pkg)
+	}
+
 	logger.V(1).Info("reconciling custom package", "name", req.Name, "namespace", req.Namespace)
 	defer r.postProcessReconcile(ctx, req, &pkg)
 	result, err := r.reconcileCustomPackage(ctx, &pkg)
@@ -87,6 +109,11 @@ func (r *Reconciler) reconcileCustomPackage(ctx context.Context, resource *v1alp
 		return ctrl.Result{}, fmt.Errorf("file contained 0 kubernetes objects %s", resource.Spec.ArgoCD.ApplicationFile)
 	}
 
+	// images are built first so git repositories are created with the references to replace.
+	if err = r.reconcileImages(ctx, resource, objs[0]); err != nil {
+		return ctrl.Result{}, fmt.Errorf("building images of %s: %w", resource.Spec.ArgoCD.ApplicationFile, err)
+	}
+
 	switch resource.Spec.ArgoCD.Type {
 	case argocdapplication.ApplicationKind:
 		app, ok := objs[0].(*argov1alpha1.Application)
@@ -99,6 +126,18 @@ func (r *Reconciler) reconcileCustomPackage(ctx context.Context, resource *v1alp
 			return ctrl.Result{}, err
 		}
 
+		err = r.setScopedProject(ctx, resource, app, &app.Spec)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("setting project for %s: %w", app.Name, err)
+		}
+		if resource.Spec.SyncPolicy != nil {
+			localbuild.SetSyncPolicy(&app.Spec, *resource.Spec.SyncPolicy)
+		}
+		err = r.reconcileSecrets(ctx, app, app.Spec.Destination.Namespace)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("creating secrets for %s: %w", app.Name, err)
+		}
+
 		foundAppObj := argov1alpha1.Application{}
 		err = r.Client.Get(ctx, client.ObjectKeyFromObject(app), &foundAppObj)
 		if err != nil {
@@ -132,6 +171,18 @@ func (r *Reconciler) reconcileCustomPackage(ctx context.Context, resource *v1alp
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+
+		err = r.setScopedProject(ctx, resource, appSet, &appSet.Spec.Template.Spec)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("setting project for %s: %w", appSet.Name, err)
+		}
+		if resource.Spec.SyncPolicy != nil {
+			localbuild.SetSyncPolicy(&appSet.Spec.Template.Spec, *resource.Spec.SyncPolicy)
+		}
+		err = r.reconcileSecrets(ctx, appSet, appSet.Spec.Template.Spec.Destination.Namespace)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("creating secrets for %s: %w", appSet.Name, err)
+		}
 		foundAppSetObj := argov1alpha1.ApplicationSet{}
 		err = r.Client.Get(ctx, client.ObjectKeyFromObject(appSet), &foundAppSetObj)
 		if err != nil {
@@ -181,6 +232,7 @@ func (r *Reconciler) reconcileArgoCDApp(ctx context.Context, resource *v1alpha1.
 					notSyncedRepos += 1
 				}
 				s.RepoURL = repo.Status.InternalGitRepositoryUrl
+				setTargetRevision(&s.TargetRevision, repo)
 				repoRefs = append(repoRefs, v1alpha1.ObjectRef{
 					Namespace: repo.Namespace,
 					Name:      repo.Name,
@@ -204,6 +256,7 @@ func (r *Reconciler) reconcileArgoCDApp(ctx context.Context, resource *v1alpha1.
 		if repo != nil {
 			appSourcesSynced = repo.Status.InternalGitRepositoryUrl != ""
 			s.RepoURL = repo.Status.InternalGitRepositoryUrl
+			setTargetRevision(&s.TargetRevision, repo)
 			repoRefs = append(repoRefs, v1alpha1.ObjectRef{
 				Namespace: repo.Namespace,
 				Name:      repo.Name,
@@ -217,39 +270,19 @@ func (r *Reconciler) reconcileArgoCDApp(ctx context.Context, resource *v1alpha1.
 }
 
 func (r *Reconciler) reconcileArgoCDAppSet(ctx context.Context, resource *v1alpha1.CustomPackage, appSet *argov1alpha1.ApplicationSet) (ctrl.Result, error) {
-	notSyncedRepos := 0
-	for i := range appSet.Spec.Generators {
-		g := appSet.Spec.Generators[i]
-		if g.Git != nil {
-			res, repo, gErr := r.reconcileArgoCDSource(ctx, resource, g.Git.RepoURL, appSet.GetName())
-			if gErr != nil {
-				return res, fmt.Errorf("reconciling git generator URL %s, %s: %w", g.Git.RepoURL, resource.Spec.ArgoCD.ApplicationFile, gErr)
-			}
-			if repo != nil {
-				g.Git.RepoURL = repo.Status.InternalGitRepositoryUrl
-				if repo.Status.InternalGitRepositoryUrl == "" {
-					notSyncedRepos += 1
-				}
-			}
-		}
-		if g.Matrix != nil {
-			for j := range g.Matrix.Generators {
-				nestedGenerator := g.Matrix.Generators[j]
-				if nestedGenerator.Git != nil {
-					res, repo, gErr := r.reconcileArgoCDSource(ctx, resource, nestedGenerator.Git.RepoURL, appSet.GetName())
-					if gErr != nil {
-						return res, fmt.Errorf("reconciling git generator URL %s, %s: %w", nestedGenerator.Git.RepoURL, resource.Spec.ArgoCD.ApplicationFile, gErr)
-					}
-					if repo != nil {
-						nestedGenerator.Git.RepoURL = repo.Status.InternalGitRepositoryUrl
-						if repo.Status.InternalGitRepositoryUrl == "" {
-							notSyncedRepos += 1
-						}
-					}
-				}
-			}
-		}
+	// generators can be nested in matrix and merge generators, and can have their own templates. walk all of them.
+	generatorRefs := map[string]v1alpha1.ObjectRef{}
+	result := ctrl.Result{RequeueAfter: requeueTime}
+	rewrite := func(repoURL string) (*v1alpha1.GitRepository, error) {
+		res, repo, err := r.reconcileArgoCDSource(ctx, resource, repoURL, appSet.GetName())
+		result = earlierResult(result, res)
+		return repo, err
 	}
+	generators, notSyncedRepos, err := rewriteObjectRepoURLs(appSet.Spec.Generators, rewrite, generatorRefs)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("reconciling generators of %s: %w", resource.Spec.ArgoCD.ApplicationFile, err)
+	}
+	appSet.Spec.Generators = generators
 
 	gitGeneratorsSynced := notSyncedRepos == 0
 	app := argov1alpha1.Application{
@@ -257,14 +290,35 @@ func (r *Reconciler) reconcileArgoCDAppSet(ctx context.Context, resource *v1alph
 	}
 	app.Spec = appSet.Spec.Template.Spec
 
-	_, err := r.reconcileArgoCDApp(ctx, resource, &app)
+	res, err := r.reconcileArgoCDApp(ctx, resource, &app)
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("reconciling application set %s %w", resource.Spec.ArgoCD.ApplicationFile, err)
+		return res, fmt.Errorf("reconciling application set %s %w", resource.Spec.ArgoCD.ApplicationFile, err)
 	}
+	appSet.Spec.Template.Spec = app.Spec
 
+	// reconcileArgoCDApp replaced the refs with those of the template. refs of removed generators are dropped.
+	resource.Status.GitRepositoryRefs = joinRepoRefs(resource.Status.GitRepositoryRefs, generatorRefs)
 	resource.Status.Synced = resource.Status.Synced && gitGeneratorsSynced
 
-	return ctrl.Result{RequeueAfter: requeueTime}, nil
+	return earlierResult(result, res), nil
+}
+
+// earlierResult returns the result that requeues first.
+func earlierResult(a, b ctrl.Result) ctrl.Result {
+	if b.Requeue && !a.Requeue {
+		return b
+	}
+	if b.RequeueAfter > 0 && (a.RequeueAfter == 0 || b.RequeueAfter < a.RequeueAfter) {
+		a.RequeueAfter = b.RequeueAfter
+	}
+	return a
+}
+
+// point to the branch the repository contents were pushed to.
+func setTargetRevision(revision *string, repo *v1alpha1.GitRepository) {
+	if repo.Status.Branch != "" {
+		*revision = repo.Status.Branch
+	}
 }
 
 // create a gitrepository custom resource, then let the git repository controller take care of the rest
@@ -312,9 +366,10 @@ func (r *Reconciler) reconcileArgoCDSourceFromRemote(ctx context.Context, resour
 				Name:             v1alpha1.GitProviderGitea,
 				GitURL:           resource.Spec.GitServerURL,
 				InternalGitURL:   resource.Spec.InternalGitServeURL,
-				OrganizationName: v1alpha1.GiteaAdminUserName,
+				OrganizationName: gitServerOrganization(resource),
 			},
 			SecretRef: resource.Spec.GitServerAuthSecretRef,
+			Branch:    resource.Spec.Branch,
 		}
 
 		return nil
@@ -357,16 +412,19 @@ func (r *Reconciler) reconcileArgoCDSourceFromLocal(ctx context.Context, resourc
 
 		repo.Spec = v1alpha1.GitRepositorySpec{
 			Source: v1alpha1.GitRepositorySource{
-				Type: v1alpha1.SourceTypeLocal,
-				Path: absPath,
+				Type:          v1alpha1.SourceTypeLocal,
+				Path:          absPath,
+				MirrorHistory: resource.Spec.MirrorHistory,
+				Images:        imageReplacements(resource.Status.Images),
 			},
 			Provider: v1alpha1.Provider{
 				Name:             v1alpha1.GitProviderGitea,
 				GitURL:           resource.Spec.GitServerURL,
 				InternalGitURL:   resource.Spec.InternalGitServeURL,
-				OrganizationName: v1alpha1.GiteaAdminUserName,
+				OrganizationName: gitServerOrganization(resource),
 			},
 			SecretRef: resource.Spec.GitServerAuthSecretRef,
+			Branch:    resource.Spec.Branch,
 		}
 
 		return nil
@@ -383,9 +441,20 @@ func (r *Reconciler) reconcileArgoCDSourceFromLocal(ctx context.Context, resourc
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.CustomPackage{}).
+		WithEventFilter(util.SourceTypePredicate(r.SourceTypes, func(obj client.Object) string {
+			return packageSourceType(obj.(*v1alpha1.CustomPackage))
+		})).
 		Complete(r)
 }
 
+// packageSourceType returns where the application file of the package is read from.
+func packageSourceType(pkg *v1alpha1.CustomPackage) string {
+	if pkg.Spec.RemoteRepository.Url == "" {
+		return v1alpha1.SourceTypeLocal
+	}
+	return v1alpha1.SourceTypeRemote
+}
+
 func (r *Reconciler) getArgoCDAppFile(ctx context.Context, resource *v1alpha1.CustomPackage) ([]byte, error) {
 	filePath := resource.Spec.ArgoCD.ApplicationFile
 
@@ -393,11 +462,18 @@ func (r *Reconciler) getArgoCDAppFile(ctx context.Context, resource *v1alpha1.Cu
 		return os.ReadFile(filePath)
 	}
 
+	auth, err := r.GitAuth.AuthMethod(ctx, resource.Spec.RemoteRepository.Url)
+	if err != nil {
+		return nil, fmt.Errorf("getting credentials for %s: %w", resource.Spec.RemoteRepository.Url, err)
+	}
+
 	cloneDir := util.RepoDir(resource.Spec.RemoteRepository.Url, r.TempDir)
 	st := r.RepoMap.LoadOrStore(resource.Spec.RemoteRepository.Url, cloneDir)
-	st.MU.Lock()
-	wt, _, err := util.CloneRemoteRepoToDir(ctx, resource.Spec.RemoteRepository, 1, false, cloneDir, "")
-	defer st.MU.Unlock()
+	if err = st.Lock(); err != nil {
+		return nil, fmt.Errorf("locking %s: %w", cloneDir, err)
+	}
+	defer st.Unlock()
+	wt, _, err := util.CloneRemoteRepoToDir(ctx, resource.Spec.RemoteRepository, 1, false, auth, cloneDir, "")
 	if err != nil {
 		return nil, fmt.Errorf("cloning repo, %s: %w", resource.Spec.RemoteRepository.Url, err)
 	}
@@ -495,3 +571,10 @@ func getCNOEAbsPath(fPath, repoURL string) (string, error) {
 	}
 	return absPath, err
 }
+
+func gitServerOrganization(resource *v1alpha1.CustomPackage) string {
+	if resource.Spec.GitServerOrganization != "" {
+		return resource.Spec.GitServerOrganization
+	}
+	return v1alpha1.GiteaAdminUserName
+}
@@ -0,0 +1,274 @@
+package custompackage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	containerruntime "github.com/cnoe-io/idpbuilder/pkg/runtime"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	imageDefaultDockerfile = "Dockerfile"
+	imageTagLength         = 12
+)
+
+var (
+	imageNamePattern = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+	// registries return the digest of multi platform images only if index types are accepted.
+	imageManifestTypes = []string{
+		"application/vnd.oci.image.index.v1+json",
+		"application/vnd.docker.distribution.manifest.list.v2+json",
+		"application/vnd.oci.image.manifest.v1+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+	}
+)
+
+// imageSpec is an entry of the images annotation.
+type imageSpec struct {
+	Name string `json:"name"`
+	// Context is the build context directory.
+	Context    string `json:"context"`
+	Dockerfile string `json:"dockerfile,omitempty"`
+}
+
+// imageSpecs returns the images declared in the annotations.
+func imageSpecs(annotations map[string]string) ([]imageSpec, error) {
+	value := strings.TrimSpace(annotations[v1alpha1.ImagesAnnotation])
+	if value == "" {
+		return nil, nil
+	}
+
+	var specs []imageSpec
+	if err := yaml.UnmarshalStrict([]byte(value), &specs); err != nil {
+		return nil, fmt.Errorf("parsing %s annotation: %w", v1alpha1.ImagesAnnotation, err)
+	}
+
+	names := make(map[string]struct{}, len(specs))
+	for i := range specs {
+		s := &specs[i]
+		if !imageNamePattern.MatchString(s.Name) {
+			return nil, fmt.Errorf("image at index %d in %s annotation has invalid name %q", i, v1alpha1.ImagesAnnotation, s.Name)
+		}
+		if _, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("image %s is declared more than once", s.Name)
+		}
+		names[s.Name] = struct{}{}
+		if s.Context == "" {
+			return nil, fmt.Errorf("image %s must specify a context", s.Name)
+		}
+		if s.Dockerfile == "" {
+			s.Dockerfile = imageDefaultDockerfile
+		}
+	}
+	return specs, nil
+}
+
+// reconcileImages builds the images declared on the application and pushes them to the git server registry.
+// Images are only rebuilt when their build context changes.
+func (r *Reconciler) reconcileImages(ctx context.Context, resource *v1alpha1.CustomPackage, obj client.Object) error {
+	logger := log.FromContext(ctx)
+
+	specs, err := imageSpecs(obj.GetAnnotations())
+	if err != nil {
+		return err
+	}
+	if len(specs) == 0 {
+		resource.Status.Images = nil
+		return nil
+	}
+	if resource.Spec.RemoteRepository.Url != "" {
+		return fmt.Errorf("images can only be built for packages in local directories")
+	}
+	if r.ContainerRuntime == nil {
+		return fmt.Errorf("building images requires a container runtime")
+	}
+
+	auth, err := r.registryAuth(ctx, resource.Spec.GitServerAuthSecretRef)
+	if err != nil {
+		return err
+	}
+	registry, err := url.Parse(resource.Spec.GitServerURL)
+	if err != nil {
+		return fmt.Errorf("parsing git server url: %w", err)
+	}
+
+	appDir := filepath.Dir(resource.Spec.ArgoCD.ApplicationFile)
+	built := make([]v1alpha1.BuiltImage, 0, len(specs))
+	for i := range specs {
+		s := specs[i]
+		contextDir := s.Context
+		if !filepath.IsAbs(contextDir) {
+			contextDir = filepath.Join(appDir, contextDir)
+		}
+		hash, hErr := hashDirectory(contextDir, s.Dockerfile)
+		if hErr != nil {
+			return fmt.Errorf("hashing build context of image %s: %w", s.Name, hErr)
+		}
+		if prev := findBuiltImage(resource.Status.Images, s.Name); prev != nil && prev.ContextHash == hash {
+			built = append(built, *prev)
+			continue
+		}
+
+		repository := fmt.Sprintf("%s/%s/%s", registry.Host, strings.ToLower(auth.Username), s.Name)
+		tag := fmt.Sprintf("%s:%s", repository, hash[:imageTagLength])
+		logger.Info("building image", "image", tag, "context", contextDir)
+		err = r.ContainerRuntime.BuildImage(ctx, containerruntime.BuildImageOptions{
+			Context:    contextDir,
+			Dockerfile: s.Dockerfile,
+			Tag:        tag,
+		})
+		if err != nil {
+			return fmt.Errorf("building image %s: %w", s.Name, err)
+		}
+		if err = r.ContainerRuntime.PushImage(ctx, tag, auth); err != nil {
+			return fmt.Errorf("pushing image %s: %w", tag, err)
+		}
+
+		digest, dErr := getImageDigest(ctx, registry.Scheme, tag, auth)
+		if dErr != nil {
+			return fmt.Errorf("getting digest of image %s: %w", tag, dErr)
+		}
+		built = append(built, v1alpha1.BuiltImage{
+			Name:        s.Name,
+			Image:       fmt.Sprintf("%s@%s", repository, digest),
+			ContextHash: hash,
+		})
+	}
+	resource.Status.Images = built
+	return nil
+}
+
+func (r *Reconciler) registryAuth(ctx context.Context, ref v1alpha1.SecretReference) (containerruntime.RegistryAuth, error) {
+	secret := corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}, &secret)
+	if err != nil {
+		return containerruntime.RegistryAuth{}, fmt.Errorf("getting git server secret: %w", err)
+	}
+	auth := containerruntime.RegistryAuth{
+		Username: string(secret.Data[secretUsernameKey]),
+		Password: string(secret.Data[secretPasswordKey]),
+	}
+	if auth.Username == "" || auth.Password == "" {
+		return containerruntime.RegistryAuth{}, fmt.Errorf("username or password not found in secret %s in %s ns", ref.Name, ref.Namespace)
+	}
+	return auth, nil
+}
+
+// imageReplacements returns the references local sources of the package use for the built images.
+func imageReplacements(images []v1alpha1.BuiltImage) []v1alpha1.ImageReplacement {
+	if len(images) == 0 {
+		return nil
+	}
+	out := make([]v1alpha1.ImageReplacement, len(images))
+	for i := range images {
+		out[i] = v1alpha1.ImageReplacement{Name: images[i].Name, Image: images[i].Image}
+	}
+	return out
+}
+
+func findBuiltImage(images []v1alpha1.BuiltImage, name string) *v1alpha1.BuiltImage {
+	for i := range images {
+		if images[i].Name == name {
+			return &images[i]
+		}
+	}
+	return nil
+}
+
+// hashDirectory returns the hash of paths, modes and contents of files in the build context in dir.
+// Files left out of the build context by .dockerignore do not change the hash.
+func hashDirectory(dir, dockerfile string) (string, error) {
+	h := sha256.New()
+	err := containerruntime.WalkBuildContext(dir, dockerfile, func(path, rel string, info fs.FileInfo) error {
+		fmt.Fprintf(h, "%s %s\n", rel, info.Mode())
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// getImageDigest asks the registry for the digest of the tagged image.
+func getImageDigest(ctx context.Context, scheme, tag string, auth containerruntime.RegistryAuth) (string, error) {
+	host, rest, _ := strings.Cut(tag, "/")
+	i := strings.LastIndex(rest, ":")
+	name, reference := rest[:i], rest[i+1:]
+
+	token, err := getRegistryToken(ctx, fmt.Sprintf("%s://%s/v2/token", scheme, host), auth)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, host, name, reference), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", strings.Join(imageManifestTypes, ", "))
+	resp, err := util.GetHttpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting manifest: status %s", resp.Status)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return the digest")
+	}
+	return digest, nil
+}
+
+func getRegistryToken(ctx context.Context, tokenURL string, auth containerruntime.RegistryAuth) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(auth.Username, auth.Password)
+	resp, err := util.GetHttpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting registry token: status %s", resp.Status)
+	}
+
+	t := struct {
+		Token string `json:"token"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("decoding registry token: %w", err)
+	}
+	return t.Token, nil
+}
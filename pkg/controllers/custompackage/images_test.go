@@ -0,0 +1,168 @@
+package custompackage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	containerruntime "github.com/cnoe-io/idpbuilder/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type fakeRuntime struct {
+	containerruntime.IRuntime
+	mock.Mock
+}
+
+func (f *fakeRuntime) BuildImage(ctx context.Context, opts containerruntime.BuildImageOptions) error {
+	return f.Called(opts).Error(0)
+}
+
+func (f *fakeRuntime) PushImage(ctx context.Context, image string, auth containerruntime.RegistryAuth) error {
+	return f.Called(image, auth).Error(0)
+}
+
+type secretClient struct {
+	client.Client
+}
+
+func (f *secretClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	obj.(*corev1.Secret).Data = map[string][]byte{
+		secretUsernameKey: []byte("giteaAdmin"),
+		secretPasswordKey: []byte("password"),
+	}
+	return nil
+}
+
+func TestImageSpecs(t *testing.T) {
+	specs, err := imageSpecs(map[string]string{v1alpha1.ImagesAnnotation: `
+- name: my-app
+  context: ../src
+- name: worker
+  context: ../worker
+  dockerfile: build/Dockerfile
+`})
+	require.NoError(t, err)
+	assert.Equal(t, []imageSpec{
+		{Name: "my-app", Context: "../src", Dockerfile: "Dockerfile"},
+		{Name: "worker", Context: "../worker", Dockerfile: "build/Dockerfile"},
+	}, specs)
+
+	specs, err = imageSpecs(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, specs)
+
+	invalid := []string{
+		`[{"name": "My-App", "context": "."}]`,
+		`[{"name": "my-app"}]`,
+		`[{"name": "my-app", "context": "."}, {"name": "my-app", "context": "src"}]`,
+		`[{"name": "my-app", "context": ".", "tag": "latest"}]`,
+	}
+	for i := range invalid {
+		_, err = imageSpecs(map[string]string{v1alpha1.ImagesAnnotation: invalid[i]})
+		assert.Error(t, err, invalid[i])
+	}
+}
+
+func TestHashDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+
+	hash, err := hashDirectory(dir, "Dockerfile")
+	require.NoError(t, err)
+
+	// git metadata does not change the image
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644))
+	same, err := hashDirectory(dir, "Dockerfile")
+	require.NoError(t, err)
+	assert.Equal(t, hash, same)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+	changed, err := hashDirectory(dir, "Dockerfile")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changed)
+
+	// files left out by .dockerignore are not built
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("*.md\n"), 0644))
+	ignored, err := hashDirectory(dir, "Dockerfile")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# readme\n"), 0644))
+	same, err = hashDirectory(dir, "Dockerfile")
+	require.NoError(t, err)
+	assert.Equal(t, ignored, same)
+}
+
+func TestReconcileImages(t *testing.T) {
+	ctx := context.Background()
+	digest := "sha256:6f7e6e3e6f9c2a1f0e8b0f5c2a4d3b1e0f9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b"
+
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/v2/token":
+			user, pass, _ := req.BasicAuth()
+			if user != "giteaAdmin" || pass != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token": "registry-token"}`))
+		case strings.HasPrefix(req.URL.Path, "/v2/giteaadmin/my-app/manifests/") && req.Header.Get("Authorization") == "Bearer registry-token":
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer registry.Close()
+	host := strings.TrimPrefix(registry.URL, "https://")
+
+	dir := t.TempDir()
+	appFile := filepath.Join(dir, "app.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "Dockerfile"), []byte("FROM scratch\n"), 0644))
+	hash, err := hashDirectory(filepath.Join(dir, "src"), "Dockerfile")
+	require.NoError(t, err)
+	tag := host + "/giteaadmin/my-app:" + hash[:imageTagLength]
+
+	rt := new(fakeRuntime)
+	rt.On("BuildImage", containerruntime.BuildImageOptions{
+		Context:    filepath.Join(dir, "src"),
+		Dockerfile: "Dockerfile",
+		Tag:        tag,
+	}).Return(nil).Once()
+	rt.On("PushImage", tag, containerruntime.RegistryAuth{Username: "giteaAdmin", Password: "password"}).Return(nil).Once()
+
+	r := &Reconciler{Client: &secretClient{}, ContainerRuntime: rt}
+	resource := &v1alpha1.CustomPackage{
+		Spec: v1alpha1.CustomPackageSpec{
+			ArgoCD:       v1alpha1.ArgoCDPackageSpec{ApplicationFile: appFile},
+			GitServerURL: registry.URL,
+		},
+	}
+	app := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{v1alpha1.ImagesAnnotation: `[{"name": "my-app", "context": "src"}]`},
+	}}
+
+	require.NoError(t, r.reconcileImages(ctx, resource, app))
+	expected := []v1alpha1.BuiltImage{{Name: "my-app", Image: host + "/giteaadmin/my-app@" + digest, ContextHash: hash}}
+	assert.Equal(t, expected, resource.Status.Images)
+	assert.Equal(t, []v1alpha1.ImageReplacement{{Name: "my-app", Image: host + "/giteaadmin/my-app@" + digest}},
+		imageReplacements(resource.Status.Images))
+
+	// unchanged contexts are not built again
+	require.NoError(t, r.reconcileImages(ctx, resource, app))
+	assert.Equal(t, expected, resource.Status.Images)
+	rt.AssertExpectations(t)
+
+	resource.Spec.RemoteRepository.Url = "https://github.com/cnoe-io/idpbuilder"
+	assert.Error(t, r.reconcileImages(ctx, resource, app))
+}
@@ -0,0 +1,318 @@
+package custompackage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/resources/localbuild"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	createNamespaceSyncOption = "CreateNamespace=true"
+	// scopedProjectFinalizer removes the permissions of a deleted package from its project.
+	scopedProjectFinalizer = "cnoe.io/scoped-project"
+)
+
+// application set templates are only known after generators run.
+var templateExpression = regexp.MustCompile(`{{[^}]*}}`)
+
+// projectPermissions returns what an application needs from its project. Repository URLs in spec must already point to the git server.
+func projectPermissions(appName string, annotations map[string]string, spec *argov1alpha1.ApplicationSpec) (v1alpha1.ProjectPermissions, error) {
+	perms := v1alpha1.ProjectPermissions{Name: appName}
+	if name := strings.TrimSpace(annotations[v1alpha1.ProjectAnnotation]); name != "" {
+		perms.Name = name
+	}
+
+	sources := spec.GetSources()
+	for i := range sources {
+		// repositories not yet created on the git server do not have a URL.
+		if sources[i].RepoURL == "" || isCNOEScheme(sources[i].RepoURL) {
+			continue
+		}
+		perms.SourceRepos = append(perms.SourceRepos, untemplate(sources[i].RepoURL))
+	}
+
+	dsts, err := projectDestinations(annotations, spec.Destination)
+	if err != nil {
+		return v1alpha1.ProjectPermissions{}, err
+	}
+	perms.Destinations = dsts
+
+	if spec.SyncPolicy != nil && spec.SyncPolicy.SyncOptions.HasOption(createNamespaceSyncOption) {
+		perms.ClusterResources = append(perms.ClusterResources, metav1.GroupKind{Kind: "Namespace"})
+	}
+	for _, r := range splitList(annotations[v1alpha1.ProjectClusterResourcesAnnotation]) {
+		gk, err := parseGroupKind(r)
+		if err != nil {
+			return v1alpha1.ProjectPermissions{}, fmt.Errorf("parsing %s annotation: %w", v1alpha1.ProjectClusterResourcesAnnotation, err)
+		}
+		perms.ClusterResources = append(perms.ClusterResources, gk)
+	}
+
+	return mergePermissions(perms), nil
+}
+
+// projectDestinations returns the destination and the clusters and namespaces listed in annotations.
+// Templated destinations are only known after generators run, so the annotations must list what they resolve to.
+func projectDestinations(annotations map[string]string, dst argov1alpha1.ApplicationDestination) ([]v1alpha1.ProjectDestination, error) {
+	clusters := make([]v1alpha1.ProjectDestination, 0)
+	if isTemplated(dst.Server) || isTemplated(dst.Name) {
+		if len(splitList(annotations[v1alpha1.ProjectClustersAnnotation])) == 0 {
+			return nil, fmt.Errorf("destination cluster is templated. list the clusters it resolves to in %s annotation", v1alpha1.ProjectClustersAnnotation)
+		}
+	} else {
+		clusters = append(clusters, v1alpha1.ProjectDestination{Server: dst.Server, Name: dst.Name})
+	}
+	for _, c := range splitList(annotations[v1alpha1.ProjectClustersAnnotation]) {
+		if strings.Contains(c, "://") {
+			clusters = append(clusters, v1alpha1.ProjectDestination{Server: c})
+		} else {
+			clusters = append(clusters, v1alpha1.ProjectDestination{Name: c})
+		}
+	}
+
+	namespaces := make([]string, 0)
+	if isTemplated(dst.Namespace) {
+		if len(splitList(annotations[v1alpha1.ProjectNamespacesAnnotation])) == 0 {
+			return nil, fmt.Errorf("destination namespace is templated. list the namespaces it resolves to in %s annotation", v1alpha1.ProjectNamespacesAnnotation)
+		}
+	} else {
+		namespaces = append(namespaces, dst.Namespace)
+	}
+	namespaces = append(namespaces, splitList(annotations[v1alpha1.ProjectNamespacesAnnotation])...)
+
+	out := make([]v1alpha1.ProjectDestination, 0, len(clusters)*len(namespaces))
+	for _, c := range clusters {
+		for _, ns := range namespaces {
+			out = append(out, v1alpha1.ProjectDestination{Server: c.Server, Name: c.Name, Namespace: ns})
+		}
+	}
+	return out, nil
+}
+
+// mergePermissions returns the union of permissions with duplicates removed. The name and namespace of the first one are used.
+func mergePermissions(perms ...v1alpha1.ProjectPermissions) v1alpha1.ProjectPermissions {
+	out := v1alpha1.ProjectPermissions{}
+	if len(perms) == 0 {
+		return out
+	}
+	out.Name, out.Namespace = perms[0].Name, perms[0].Namespace
+
+	repos := map[string]struct{}{}
+	dsts := map[v1alpha1.ProjectDestination]struct{}{}
+	kinds := map[metav1.GroupKind]struct{}{}
+	for i := range perms {
+		for _, r := range perms[i].SourceRepos {
+			repos[r] = struct{}{}
+		}
+		for _, d := range perms[i].Destinations {
+			dsts[d] = struct{}{}
+		}
+		for _, k := range perms[i].ClusterResources {
+			kinds[k] = struct{}{}
+		}
+	}
+
+	// sorted so that the project is not updated on every reconcile.
+	for r := range repos {
+		out.SourceRepos = append(out.SourceRepos, r)
+	}
+	sort.Strings(out.SourceRepos)
+
+	for d := range dsts {
+		out.Destinations = append(out.Destinations, d)
+	}
+	sort.Slice(out.Destinations, func(i, j int) bool {
+		a, b := out.Destinations[i], out.Destinations[j]
+		if a.Server != b.Server {
+			return a.Server < b.Server
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Namespace < b.Namespace
+	})
+
+	for k := range kinds {
+		out.ClusterResources = append(out.ClusterResources, k)
+	}
+	sort.Slice(out.ClusterResources, func(i, j int) bool {
+		a, b := out.ClusterResources[i], out.ClusterResources[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Kind < b.Kind
+	})
+	return out
+}
+
+// reconcileProject creates or updates the project with permissions of all packages sharing it.
+// Permissions of other packages are taken from their status, so permissions no longer needed are removed.
+// perms is nil when the package no longer uses the project. The project is deleted once no package uses it.
+func (r *Reconciler) reconcileProject(ctx context.Context, resource *v1alpha1.CustomPackage, name, namespace string, perms *v1alpha1.ProjectPermissions) error {
+	pkgs := v1alpha1.CustomPackageList{}
+	err := r.Client.List(ctx, &pkgs, client.InNamespace(resource.Namespace))
+	if err != nil {
+		return fmt.Errorf("listing custom packages: %w", err)
+	}
+
+	all := make([]v1alpha1.ProjectPermissions, 0, len(pkgs.Items))
+	if perms != nil {
+		all = append(all, *perms)
+	}
+	for i := range pkgs.Items {
+		p := pkgs.Items[i]
+		if p.Name == resource.Name || !p.DeletionTimestamp.IsZero() || !p.Spec.ScopedProject || p.Status.Project == nil ||
+			p.Status.Project.Name != name || projectNamespace(p.Status.Project.Namespace) != namespace {
+			continue
+		}
+		all = append(all, *p.Status.Project)
+	}
+
+	project := &argov1alpha1.AppProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	if len(all) == 0 {
+		err = r.Client.Delete(ctx, project)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("deleting argocd project %s: %w", name, err)
+		}
+		return nil
+	}
+
+	merged := mergePermissions(all...)
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, project, func() error {
+		localbuild.SetScopedProjectSpec(project, merged)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("reconciling argocd project %s: %w", name, err)
+	}
+	return nil
+}
+
+// finalizeProject removes the permissions of the package from its project, then removes the finalizer.
+func (r *Reconciler) finalizeProject(ctx context.Context, resource *v1alpha1.CustomPackage) error {
+	if !controllerutil.ContainsFinalizer(resource, scopedProjectFinalizer) {
+		return nil
+	}
+	if resource.Status.Project != nil {
+		err := r.reconcileProject(ctx, resource, resource.Status.Project.Name, projectNamespace(resource.Status.Project.Namespace), nil)
+		if err != nil {
+			return err
+		}
+	}
+	return r.patchFinalizer(ctx, resource, false)
+}
+
+// patchFinalizer adds or removes the finalizer without touching the status being reconciled.
+func (r *Reconciler) patchFinalizer(ctx context.Context, resource *v1alpha1.CustomPackage, add bool) error {
+	patched := resource.DeepCopy()
+	if add {
+		controllerutil.AddFinalizer(patched, scopedProjectFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(patched, scopedProjectFinalizer)
+	}
+	err := r.Client.Patch(ctx, patched, client.MergeFrom(resource))
+	if err != nil {
+		return fmt.Errorf("updating finalizers: %w", err)
+	}
+	resource.Finalizers = patched.Finalizers
+	resource.ResourceVersion = patched.ResourceVersion
+	return nil
+}
+
+func projectNamespace(namespace string) string {
+	if namespace == "" {
+		return globals.ArgoCDNamespace
+	}
+	return namespace
+}
+
+func isTemplated(s string) bool {
+	return templateExpression.MatchString(s)
+}
+
+func untemplate(s string) string {
+	return templateExpression.ReplaceAllString(s, "*")
+}
+
+func splitList(s string) []string {
+	out := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseGroupKind parses group/Kind. Kinds in the core group do not have a group. e.g. PersistentVolume
+func parseGroupKind(s string) (metav1.GroupKind, error) {
+	parts := strings.Split(s, "/")
+	switch len(parts) {
+	case 1:
+		return metav1.GroupKind{Kind: parts[0]}, nil
+	case 2:
+		if parts[1] == "" {
+			return metav1.GroupKind{}, fmt.Errorf("kind missing in %s", s)
+		}
+		return metav1.GroupKind{Group: parts[0], Kind: parts[1]}, nil
+	default:
+		return metav1.GroupKind{}, fmt.Errorf("expected group/Kind, got %s", s)
+	}
+}
+
+// setScopedProject points the application spec at the project generated for the package.
+func (r *Reconciler) setScopedProject(ctx context.Context, resource *v1alpha1.CustomPackage, obj client.Object, spec *argov1alpha1.ApplicationSpec) error {
+	if !resource.Spec.ScopedProject {
+		err := r.finalizeProject(ctx, resource)
+		if err != nil {
+			return err
+		}
+		resource.Status.Project = nil
+		return nil
+	}
+
+	perms, err := projectPermissions(obj.GetName(), obj.GetAnnotations(), spec)
+	if err != nil {
+		return err
+	}
+	perms.Namespace = projectNamespace(obj.GetNamespace())
+
+	if !controllerutil.ContainsFinalizer(resource, scopedProjectFinalizer) {
+		err = r.patchFinalizer(ctx, resource, true)
+		if err != nil {
+			return err
+		}
+	}
+	// the package moved to another project
+	if prev := resource.Status.Project; prev != nil && (prev.Name != perms.Name || projectNamespace(prev.Namespace) != perms.Namespace) {
+		err = r.reconcileProject(ctx, resource, prev.Name, projectNamespace(prev.Namespace), nil)
+		if err != nil {
+			return err
+		}
+	}
+	resource.Status.Project = &perms
+
+	err = r.reconcileProject(ctx, resource, perms.Name, perms.Namespace, &perms)
+	if err != nil {
+		return err
+	}
+	spec.Project = perms.Name
+	return nil
+}
@@ -0,0 +1,204 @@
+package custompackage
+
+import (
+	"context"
+	"testing"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestProjectPermissions(t *testing.T) {
+	type testCase struct {
+		name        string
+		annotations map[string]string
+		spec        argov1alpha1.ApplicationSpec
+		expected    v1alpha1.ProjectPermissions
+		expectErr   bool
+	}
+
+	gitea := "http://my-gitea-http.gitea.svc.cluster.local:3000/giteaAdmin/app-manifests"
+	cases := []testCase{
+		{
+			name: "single source",
+			spec: argov1alpha1.ApplicationSpec{
+				Source:      &argov1alpha1.ApplicationSource{RepoURL: gitea},
+				Destination: argov1alpha1.ApplicationDestination{Server: "https://kubernetes.default.svc", Namespace: "my-app"},
+				SyncPolicy:  &argov1alpha1.SyncPolicy{SyncOptions: argov1alpha1.SyncOptions{"CreateNamespace=true"}},
+			},
+			expected: v1alpha1.ProjectPermissions{
+				Name:             "app",
+				SourceRepos:      []string{gitea},
+				Destinations:     []v1alpha1.ProjectDestination{{Server: "https://kubernetes.default.svc", Namespace: "my-app"}},
+				ClusterResources: []metav1.GroupKind{{Kind: "Namespace"}},
+			},
+		},
+		{
+			name: "annotations",
+			annotations: map[string]string{
+				v1alpha1.ProjectAnnotation:                 "team-a",
+				v1alpha1.ProjectNamespacesAnnotation:       "b, a",
+				v1alpha1.ProjectClusterResourcesAnnotation: "rbac.authorization.k8s.io/ClusterRole,PersistentVolume",
+			},
+			spec: argov1alpha1.ApplicationSpec{
+				Sources: argov1alpha1.ApplicationSources{
+					{RepoURL: gitea},
+					{RepoURL: "https://charts.example.com"},
+					// not created on the git server yet
+					{RepoURL: ""},
+				},
+				Destination: argov1alpha1.ApplicationDestination{Name: "in-cluster", Namespace: "c"},
+			},
+			expected: v1alpha1.ProjectPermissions{
+				Name:        "team-a",
+				SourceRepos: []string{"http://my-gitea-http.gitea.svc.cluster.local:3000/giteaAdmin/app-manifests", "https://charts.example.com"},
+				Destinations: []v1alpha1.ProjectDestination{
+					{Name: "in-cluster", Namespace: "a"},
+					{Name: "in-cluster", Namespace: "b"},
+					{Name: "in-cluster", Namespace: "c"},
+				},
+				ClusterResources: []metav1.GroupKind{
+					{Kind: "PersistentVolume"},
+					{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"},
+				},
+			},
+		},
+		{
+			name:        "application set template",
+			annotations: map[string]string{v1alpha1.ProjectNamespacesAnnotation: "team-a,team-b"},
+			spec: argov1alpha1.ApplicationSpec{
+				Source:      &argov1alpha1.ApplicationSource{RepoURL: gitea},
+				Destination: argov1alpha1.ApplicationDestination{Server: "https://kubernetes.default.svc", Namespace: "{{path.basename}}"},
+			},
+			expected: v1alpha1.ProjectPermissions{
+				Name:        "app",
+				SourceRepos: []string{gitea},
+				Destinations: []v1alpha1.ProjectDestination{
+					{Server: "https://kubernetes.default.svc", Namespace: "team-a"},
+					{Server: "https://kubernetes.default.svc", Namespace: "team-b"},
+				},
+			},
+		},
+		{
+			name: "application set templated cluster",
+			annotations: map[string]string{
+				v1alpha1.ProjectClustersAnnotation: "localdev-staging, https://10.0.0.1:6443",
+			},
+			spec: argov1alpha1.ApplicationSpec{
+				Source:      &argov1alpha1.ApplicationSource{RepoURL: gitea},
+				Destination: argov1alpha1.ApplicationDestination{Name: "{{.name}}", Namespace: "my-app"},
+			},
+			expected: v1alpha1.ProjectPermissions{
+				Name:        "app",
+				SourceRepos: []string{gitea},
+				Destinations: []v1alpha1.ProjectDestination{
+					{Name: "localdev-staging", Namespace: "my-app"},
+					{Server: "https://10.0.0.1:6443", Namespace: "my-app"},
+				},
+			},
+		},
+		{
+			name: "templated namespace not listed",
+			spec: argov1alpha1.ApplicationSpec{
+				Source:      &argov1alpha1.ApplicationSource{RepoURL: gitea},
+				Destination: argov1alpha1.ApplicationDestination{Server: "https://kubernetes.default.svc", Namespace: "{{path.basename}}"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "templated cluster not listed",
+			spec: argov1alpha1.ApplicationSpec{
+				Source:      &argov1alpha1.ApplicationSource{RepoURL: gitea},
+				Destination: argov1alpha1.ApplicationDestination{Server: "{{server}}", Namespace: "my-app"},
+			},
+			expectErr: true,
+		},
+		{
+			name:        "invalid cluster resource",
+			annotations: map[string]string{v1alpha1.ProjectClusterResourcesAnnotation: "a/b/c"},
+			spec:        argov1alpha1.ApplicationSpec{Source: &argov1alpha1.ApplicationSource{RepoURL: gitea}},
+			expectErr:   true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			perms, err := projectPermissions("app", c.annotations, &c.spec)
+			if c.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, perms)
+		})
+	}
+}
+
+func TestMergePermissions(t *testing.T) {
+	a := v1alpha1.ProjectPermissions{
+		Name:             "group",
+		SourceRepos:      []string{"repo-b", "repo-a"},
+		Destinations:     []v1alpha1.ProjectDestination{{Server: "s", Namespace: "a"}},
+		ClusterResources: []metav1.GroupKind{{Kind: "Namespace"}},
+	}
+	b := v1alpha1.ProjectPermissions{
+		Name:             "group",
+		SourceRepos:      []string{"repo-a", "repo-c"},
+		Destinations:     []v1alpha1.ProjectDestination{{Server: "s", Namespace: "a"}, {Server: "s", Namespace: "b"}},
+		ClusterResources: []metav1.GroupKind{{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}, {Kind: "Namespace"}},
+	}
+
+	assert.Equal(t, v1alpha1.ProjectPermissions{
+		Name:             "group",
+		SourceRepos:      []string{"repo-a", "repo-b", "repo-c"},
+		Destinations:     []v1alpha1.ProjectDestination{{Server: "s", Namespace: "a"}, {Server: "s", Namespace: "b"}},
+		ClusterResources: []metav1.GroupKind{{Kind: "Namespace"}, {Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}},
+	}, mergePermissions(a, b))
+}
+
+func TestFinalizeProject(t *testing.T) {
+	ctx := context.Background()
+	scheme := k8sruntime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, argov1alpha1.AddToScheme(scheme))
+
+	now := metav1.Now()
+	pkg := func(name string, perms v1alpha1.ProjectPermissions, deleting bool) *v1alpha1.CustomPackage {
+		p := &v1alpha1.CustomPackage{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "idpbuilder-localdev", Finalizers: []string{scopedProjectFinalizer}},
+			Spec:       v1alpha1.CustomPackageSpec{ScopedProject: true},
+			Status:     v1alpha1.CustomPackageStatus{Project: &perms},
+		}
+		if deleting {
+			p.DeletionTimestamp = &now
+		}
+		return p
+	}
+	a := pkg("a", v1alpha1.ProjectPermissions{Name: "team", SourceRepos: []string{"repo-a"}}, true)
+	b := pkg("b", v1alpha1.ProjectPermissions{Name: "team", SourceRepos: []string{"repo-b"}}, false)
+	project := &argov1alpha1.AppProject{ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "argocd"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(a, b, project).WithStatusSubresource(a, b).Build()
+	r := &Reconciler{Client: c, Scheme: scheme}
+
+	// permissions of the deleted package are removed
+	require.NoError(t, r.finalizeProject(ctx, a))
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(project), project))
+	assert.Equal(t, []string{"repo-b"}, project.Spec.SourceRepos)
+	err := c.Get(ctx, client.ObjectKeyFromObject(a), &v1alpha1.CustomPackage{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	// the project is deleted with the last package using it
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(b), b))
+	require.NoError(t, r.finalizeProject(ctx, b))
+	err = c.Get(ctx, client.ObjectKeyFromObject(project), project)
+	assert.True(t, k8serrors.IsNotFound(err))
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(b), b))
+	assert.Empty(t, b.Finalizers)
+}
@@ -0,0 +1,131 @@
+package custompackage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+)
+
+const (
+	repoURLKey        = "repoURL"
+	revisionKey       = "revision"
+	targetRevisionKey = "targetRevision"
+	directoriesKey    = "directories"
+	filesKey          = "files"
+)
+
+// repoURLRewriter returns the repository on the git server for a cnoe:// URL.
+type repoURLRewriter func(repoURL string) (*v1alpha1.GitRepository, error)
+
+// rewriteRepoURLs walks v, the JSON form of an object, and replaces every cnoe:// repoURL field with the URL of the repository on the git server.
+// Git generators use revision and application sources use targetRevision. Whichever is next to the URL is set to the branch the contents were pushed to.
+// Rewritten repositories are added to refs. It returns the number of repositories without a URL yet.
+func rewriteRepoURLs(v any, rewrite repoURLRewriter, refs map[string]v1alpha1.ObjectRef) (int, error) {
+	notSynced := 0
+	switch val := v.(type) {
+	case map[string]any:
+		if u, ok := val[repoURLKey].(string); ok && isCNOEScheme(u) {
+			repo, err := rewrite(u)
+			if err != nil {
+				return 0, fmt.Errorf("reconciling %s: %w", u, err)
+			}
+			if repo.Status.InternalGitRepositoryUrl == "" {
+				notSynced += 1
+			}
+			val[repoURLKey] = repo.Status.InternalGitRepositoryUrl
+			if repo.Status.Branch != "" {
+				if isGitGenerator(val) {
+					val[revisionKey] = repo.Status.Branch
+				} else {
+					val[targetRevisionKey] = repo.Status.Branch
+				}
+			}
+			refs[repo.Name] = v1alpha1.ObjectRef{
+				Namespace: repo.Namespace,
+				Name:      repo.Name,
+				UID:       string(repo.ObjectMeta.UID),
+			}
+		}
+		for k := range val {
+			if k == repoURLKey {
+				continue
+			}
+			n, err := rewriteRepoURLs(val[k], rewrite, refs)
+			if err != nil {
+				return 0, err
+			}
+			notSynced += n
+		}
+	case []any:
+		for i := range val {
+			n, err := rewriteRepoURLs(val[i], rewrite, refs)
+			if err != nil {
+				return 0, err
+			}
+			notSynced += n
+		}
+	}
+	return notSynced, nil
+}
+
+// isGitGenerator reports whether m is a git generator. revision may be omitted in generators,
+// so generators are also recognised by the directories or files they list.
+func isGitGenerator(m map[string]any) bool {
+	for _, k := range []string{revisionKey, directoriesKey, filesKey} {
+		if _, ok := m[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteObjectRepoURLs calls rewriteRepoURLs on the JSON form of obj and returns the result decoded as T.
+func rewriteObjectRepoURLs[T any](obj T, rewrite repoURLRewriter, refs map[string]v1alpha1.ObjectRef) (T, int, error) {
+	var out T
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return out, 0, fmt.Errorf("converting to json: %w", err)
+	}
+	var data any
+	err = json.Unmarshal(raw, &data)
+	if err != nil {
+		return out, 0, fmt.Errorf("converting from json: %w", err)
+	}
+
+	notSynced, err := rewriteRepoURLs(data, rewrite, refs)
+	if err != nil {
+		return out, 0, err
+	}
+
+	raw, err = json.Marshal(data)
+	if err != nil {
+		return out, 0, fmt.Errorf("converting to json: %w", err)
+	}
+	err = json.Unmarshal(raw, &out)
+	if err != nil {
+		return out, 0, fmt.Errorf("converting from json: %w", err)
+	}
+	return out, notSynced, nil
+}
+
+// joinRepoRefs returns refs and the refs in m without duplicates, sorted by name.
+func joinRepoRefs(refs []v1alpha1.ObjectRef, m map[string]v1alpha1.ObjectRef) []v1alpha1.ObjectRef {
+	all := make(map[string]v1alpha1.ObjectRef, len(refs)+len(m))
+	for i := range refs {
+		all[refs[i].Name] = refs[i]
+	}
+	for k := range m {
+		all[k] = m[k]
+	}
+
+	out := make([]v1alpha1.ObjectRef, 0, len(all))
+	for k := range all {
+		out = append(out, all[k])
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,96 @@
+package custompackage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+func TestRewriteObjectRepoURLs(t *testing.T) {
+	// a merge generator with a nested matrix generator. nested generators are not typed beyond the first level.
+	nestedMatrix := `
+generators:
+  - git:
+      repoURL: cnoe://nested
+      directories:
+        - path: "*"
+  - list:
+      elements:
+        - cluster: in-cluster
+`
+	nestedJSON, err := yaml.YAMLToJSON([]byte(nestedMatrix))
+	assert.NoError(t, err)
+
+	generators := []argov1alpha1.ApplicationSetGenerator{
+		{
+			Merge: &argov1alpha1.MergeGenerator{
+				MergeKeys: []string{"path"},
+				Generators: []argov1alpha1.ApplicationSetNestedGenerator{
+					{
+						Git: &argov1alpha1.GitGenerator{
+							RepoURL:  "cnoe://top",
+							Revision: "HEAD",
+							Template: argov1alpha1.ApplicationSetTemplate{
+								Spec: argov1alpha1.ApplicationSpec{
+									Source: &argov1alpha1.ApplicationSource{RepoURL: "cnoe://template"},
+								},
+							},
+						},
+					},
+					{Matrix: &apiextensionsv1.JSON{Raw: nestedJSON}},
+				},
+			},
+		},
+		{
+			Git: &argov1alpha1.GitGenerator{RepoURL: "https://github.com/cnoe-io/idpbuilder", Revision: "HEAD"},
+		},
+	}
+
+	rewrite := func(repoURL string) (*v1alpha1.GitRepository, error) {
+		name := strings.TrimPrefix(repoURL, v1alpha1.CNOEURIScheme)
+		repo := &v1alpha1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", UID: types.UID("uid-" + name)},
+			Status:     v1alpha1.GitRepositoryStatus{Branch: "dev"},
+		}
+		// not pushed yet
+		if name != "nested" {
+			repo.Status.InternalGitRepositoryUrl = fmt.Sprintf("http://gitea/%s", name)
+		}
+		return repo, nil
+	}
+
+	refs := map[string]v1alpha1.ObjectRef{}
+	out, notSynced, err := rewriteObjectRepoURLs(generators, rewrite, refs)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, notSynced)
+
+	g := out[0].Merge.Generators[0].Git
+	assert.Equal(t, "http://gitea/top", g.RepoURL)
+	assert.Equal(t, "dev", g.Revision)
+	assert.Equal(t, "http://gitea/template", g.Template.Spec.Source.RepoURL)
+	assert.Equal(t, "dev", g.Template.Spec.Source.TargetRevision)
+
+	nested := argov1alpha1.MatrixGenerator{}
+	assert.NoError(t, yaml.Unmarshal(out[0].Merge.Generators[1].Matrix.Raw, &nested))
+	assert.Equal(t, "", nested.Generators[0].Git.RepoURL)
+	// set even though the generator did not specify a revision
+	assert.Equal(t, "dev", nested.Generators[0].Git.Revision)
+	assert.NotContains(t, string(out[0].Merge.Generators[1].Matrix.Raw), targetRevisionKey)
+	assert.NotNil(t, nested.Generators[1].List)
+
+	assert.Equal(t, generators[1], out[1])
+
+	assert.Equal(t, []v1alpha1.ObjectRef{
+		{Name: "nested", Namespace: "test", UID: "uid-nested"},
+		{Name: "template", Namespace: "test", UID: "uid-template"},
+		{Name: "top", Namespace: "test", UID: "uid-top"},
+	}, joinRepoRefs(nil, refs))
+}
@@ -0,0 +1,238 @@
+package custompackage
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	secretCertificateValidLength = time.Hour * 8766
+	secretCAKey                  = "ca.crt"
+	secretPasswordKey            = "password"
+	secretUsernameKey            = "username"
+)
+
+// secretSpec is an entry of the secrets annotation.
+type secretSpec struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Type      string `json:"type"`
+	// Username is stored next to the generated password.
+	Username string `json:"username,omitempty"`
+	// DNSNames of the TLS certificate.
+	DNSNames []string `json:"dnsNames,omitempty"`
+}
+
+// secretSpecs returns the secrets declared in the annotations. Secrets without namespace default to the destination namespace.
+func secretSpecs(annotations map[string]string, namespace string) ([]secretSpec, error) {
+	value := strings.TrimSpace(annotations[v1alpha1.SecretsAnnotation])
+	if value == "" {
+		return nil, nil
+	}
+
+	var specs []secretSpec
+	if err := yaml.UnmarshalStrict([]byte(value), &specs); err != nil {
+		return nil, fmt.Errorf("parsing %s annotation: %w", v1alpha1.SecretsAnnotation, err)
+	}
+
+	for i := range specs {
+		s := &specs[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("secret at index %d in %s annotation does not have a name", i, v1alpha1.SecretsAnnotation)
+		}
+		if s.Namespace == "" {
+			if namespace == "" || templateExpression.MatchString(namespace) {
+				return nil, fmt.Errorf("secret %s must specify a namespace", s.Name)
+			}
+			s.Namespace = namespace
+		}
+		switch s.Type {
+		case v1alpha1.PackageSecretTypePassword, v1alpha1.PackageSecretTypeSSH:
+		case v1alpha1.PackageSecretTypeTLS:
+			if len(s.DNSNames) == 0 {
+				return nil, fmt.Errorf("tls secret %s must specify dnsNames", s.Name)
+			}
+		default:
+			return nil, fmt.Errorf("secret %s has unsupported type %q", s.Name, s.Type)
+		}
+	}
+	return specs, nil
+}
+
+// reconcileSecrets creates the secrets declared by the package. Secrets are generated once and never overwritten.
+func (r *Reconciler) reconcileSecrets(ctx context.Context, obj client.Object, namespace string) error {
+	specs, err := secretSpecs(obj.GetAnnotations(), namespace)
+	if err != nil {
+		return err
+	}
+
+	for i := range specs {
+		s := specs[i]
+		existing := corev1.Secret{}
+		err = r.Client.Get(ctx, client.ObjectKey{Name: s.Name, Namespace: s.Namespace}, &existing)
+		if err == nil {
+			continue
+		}
+		if !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("getting secret %s/%s: %w", s.Namespace, s.Name, err)
+		}
+
+		secret, err := r.newPackageSecret(ctx, s)
+		if err != nil {
+			return fmt.Errorf("generating secret %s/%s: %w", s.Namespace, s.Name, err)
+		}
+		secret.Labels = map[string]string{
+			v1alpha1.CLISecretLabelKey:   v1alpha1.CLISecretLabelValue,
+			v1alpha1.PackageNameLabelKey: obj.GetName(),
+		}
+
+		if err = k8s.EnsureNamespace(ctx, r.Client, s.Namespace); err != nil {
+			return fmt.Errorf("creating namespace %s: %w", s.Namespace, err)
+		}
+		err = r.Client.Create(ctx, &secret)
+		if err != nil && !k8serrors.IsAlreadyExists(err) {
+			return fmt.Errorf("creating secret %s/%s: %w", s.Namespace, s.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) newPackageSecret(ctx context.Context, s secretSpec) (corev1.Secret, error) {
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.Name,
+			Namespace: s.Namespace,
+		},
+	}
+
+	switch s.Type {
+	case v1alpha1.PackageSecretTypePassword:
+		pass, err := util.GeneratePassword()
+		if err != nil {
+			return corev1.Secret{}, err
+		}
+		secret.StringData = map[string]string{secretPasswordKey: pass}
+		if s.Username != "" {
+			secret.StringData[secretUsernameKey] = s.Username
+		}
+	case v1alpha1.PackageSecretTypeSSH:
+		privateKey, publicKey, err := generateSSHKey()
+		if err != nil {
+			return corev1.Secret{}, err
+		}
+		secret.Type = corev1.SecretTypeSSHAuth
+		secret.Data = map[string][]byte{
+			corev1.SSHAuthPrivateKey: privateKey,
+			"ssh-publickey":          publicKey,
+		}
+	case v1alpha1.PackageSecretTypeTLS:
+		ca := corev1.Secret{}
+		err := r.Client.Get(ctx, client.ObjectKey{Name: globals.SelfSignedCertSecretName, Namespace: globals.NginxNamespace}, &ca)
+		if err != nil {
+			return corev1.Secret{}, fmt.Errorf("getting idpbuilder certificate authority: %w", err)
+		}
+		cert, key, err := generateSignedCertificate(ca.Data[corev1.TLSCertKey], ca.Data[corev1.TLSPrivateKeyKey], s.DNSNames)
+		if err != nil {
+			return corev1.Secret{}, err
+		}
+		secret.Type = corev1.SecretTypeTLS
+		secret.Data = map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+			secretCAKey:             ca.Data[corev1.TLSCertKey],
+		}
+	}
+	return secret, nil
+}
+
+// generateSSHKey returns an ed25519 private key in OpenSSH format and its public key in authorized keys format.
+func generateSSHKey() ([]byte, []byte, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating ssh key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshalling ssh private key: %w", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("converting ssh public key: %w", err)
+	}
+	return pem.EncodeToMemory(block), ssh.MarshalAuthorizedKey(sshPub), nil
+}
+
+// generateSignedCertificate returns a server certificate for dnsNames and its key, signed by the given certificate authority.
+func generateSignedCertificate(caCertPEM, caKeyPEM []byte, dnsNames []string) ([]byte, []byte, error) {
+	certBlock, _ := pem.Decode(caCertPEM)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("decoding certificate authority certificate")
+	}
+	caCert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing certificate authority certificate: %w", err)
+	}
+	keyBlock, _ := pem.Decode(caKeyPEM)
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("decoding certificate authority key")
+	}
+	caKey, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing certificate authority key: %w", err)
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating private key: %w", err)
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating certificate serial number: %w", err)
+	}
+
+	notBefore := time.Now()
+	cert := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   dnsNames[0],
+			Organization: caCert.Subject.Organization,
+		},
+		NotBefore:   notBefore,
+		NotAfter:    notBefore.Add(secretCertificateValidLength),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    dnsNames,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, &cert, caCert, &privateKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating certificate: %w", err)
+	}
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal private key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes}), nil
+}
@@ -0,0 +1,141 @@
+package custompackage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSecretSpecs(t *testing.T) {
+	type testCase struct {
+		name      string
+		value     string
+		namespace string
+		expected  []secretSpec
+		expectErr bool
+	}
+
+	cases := []testCase{
+		{
+			name:      "no annotation",
+			namespace: "my-app",
+		},
+		{
+			name: "all types",
+			value: `
+- name: db
+  type: password
+  username: admin
+- name: web-tls
+  namespace: web
+  type: tls
+  dnsNames: [web.cnoe.localtest.me]
+- name: deploy-key
+  type: ssh
+`,
+			namespace: "my-app",
+			expected: []secretSpec{
+				{Name: "db", Namespace: "my-app", Type: v1alpha1.PackageSecretTypePassword, Username: "admin"},
+				{Name: "web-tls", Namespace: "web", Type: v1alpha1.PackageSecretTypeTLS, DNSNames: []string{"web.cnoe.localtest.me"}},
+				{Name: "deploy-key", Namespace: "my-app", Type: v1alpha1.PackageSecretTypeSSH},
+			},
+		},
+		{
+			name:      "templated namespace",
+			value:     `[{"name": "db", "type": "password"}]`,
+			namespace: "{{name}}",
+			expectErr: true,
+		},
+		{
+			name:      "tls without dns names",
+			value:     `[{"name": "web-tls", "type": "tls"}]`,
+			namespace: "my-app",
+			expectErr: true,
+		},
+		{
+			name:      "unknown type",
+			value:     `[{"name": "db", "type": "token"}]`,
+			namespace: "my-app",
+			expectErr: true,
+		},
+		{
+			name:      "unknown field",
+			value:     `[{"name": "db", "type": "password", "length": 10}]`,
+			namespace: "my-app",
+			expectErr: true,
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			specs, err := secretSpecs(map[string]string{v1alpha1.SecretsAnnotation: c.value}, c.namespace)
+			if c.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, specs)
+		})
+	}
+}
+
+func TestGenerateSignedCertificate(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"cnoe.io"}},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caBytes, err := x509.CreateCertificate(rand.Reader, &caTemplate, &caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caKeyBytes, err := x509.MarshalPKCS8PrivateKey(caKey)
+	require.NoError(t, err)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caBytes})
+	certPEM, keyPEM, err := generateSignedCertificate(caPEM,
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: caKeyBytes}), []string{"web.cnoe.localtest.me"})
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: "web.cnoe.localtest.me", Roots: roots})
+	assert.NoError(t, err)
+
+	block, _ = pem.Decode(keyPEM)
+	require.NotNil(t, block)
+	_, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	assert.NoError(t, err)
+}
+
+func TestGenerateSSHKey(t *testing.T) {
+	privateKey, publicKey, err := generateSSHKey()
+	require.NoError(t, err)
+
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	require.NoError(t, err)
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+	require.NoError(t, err)
+	assert.Equal(t, pub.Marshal(), signer.PublicKey().Marshal())
+}
@@ -0,0 +1,61 @@
+package gitrepository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	argoCDSecretTypeLabelKey             = "argocd.argoproj.io/secret-type"
+	argoCDSecretTypeLabelValueRepository = "repository"
+	argoCDRepositorySecretPrefix         = "repo-"
+)
+
+// argoCDRepositorySecretName returns the name of the ArgoCD repository secret of the GitRepository.
+func argoCDRepositorySecretName(repo *v1alpha1.GitRepository) string {
+	return fmt.Sprintf("%s%s-%s", argoCDRepositorySecretPrefix, repo.Namespace, repo.Name)
+}
+
+// ensureArgoCDRepositorySecret gives ArgoCD the credentials to fetch private repositories.
+// The URL must match the one applications use, which is the URL reachable within the cluster.
+func ensureArgoCDRepositorySecret(ctx context.Context, kubeClient client.Client, repo *v1alpha1.GitRepository, info repoInfo, creds gitProviderCredentials) error {
+	url := info.internalGitRepositoryUrl
+	if url == "" {
+		url = info.cloneUrl
+	}
+	password := creds.password
+	if password == "" {
+		password = creds.accessToken
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      argoCDRepositorySecretName(repo),
+			Namespace: globals.ArgoCDNamespace,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, kubeClient, secret, func() error {
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[argoCDSecretTypeLabelKey] = argoCDSecretTypeLabelValueRepository
+		secret.Data = map[string][]byte{
+			"type":     []byte("git"),
+			"url":      []byte(url),
+			"username": []byte(creds.username),
+			"password": []byte(password),
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("creating argocd repository secret %s: %w", secret.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package gitrepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureArgoCDRepositorySecret(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).Build()
+	repo := &v1alpha1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "platform"},
+		Spec: v1alpha1.GitRepositorySpec{
+			Repository: v1alpha1.RepositorySettings{Private: true},
+		},
+	}
+	info := repoInfo{
+		cloneUrl:                 "https://gitea.cnoe.localtest.me:8443/giteaAdmin/platform-payments.git",
+		internalGitRepositoryUrl: "http://my-gitea-http.gitea.svc.cluster.local:3000/giteaAdmin/platform-payments.git",
+	}
+
+	err := ensureArgoCDRepositorySecret(ctx, kubeClient, repo, info, gitProviderCredentials{username: "giteaAdmin", password: "old"})
+	require.NoError(t, err)
+	// credentials are kept up to date
+	err = ensureArgoCDRepositorySecret(ctx, kubeClient, repo, info, gitProviderCredentials{username: "giteaAdmin", password: "new"})
+	require.NoError(t, err)
+
+	secret := corev1.Secret{}
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKey{Name: "repo-platform-payments", Namespace: globals.ArgoCDNamespace}, &secret))
+	assert.Equal(t, argoCDSecretTypeLabelValueRepository, secret.Labels[argoCDSecretTypeLabelKey])
+	assert.Equal(t, map[string][]byte{
+		"type":     []byte("git"),
+		"url":      []byte(info.internalGitRepositoryUrl),
+		"username": []byte("giteaAdmin"),
+		"password": []byte("new"),
+	}, secret.Data)
+}
@@ -0,0 +1,122 @@
+package gitrepository
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/user"
+	"strings"
+	"text/template"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// BranchTemplateData is available to the branch template in GitRepositorySpec.
+type BranchTemplateData struct {
+	// User is the name of the user running idpbuilder.
+	User string
+	// SourceBranch is the branch checked out in the source directory or the ref of the remote repository.
+	SourceBranch string
+}
+
+// getTargetBranch renders the branch to push contents to. The default branch of the repository is used when the spec does not specify one.
+func getTargetBranch(repo *v1alpha1.GitRepository, sourceBranch string) (string, error) {
+	if repo.Spec.Branch == "" {
+		return getDefaultBranch(*repo), nil
+	}
+
+	t, err := template.New("branch").Option("missingkey=error").Parse(repo.Spec.Branch)
+	if err != nil {
+		return "", fmt.Errorf("parsing branch template %s: %w", repo.Spec.Branch, err)
+	}
+
+	data := BranchTemplateData{
+		User:         currentUserName(),
+		SourceBranch: sourceBranch,
+	}
+	if data.SourceBranch == "" {
+		data.SourceBranch = getDefaultBranch(*repo)
+	}
+
+	b := new(bytes.Buffer)
+	err = t.Execute(b, data)
+	if err != nil {
+		return "", fmt.Errorf("rendering branch template %s: %w", repo.Spec.Branch, err)
+	}
+
+	branch := strings.TrimSpace(b.String())
+	err = plumbing.NewBranchReferenceName(branch).Validate()
+	if err != nil {
+		return "", fmt.Errorf("invalid branch name %s: %w", branch, err)
+	}
+	return branch, nil
+}
+
+func currentUserName() string {
+	u, err := user.Current()
+	if err != nil {
+		return "unknown"
+	}
+	// windows user names may include the domain. e.g. DOMAIN\user
+	name := u.Username
+	if i := strings.LastIndex(name, "\\"); i != -1 {
+		name = name[i+1:]
+	}
+	return strings.ReplaceAll(strings.ToLower(name), " ", "-")
+}
+
+// localSourceBranch returns the branch checked out at path. Empty string is returned if path is not in a git repository or head is detached.
+func localSourceBranch(path string) string {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return ""
+	}
+	return headBranch(repo)
+}
+
+func headBranch(repo *git.Repository) string {
+	head, err := repo.Head()
+	if err != nil || !head.Name().IsBranch() {
+		return ""
+	}
+	return head.Name().Short()
+}
+
+// checkoutBranch switches the worktree to branch. The branch starts from the remote branch if it exists, otherwise from the current head.
+func checkoutBranch(repo *git.Repository, branch string) error {
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("getting head: %w", err)
+	}
+	if head.Name().Short() == branch {
+		return nil
+	}
+
+	start := head.Hash()
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch), true)
+	if err == nil {
+		start = remoteRef.Hash()
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return fmt.Errorf("getting remote branch %s: %w", branch, err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("getting git worktree: %w", err)
+	}
+
+	refName := plumbing.NewBranchReferenceName(branch)
+	// always start from the remote state to match a fresh clone. the local branch may be left over from a previous run.
+	err = repo.Storer.SetReference(plumbing.NewHashReference(refName, start))
+	if err != nil {
+		return fmt.Errorf("setting branch %s: %w", branch, err)
+	}
+
+	err = wt.Checkout(&git.CheckoutOptions{Branch: refName, Force: true})
+	if err != nil {
+		return fmt.Errorf("checking out branch %s: %w", branch, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package gitrepository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetTargetBranch(t *testing.T) {
+	type testCase struct {
+		branch        string
+		sourceBranch  string
+		defaultBranch string
+		expect        string
+		err           bool
+	}
+
+	cases := []testCase{
+		{branch: "", sourceBranch: "feature", expect: DefaultBranchName},
+		{branch: "preview", sourceBranch: "feature", expect: "preview"},
+		{branch: "preview/{{ .SourceBranch }}", sourceBranch: "feature/a", expect: "preview/feature/a"},
+		{branch: "{{ .SourceBranch }}", sourceBranch: "", expect: DefaultBranchName},
+		{branch: "", sourceBranch: "feature", defaultBranch: "trunk", expect: "trunk"},
+		{branch: "{{ .User }}/x", expect: currentUserName() + "/x"},
+		{branch: "{{ .Nope }}", err: true},
+		{branch: "a..b", err: true},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		repo := &v1alpha1.GitRepository{Spec: v1alpha1.GitRepositorySpec{
+			Branch:     c.branch,
+			Repository: v1alpha1.RepositorySettings{DefaultBranch: c.defaultBranch},
+		}}
+		b, err := getTargetBranch(repo, c.sourceBranch)
+		if c.err {
+			assert.Error(t, err, c.branch)
+			continue
+		}
+		assert.NoError(t, err, c.branch)
+		assert.Equal(t, c.expect, b)
+	}
+}
+
+func TestGitRepositoryContentReconcileBranch(t *testing.T) {
+	ctx := context.Background()
+	localRepoDir, initHash, err := setUpLocalRepo()
+	defer os.RemoveAll(localRepoDir)
+	assert.NoError(t, err)
+
+	srcDir, err := setupDir()
+	defer os.RemoveAll(srcDir)
+	assert.NoError(t, err)
+
+	resource := v1alpha1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: v1alpha1.GitRepositorySpec{
+			Source: v1alpha1.GitRepositorySource{
+				Path: srcDir,
+				Type: v1alpha1.SourceTypeLocal,
+			},
+			Branch: "preview",
+		},
+	}
+	p := giteaProvider{Client: &fakeClient{}, giteaClient: mockGitea{}}
+
+	err = p.updateRepoContent(ctx, &resource, repoInfo{cloneUrl: localRepoDir}, gitProviderCredentials{}, util.GitAuthOptions{}, t.TempDir(), util.NewRepoLock())
+	assert.NoError(t, err)
+	assert.Equal(t, "preview", resource.Status.Branch)
+
+	remote, err := git.PlainOpen(localRepoDir)
+	assert.NoError(t, err)
+
+	// default branch is untouched
+	main, err := remote.Reference(plumbing.NewBranchReferenceName(DefaultBranchName), true)
+	assert.NoError(t, err)
+	assert.Equal(t, initHash, main.Hash().String())
+
+	preview, err := remote.Reference(plumbing.NewBranchReferenceName("preview"), true)
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Status.LatestCommit.Hash, preview.Hash().String())
+
+	c, err := remote.CommitObject(preview.Hash())
+	assert.NoError(t, err)
+	_, err = c.File(filepath.Base("add"))
+	assert.NoError(t, err)
+}
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"code.gitea.io/sdk/gitea"
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
@@ -35,6 +39,9 @@ const (
 	gitHTTPTimeout = 30 * time.Second
 )
 
+// largeFileWarnings holds large files already reported.
+var largeFileWarnings sync.Map
+
 func init() {
 	configureGitClient()
 }
@@ -47,6 +54,10 @@ type RepositoryReconciler struct {
 	GitProviderFunc gitProviderFunc
 	TempDir         string
 	RepoMap         *util.RepoMap
+	// GitAuth is used to authenticate against remote source repositories.
+	GitAuth util.GitAuthOptions
+	// SourceTypes limits reconciliation to repositories with these source types. All repositories are reconciled when empty.
+	SourceTypes []string
 }
 
 type gitProviderFunc func(context.Context, *v1alpha1.GitRepository, client.Client, *runtime.Scheme, v1alpha1.BuildCustomizationSpec) (gitProvider, error)
@@ -58,9 +69,19 @@ func (n notFoundError) Error() string {
 }
 
 func getRepositoryName(repo v1alpha1.GitRepository) string {
+	if repo.Spec.Repository.Name != "" {
+		return repo.Spec.Repository.Name
+	}
 	return fmt.Sprintf("%s-%s", repo.Namespace, repo.Name)
 }
 
+func getDefaultBranch(repo v1alpha1.GitRepository) string {
+	if repo.Spec.Repository.DefaultBranch != "" {
+		return repo.Spec.Repository.DefaultBranch
+	}
+	return DefaultBranchName
+}
+
 func getOrganizationName(repo v1alpha1.GitRepository) string {
 	return repo.Spec.Provider.OrganizationName
 }
@@ -93,6 +114,11 @@ func GetGitProvider(ctx context.Context, repo *v1alpha1.GitRepository, kubeClien
 	return nil, fmt.Errorf("invalid git provider %s ", repo.Spec.Provider.Name)
 }
 
+// +kubebuilder:rbac:groups=idpbuilder.cnoe.io,resources=gitrepositories,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=idpbuilder.cnoe.io,resources=gitrepositories/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
 func (r *RepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
@@ -164,7 +190,14 @@ func (r *RepositoryReconciler) reconcileGitRepo(ctx context.Context, repo *v1alp
 		providerRepo = p
 	}
 
-	err = provider.updateRepoContent(ctx, repo, providerRepo, creds, r.TempDir, r.RepoMap)
+	if repo.Spec.Repository.Private {
+		err = ensureArgoCDRepositorySecret(ctx, r.Client, repo, providerRepo, creds)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	err = provider.updateRepoContent(ctx, repo, providerRepo, creds, r.GitAuth, r.TempDir, r.RepoMap)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("updating repository contents: %w", err)
 	}
@@ -179,6 +212,9 @@ func (r *RepositoryReconciler) SetupWithManager(mgr ctrl.Manager, notifyChan cha
 	// TODO: should use notifyChan to trigger reconcile when FS changes
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.GitRepository{}).
+		WithEventFilter(util.SourceTypePredicate(r.SourceTypes, func(obj client.Object) string {
+			return obj.(*v1alpha1.GitRepository).Spec.Source.Type
+		})).
 		Complete(r)
 }
 
@@ -215,14 +251,61 @@ func addAllAndCommit(path string, gitRepo *git.Repository) (plumbing.Hash, bool,
 	return h, true, nil
 }
 
+// copyLocalSource copies files that are not ignored by .gitignore or .idpbuilderignore files.
+func copyLocalSource(ctx context.Context, srcPath, dstPath string) error {
+	logger := log.FromContext(ctx)
+
+	report, err := util.CopyDirectoryWithIgnore(srcPath, dstPath, util.DefaultLargeFileThreshold)
+	if err != nil {
+		return fmt.Errorf("copying files: %w", err)
+	}
+
+	if len(report.Ignored) > 0 {
+		logger.V(1).Info("ignored files", "path", srcPath, "files", report.Ignored)
+	}
+	for i := range report.LargeFiles {
+		// sources are copied on every reconcile. warn once per file.
+		if _, warned := largeFileWarnings.LoadOrStore(filepath.Join(srcPath, report.LargeFiles[i]), struct{}{}); warned {
+			continue
+		}
+		logger.Info("WARNING: large file in package. add it to .idpbuilderignore if it should not be pushed",
+			"path", srcPath, "file", report.LargeFiles[i], "threshold", util.DefaultLargeFileThreshold)
+	}
+	return nil
+}
+
+// clearWorktree removes everything but the git directory from the worktree at dir.
+func clearWorktree(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if entries[i].Name() == git.GitDirName {
+			continue
+		}
+		rErr := os.RemoveAll(filepath.Join(dir, entries[i].Name()))
+		if rErr != nil {
+			return rErr
+		}
+	}
+	return nil
+}
+
 func pushToRemote(ctx context.Context, remoteRepo *git.Repository, creds gitProviderCredentials) error {
 	auth, err := getBasicAuth(creds)
 	if err != nil {
 		return fmt.Errorf("getting basic auth: %w", err)
 	}
+	head, err := remoteRepo.Head()
+	if err != nil {
+		return fmt.Errorf("getting head: %w", err)
+	}
+	// push the checked out branch only. other local branches may be out of date.
 	return remoteRepo.PushContext(ctx, &git.PushOptions{
 		Auth:            &auth,
 		InsecureSkipTLS: true,
+		RefSpecs:        []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:%s", head.Name(), head.Name()))},
 	})
 }
 
@@ -232,8 +315,10 @@ func reconcileLocalRepoContent(ctx context.Context, repo *v1alpha1.GitRepository
 	tgtCloneDir := util.RepoDir(tgtRepo.cloneUrl, tmpDir)
 
 	st := repoMap.LoadOrStore(tgtRepo.cloneUrl, tgtCloneDir)
-	st.MU.Lock()
-	defer st.MU.Unlock()
+	if err := st.Lock(); err != nil {
+		return fmt.Errorf("locking %s: %w", tgtCloneDir, err)
+	}
+	defer st.Unlock()
 
 	tgtRepoSpec := v1alpha1.RemoteRepositorySpec{
 		CloneSubmodules: false,
@@ -242,16 +327,47 @@ func reconcileLocalRepoContent(ctx context.Context, repo *v1alpha1.GitRepository
 		Ref:             "",
 	}
 	logger.V(1).Info("cloning repo", "repoUrl", tgtRepoSpec.Url, "fallbackUrl", getFallbackRepositoryURL(repo, tgtRepo), "cloneDir", tgtCloneDir)
-	_, tgtRepository, err := util.CloneRemoteRepoToDir(ctx, tgtRepoSpec, 1, true, tgtCloneDir, getFallbackRepositoryURL(repo, tgtRepo))
+	_, tgtRepository, err := util.CloneRemoteRepoToDir(ctx, tgtRepoSpec, 1, true, nil, tgtCloneDir, getFallbackRepositoryURL(repo, tgtRepo))
 	if err != nil {
 		return fmt.Errorf("cloning repo %s: %w", tgtRepoSpec.Url, err)
 	}
 
-	err = writeRepoContents(repo, tgtCloneDir, tmplConfig, scheme)
+	branch, err := getTargetBranch(repo, localSourceBranch(repo.Spec.Source.Path))
+	if err != nil {
+		return err
+	}
+	err = checkoutBranch(tgtRepository, branch)
+	if err != nil {
+		return fmt.Errorf("checking out branch in %s: %w", tgtRepoSpec.Url, err)
+	}
+	repo.Status.Branch = branch
+
+	if repo.Spec.Source.Type == v1alpha1.SourceTypeLocal && repo.Spec.Source.MirrorHistory {
+		mirrored, mErr := mirrorLocalRepoContent(ctx, repo, tgtRepository, tgtCloneDir, creds)
+		if mErr != nil {
+			return fmt.Errorf("mirroring history: %w", mErr)
+		}
+		if mirrored {
+			return nil
+		}
+	}
+
+	// clones are reused, so files removed from the source must be removed here too.
+	err = clearWorktree(tgtCloneDir)
+	if err != nil {
+		return fmt.Errorf("clearing worktree %s: %w", tgtCloneDir, err)
+	}
+
+	err = writeRepoContents(ctx, repo, tgtCloneDir, tmplConfig, scheme)
 	if err != nil {
 		return fmt.Errorf("writing repo contents: %w", err)
 	}
 
+	err = replaceImages(tgtCloneDir, repo.Spec.Source.Images)
+	if err != nil {
+		return fmt.Errorf("replacing images: %w", err)
+	}
+
 	hash, push, err := addAllAndCommit(repo.Spec.Source.Path, tgtRepository)
 	if err != nil {
 		return fmt.Errorf("add and commit %w", err)
@@ -278,21 +394,38 @@ func reconcileLocalRepoContent(ctx context.Context, repo *v1alpha1.GitRepository
 }
 
 // add files from another repository at specified path to target repository (gitea for now)
-func reconcileRemoteRepoContent(ctx context.Context, repo *v1alpha1.GitRepository, tgtRepo repoInfo, creds gitProviderCredentials, tmpDir string, repoMap *util.RepoMap) error {
+func reconcileRemoteRepoContent(ctx context.Context, repo *v1alpha1.GitRepository, tgtRepo repoInfo, creds gitProviderCredentials, srcAuth util.GitAuthOptions, tmpDir string, repoMap *util.RepoMap) error {
 	logger := log.FromContext(ctx)
 	srcRepo := repo.Spec.Source.RemoteRepository
 	cloneDir := util.RepoDir(srcRepo.Url, tmpDir)
 
 	st := repoMap.LoadOrStore(srcRepo.Url, cloneDir)
-	st.MU.Lock()
-	defer st.MU.Unlock()
+	if err := st.Lock(); err != nil {
+		return fmt.Errorf("locking %s: %w", cloneDir, err)
+	}
+	defer st.Unlock()
+
+	auth, err := srcAuth.AuthMethod(ctx, srcRepo.Url)
+	if err != nil {
+		return fmt.Errorf("getting credentials for %s: %w", srcRepo.Url, err)
+	}
 
 	logger.V(1).Info("cloning repo", "repoUrl", srcRepo.Url, "fallbackUrl", "", "cloneDir", cloneDir)
-	remoteWT, _, err := util.CloneRemoteRepoToDir(ctx, srcRepo, 1, false, cloneDir, "")
+	remoteWT, remoteRepository, err := util.CloneRemoteRepoToDir(ctx, srcRepo, 1, false, auth, cloneDir, "")
 	if err != nil {
 		return fmt.Errorf("cloning repo, %s: %w", srcRepo.Url, err)
 	}
 
+	sourceBranch := headBranch(remoteRepository)
+	if sourceBranch == "" {
+		// detached at a tag or commit
+		sourceBranch = srcRepo.Ref
+	}
+	branch, err := getTargetBranch(repo, sourceBranch)
+	if err != nil {
+		return err
+	}
+
 	tgtRepoSpec := v1alpha1.RemoteRepositorySpec{
 		CloneSubmodules: false,
 		Path:            ".",
@@ -303,15 +436,28 @@ func reconcileRemoteRepoContent(ctx context.Context, repo *v1alpha1.GitRepositor
 	tgtCloneDir := util.RepoDir(tgtRepo.cloneUrl, tmpDir)
 	lst := repoMap.LoadOrStore(tgtRepoSpec.Url, tgtCloneDir)
 
-	lst.MU.Lock()
-	defer lst.MU.Unlock()
+	if err = lst.Lock(); err != nil {
+		return fmt.Errorf("locking %s: %w", tgtCloneDir, err)
+	}
+	defer lst.Unlock()
 
 	logger.V(1).Info("cloning repo", "repoUrl", tgtRepoSpec.Url, "fallbackUrl", getFallbackRepositoryURL(repo, tgtRepo), "cloneDir", tgtCloneDir)
-	tgtRepoWT, tgtRepository, err := util.CloneRemoteRepoToDir(ctx, tgtRepoSpec, 1, true, tgtCloneDir, getFallbackRepositoryURL(repo, tgtRepo))
+	tgtRepoWT, tgtRepository, err := util.CloneRemoteRepoToDir(ctx, tgtRepoSpec, 1, true, nil, tgtCloneDir, getFallbackRepositoryURL(repo, tgtRepo))
 	if err != nil {
 		return fmt.Errorf("cloning repo %s: %w", srcRepo.Url, err)
 	}
 
+	err = checkoutBranch(tgtRepository, branch)
+	if err != nil {
+		return fmt.Errorf("checking out branch in %s: %w", tgtRepoSpec.Url, err)
+	}
+	repo.Status.Branch = branch
+
+	err = clearWorktree(tgtCloneDir)
+	if err != nil {
+		return fmt.Errorf("clearing worktree %s: %w", tgtCloneDir, err)
+	}
+
 	err = util.CopyTreeToTree(remoteWT, tgtRepoWT, fmt.Sprintf("/%s", repo.Spec.Source.Path), ".")
 	if err != nil {
 		return fmt.Errorf("copying contents, %s: %w", tgtRepo.cloneUrl, err)
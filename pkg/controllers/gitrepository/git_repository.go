@@ -12,13 +12,17 @@ import (
 type GiteaClient interface {
 	CreateAccessToken(option gitea.CreateAccessTokenOption) (*gitea.AccessToken, *gitea.Response, error)
 	CreateOrg(opt gitea.CreateOrgOption) (*gitea.Organization, *gitea.Response, error)
+	CreateOrgRepo(org string, opt gitea.CreateRepoOption) (*gitea.Repository, *gitea.Response, error)
 	CreateRepo(opt gitea.CreateRepoOption) (*gitea.Repository, *gitea.Response, error)
+	CreateRepoHook(user, repo string, opt gitea.CreateHookOption) (*gitea.Hook, *gitea.Response, error)
 	DeleteOrg(orgname string) (*gitea.Response, error)
 	DeleteRepo(owner, repo string) (*gitea.Response, error)
 	GetOrg(orgname string) (*gitea.Organization, *gitea.Response, error)
 	GetRepo(owner, reponame string) (*gitea.Repository, *gitea.Response, error)
+	ListRepoHooks(user, repo string, opt gitea.ListHooksOptions) ([]*gitea.Hook, *gitea.Response, error)
 	SetBasicAuth(username, password string)
 	SetContext(ctx context.Context)
+	SetRepoTopics(user, repo string, list []string) (*gitea.Response, error)
 }
 
 type gitHubClient interface {
@@ -45,5 +49,5 @@ type gitProvider interface {
 	getProviderCredentials(ctx context.Context, repo *v1alpha1.GitRepository) (gitProviderCredentials, error)
 	getRepository(ctx context.Context, repo *v1alpha1.GitRepository) (repoInfo, error)
 	setProviderCredentials(ctx context.Context, repo *v1alpha1.GitRepository, creds gitProviderCredentials) error
-	updateRepoContent(ctx context.Context, repo *v1alpha1.GitRepository, repoInfo repoInfo, creds gitProviderCredentials, tmpDir string, repoMap *util.RepoMap) error
+	updateRepoContent(ctx context.Context, repo *v1alpha1.GitRepository, repoInfo repoInfo, creds gitProviderCredentials, srcAuth util.GitAuthOptions, tmpDir string, repoMap *util.RepoMap) error
 }
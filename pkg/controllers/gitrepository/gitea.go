@@ -3,6 +3,7 @@ package gitrepository
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 
@@ -29,28 +30,107 @@ type giteaProvider struct {
 	Scheme      *runtime.Scheme
 	giteaClient GiteaClient
 	config      v1alpha1.BuildCustomizationSpec
+	// username of the authenticated user. repositories owned by other users are created in organizations.
+	username string
 }
 
 func (g *giteaProvider) createRepository(ctx context.Context, repo *v1alpha1.GitRepository) (repoInfo, error) {
-	resp, _, err := g.giteaClient.CreateRepo(gitea.CreateRepoOption{
-		Name:        getRepositoryName(*repo),
-		Description: fmt.Sprintf("created by Git Repository controller for %s in %s namespace", repo.Name, repo.Namespace),
-		// we should reconsider this when targeting non-local clusters.
-		Private:       false,
-		DefaultBranch: DefaultBranchName,
+	description := repo.Spec.Repository.Description
+	if description == "" {
+		description = fmt.Sprintf("created by Git Repository controller for %s in %s namespace", repo.Name, repo.Namespace)
+	}
+	opt := gitea.CreateRepoOption{
+		Name:          getRepositoryName(*repo),
+		Description:   description,
+		Private:       repo.Spec.Repository.Private,
+		DefaultBranch: getDefaultBranch(*repo),
 		AutoInit:      true,
-	})
+	}
 
+	var resp *gitea.Repository
+	var err error
+	owner := getOrganizationName(*repo)
+	if owner == "" || owner == g.username {
+		owner = g.username
+		resp, _, err = g.giteaClient.CreateRepo(opt)
+	} else {
+		if err = g.ensureOrganization(owner); err != nil {
+			return repoInfo{}, err
+		}
+		resp, _, err = g.giteaClient.CreateOrgRepo(owner, opt)
+	}
 	if err != nil {
 		return repoInfo{}, fmt.Errorf("failed to create git repository: %w", err)
 	}
+
+	if len(repo.Spec.Repository.Topics) > 0 {
+		_, err = g.giteaClient.SetRepoTopics(owner, resp.Name, repo.Spec.Repository.Topics)
+		if err != nil {
+			return repoInfo{}, fmt.Errorf("setting topics of git repository %s: %w", resp.FullName, err)
+		}
+	}
+
+	if err = g.ensureWebhook(ctx, owner, resp.Name); err != nil {
+		return repoInfo{}, fmt.Errorf("creating webhook of git repository %s: %w", resp.FullName, err)
+	}
+
 	return repoInfo{
-		name:     resp.Name,
-		fullName: resp.FullName,
-		cloneUrl: resp.CloneURL,
+		name:                     resp.Name,
+		fullName:                 resp.FullName,
+		cloneUrl:                 resp.CloneURL,
+		internalGitRepositoryUrl: getInternalGiteaRepositoryURL(getRepositoryName(*repo), getOrganizationName(*repo), repo.Spec.Provider.InternalGitURL),
 	}, nil
 }
 
+func (g *giteaProvider) ensureOrganization(name string) error {
+	_, resp, err := g.giteaClient.GetOrg(name)
+	if err == nil {
+		return nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("getting organization %s: %w", name, err)
+	}
+	_, _, err = g.giteaClient.CreateOrg(gitea.CreateOrgOption{Name: name, Visibility: gitea.VisibleTypePublic})
+	if err != nil {
+		return fmt.Errorf("creating organization %s: %w", name, err)
+	}
+	return nil
+}
+
+// ensureWebhook registers the ArgoCD webhook so pushes refresh applications immediately instead of at the next poll.
+func (g *giteaProvider) ensureWebhook(ctx context.Context, owner, name string) error {
+	secret, err := localbuild.GetArgoCDWebhookSecret(ctx, g.Client)
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return nil
+	}
+
+	url := localbuild.ArgoCDWebhookURL(g.config)
+	hooks, _, err := g.giteaClient.ListRepoHooks(owner, name, gitea.ListHooksOptions{})
+	if err != nil {
+		return fmt.Errorf("listing webhooks: %w", err)
+	}
+	for i := range hooks {
+		if hooks[i].Config["url"] == url {
+			return nil
+		}
+	}
+
+	_, _, err = g.giteaClient.CreateRepoHook(owner, name, gitea.CreateHookOption{
+		Type: gitea.HookTypeGogs,
+		Config: map[string]string{
+			"url":          url,
+			"content_type": "json",
+			"secret":       secret,
+		},
+		Events: []string{"push"},
+		Active: true,
+	})
+	return err
+}
+
 func (g *giteaProvider) getProviderCredentials(ctx context.Context, repo *v1alpha1.GitRepository) (gitProviderCredentials, error) {
 	var secret v1.Secret
 	err := g.Client.Get(ctx, types.NamespacedName{
@@ -78,6 +158,7 @@ func (g *giteaProvider) getProviderCredentials(ctx context.Context, repo *v1alph
 func (g *giteaProvider) setProviderCredentials(ctx context.Context, repo *v1alpha1.GitRepository, creds gitProviderCredentials) error {
 	g.giteaClient.SetBasicAuth(creds.username, creds.password)
 	g.giteaClient.SetContext(ctx)
+	g.username = creds.username
 	return nil
 }
 
@@ -90,11 +171,20 @@ func (g *giteaProvider) getRepository(ctx context.Context, repo *v1alpha1.GitRep
 		return repoInfo{}, err
 	}
 
+	// repositories created before the webhook secret existed, or whose webhook was removed, get it on the next reconcile.
+	owner := getOrganizationName(*repo)
+	if resp.Owner != nil {
+		owner = resp.Owner.UserName
+	}
+	if err = g.ensureWebhook(ctx, owner, resp.Name); err != nil {
+		return repoInfo{}, fmt.Errorf("creating webhook of git repository %s: %w", resp.FullName, err)
+	}
+
 	return repoInfo{
 		name:                     resp.Name,
 		fullName:                 resp.FullName,
 		cloneUrl:                 resp.CloneURL,
-		internalGitRepositoryUrl: getInternalGiteaRepositoryURL(repo.Namespace, repo.Name, repo.Spec.Provider.InternalGitURL),
+		internalGitRepositoryUrl: getInternalGiteaRepositoryURL(getRepositoryName(*repo), getOrganizationName(*repo), repo.Spec.Provider.InternalGitURL),
 	}, nil
 }
 
@@ -103,6 +193,7 @@ func (g *giteaProvider) updateRepoContent(
 	repo *v1alpha1.GitRepository,
 	repoInfo repoInfo,
 	creds gitProviderCredentials,
+	srcAuth util.GitAuthOptions,
 	tmpDir string,
 	repoMap *util.RepoMap,
 ) error {
@@ -110,13 +201,13 @@ func (g *giteaProvider) updateRepoContent(
 	case v1alpha1.SourceTypeLocal, v1alpha1.SourceTypeEmbedded:
 		return reconcileLocalRepoContent(ctx, repo, repoInfo, creds, g.Scheme, g.config, tmpDir, repoMap)
 	case v1alpha1.SourceTypeRemote:
-		return reconcileRemoteRepoContent(ctx, repo, repoInfo, creds, tmpDir, repoMap)
+		return reconcileRemoteRepoContent(ctx, repo, repoInfo, creds, srcAuth, tmpDir, repoMap)
 	default:
 		return nil
 	}
 }
 
-func writeRepoContents(repo *v1alpha1.GitRepository, dstPath string, config v1alpha1.BuildCustomizationSpec, scheme *runtime.Scheme) error {
+func writeRepoContents(ctx context.Context, repo *v1alpha1.GitRepository, dstPath string, config v1alpha1.BuildCustomizationSpec, scheme *runtime.Scheme) error {
 	if repo.Spec.Source.EmbeddedAppName != "" {
 		resources, err := localbuild.GetEmbeddedRawInstallResources(
 			repo.Spec.Source.EmbeddedAppName, config,
@@ -135,11 +226,7 @@ func writeRepoContents(repo *v1alpha1.GitRepository, dstPath string, config v1al
 		return nil
 	}
 
-	err := util.CopyDirectory(repo.Spec.Source.Path, dstPath)
-	if err != nil {
-		return fmt.Errorf("copying files: %w", err)
-	}
-	return nil
+	return copyLocalSource(ctx, repo.Spec.Source.Path, dstPath)
 }
 
 func getBasicAuth(creds gitProviderCredentials) (githttp.BasicAuth, error) {
@@ -157,6 +244,9 @@ func NewGiteaClient(url string, options ...gitea.ClientOption) (GiteaClient, err
 	return gitea.NewClient(url, options...)
 }
 
-func getInternalGiteaRepositoryURL(namespace, name, baseUrl string) string {
-	return fmt.Sprintf("%s/%s/%s-%s.git", baseUrl, v1alpha1.GiteaAdminUserName, namespace, name)
+func getInternalGiteaRepositoryURL(repoName, organization, baseUrl string) string {
+	if organization == "" {
+		organization = v1alpha1.GiteaAdminUserName
+	}
+	return fmt.Sprintf("%s/%s/%s.git", baseUrl, organization, repoName)
 }
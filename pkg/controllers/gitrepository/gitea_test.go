@@ -0,0 +1,205 @@
+package gitrepository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type fakeGiteaClient struct {
+	GiteaClient
+	mock.Mock
+}
+
+func (f *fakeGiteaClient) CreateRepo(opt gitea.CreateRepoOption) (*gitea.Repository, *gitea.Response, error) {
+	args := f.Called(opt)
+	return args.Get(0).(*gitea.Repository), nil, args.Error(1)
+}
+
+func (f *fakeGiteaClient) CreateOrgRepo(org string, opt gitea.CreateRepoOption) (*gitea.Repository, *gitea.Response, error) {
+	args := f.Called(org, opt)
+	return args.Get(0).(*gitea.Repository), nil, args.Error(1)
+}
+
+func (f *fakeGiteaClient) GetOrg(orgname string) (*gitea.Organization, *gitea.Response, error) {
+	args := f.Called(orgname)
+	return nil, args.Get(0).(*gitea.Response), args.Error(1)
+}
+
+func (f *fakeGiteaClient) CreateOrg(opt gitea.CreateOrgOption) (*gitea.Organization, *gitea.Response, error) {
+	args := f.Called(opt)
+	return nil, nil, args.Error(0)
+}
+
+func (f *fakeGiteaClient) GetRepo(owner, reponame string) (*gitea.Repository, *gitea.Response, error) {
+	args := f.Called(owner, reponame)
+	return args.Get(0).(*gitea.Repository), nil, args.Error(1)
+}
+
+func (f *fakeGiteaClient) SetRepoTopics(user, repo string, list []string) (*gitea.Response, error) {
+	args := f.Called(user, repo, list)
+	return nil, args.Error(0)
+}
+
+func (f *fakeGiteaClient) ListRepoHooks(user, repo string, opt gitea.ListHooksOptions) ([]*gitea.Hook, *gitea.Response, error) {
+	args := f.Called(user, repo, opt)
+	return args.Get(0).([]*gitea.Hook), nil, args.Error(1)
+}
+
+func (f *fakeGiteaClient) CreateRepoHook(user, repo string, opt gitea.CreateHookOption) (*gitea.Hook, *gitea.Response, error) {
+	args := f.Called(user, repo, opt)
+	return nil, nil, args.Error(0)
+}
+
+// webhookSecretClient returns the argocd secret with a webhook secret.
+type webhookSecretClient struct {
+	client.Client
+}
+
+func (f *webhookSecretClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	obj.(*v1.Secret).Data = map[string][]byte{"webhook.gogs.secret": []byte("shared")}
+	return nil
+}
+
+func TestGiteaCreateRepository(t *testing.T) {
+	ctx := context.Background()
+	notFound := &gitea.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+
+	t.Run("authenticated user", func(t *testing.T) {
+		c := new(fakeGiteaClient)
+		c.On("CreateRepo", gitea.CreateRepoOption{
+			Name:          "test-test",
+			Description:   "created by Git Repository controller for test in test namespace",
+			DefaultBranch: DefaultBranchName,
+			AutoInit:      true,
+		}).Return(&gitea.Repository{Name: "test-test", FullName: "giteaAdmin/test-test"}, nil)
+
+		p := giteaProvider{Client: &fakeClient{}, giteaClient: c, username: "giteaAdmin"}
+		info, err := p.createRepository(ctx, &v1alpha1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec: v1alpha1.GitRepositorySpec{
+				Provider: v1alpha1.Provider{OrganizationName: "giteaAdmin"},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "giteaAdmin/test-test", info.fullName)
+		c.AssertExpectations(t)
+	})
+
+	t.Run("organization", func(t *testing.T) {
+		c := new(fakeGiteaClient)
+		c.On("GetOrg", "platform").Return(notFound, assert.AnError)
+		c.On("CreateOrg", gitea.CreateOrgOption{Name: "platform", Visibility: gitea.VisibleTypePublic}).Return(nil)
+		c.On("CreateOrgRepo", "platform", gitea.CreateRepoOption{
+			Name:          "payments",
+			Description:   "payments service",
+			Private:       true,
+			DefaultBranch: "trunk",
+			AutoInit:      true,
+		}).Return(&gitea.Repository{Name: "payments", FullName: "platform/payments", Owner: &gitea.User{UserName: "platform"}}, nil)
+		c.On("SetRepoTopics", "platform", "payments", []string{"backend", "go"}).Return(nil)
+
+		p := giteaProvider{Client: &fakeClient{}, giteaClient: c, username: "giteaAdmin"}
+		info, err := p.createRepository(ctx, &v1alpha1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec: v1alpha1.GitRepositorySpec{
+				Provider: v1alpha1.Provider{OrganizationName: "platform"},
+				Repository: v1alpha1.RepositorySettings{
+					Name:          "payments",
+					Private:       true,
+					Description:   "payments service",
+					Topics:        []string{"backend", "go"},
+					DefaultBranch: "trunk",
+				},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "platform/payments", info.fullName)
+		c.AssertExpectations(t)
+	})
+}
+
+func TestGiteaEnsureWebhook(t *testing.T) {
+	ctx := context.Background()
+	opt := gitea.CreateHookOption{
+		Type: gitea.HookTypeGogs,
+		Config: map[string]string{
+			"url":          "https://argocd-server.argocd.svc.cluster.local/api/webhook",
+			"content_type": "json",
+			"secret":       "shared",
+		},
+		Events: []string{"push"},
+		Active: true,
+	}
+
+	t.Run("create", func(t *testing.T) {
+		c := new(fakeGiteaClient)
+		c.On("ListRepoHooks", "giteaAdmin", "test", gitea.ListHooksOptions{}).Return([]*gitea.Hook{
+			{Config: map[string]string{"url": "https://example.com"}},
+		}, nil)
+		c.On("CreateRepoHook", "giteaAdmin", "test", opt).Return(nil)
+
+		p := giteaProvider{Client: &webhookSecretClient{}, giteaClient: c}
+		assert.NoError(t, p.ensureWebhook(ctx, "giteaAdmin", "test"))
+		c.AssertExpectations(t)
+	})
+
+	t.Run("exists", func(t *testing.T) {
+		c := new(fakeGiteaClient)
+		c.On("ListRepoHooks", "giteaAdmin", "test", gitea.ListHooksOptions{}).Return([]*gitea.Hook{
+			{Config: map[string]string{"url": "https://argocd-server.argocd.svc.cluster.local/api/webhook"}},
+		}, nil)
+
+		p := giteaProvider{Client: &webhookSecretClient{}, giteaClient: c}
+		assert.NoError(t, p.ensureWebhook(ctx, "giteaAdmin", "test"))
+		c.AssertExpectations(t)
+	})
+
+	t.Run("path routing", func(t *testing.T) {
+		c := new(fakeGiteaClient)
+		c.On("ListRepoHooks", "giteaAdmin", "test", gitea.ListHooksOptions{}).Return([]*gitea.Hook{}, nil)
+		c.On("CreateRepoHook", "giteaAdmin", "test", mock.MatchedBy(func(o gitea.CreateHookOption) bool {
+			return o.Config["url"] == "http://argocd-server.argocd.svc.cluster.local/api/webhook"
+		})).Return(nil)
+
+		p := giteaProvider{
+			Client:      &webhookSecretClient{},
+			giteaClient: c,
+			config:      v1alpha1.BuildCustomizationSpec{UsePathRouting: true},
+		}
+		assert.NoError(t, p.ensureWebhook(ctx, "giteaAdmin", "test"))
+		c.AssertExpectations(t)
+	})
+}
+
+func TestGiteaGetRepositoryWebhook(t *testing.T) {
+	ctx := context.Background()
+	c := new(fakeGiteaClient)
+	c.On("GetRepo", "", "test-test").Return(&gitea.Repository{
+		Name:     "test-test",
+		FullName: "giteaAdmin/test-test",
+		Owner:    &gitea.User{UserName: "giteaAdmin"},
+	}, nil)
+	// existing repositories without the webhook get it
+	c.On("ListRepoHooks", "giteaAdmin", "test-test", gitea.ListHooksOptions{}).Return([]*gitea.Hook{}, nil)
+	c.On("CreateRepoHook", "giteaAdmin", "test-test", mock.Anything).Return(nil)
+
+	p := giteaProvider{Client: &webhookSecretClient{}, giteaClient: c}
+	info, err := p.getRepository(ctx, &v1alpha1.GitRepository{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "giteaAdmin/test-test", info.fullName)
+	c.AssertExpectations(t)
+}
+
+func TestGetInternalGiteaRepositoryURL(t *testing.T) {
+	assert.Equal(t, "http://cnoe.io/platform/payments.git", getInternalGiteaRepositoryURL("payments", "platform", "http://cnoe.io"))
+	assert.Equal(t, "http://cnoe.io/giteaAdmin/test-test.git", getInternalGiteaRepositoryURL("test-test", "", "http://cnoe.io"))
+}
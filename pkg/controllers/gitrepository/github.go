@@ -107,6 +107,7 @@ func (g *gitHubProvider) updateRepoContent(
 	repo *v1alpha1.GitRepository,
 	repoInfo repoInfo,
 	creds gitProviderCredentials,
+	srcAuth util.GitAuthOptions,
 	tmpDir string,
 	repoMap *util.RepoMap,
 ) error {
@@ -0,0 +1,61 @@
+package gitrepository
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/go-git/go-git/v5"
+)
+
+// matches image fields whose value is the quoted name with an optional tag and digest. e.g. `- image: "my-app:latest"`
+const imageFieldPattern = `(?m)^([ \t]*(?:-[ \t]+)?image:[ \t]*["']?)%s(?::[\w][\w.-]*)?(?:@sha256:[a-f0-9]+)?(["']?[ \t]*)$`
+
+// replaceImages replaces image references in yaml files under dir.
+func replaceImages(dir string, images []v1alpha1.ImageReplacement) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	patterns := make([]*regexp.Regexp, len(images))
+	for i := range images {
+		patterns[i] = regexp.MustCompile(fmt.Sprintf(imageFieldPattern, regexp.QuoteMeta(images[i].Name)))
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == git.GitDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !util.IsYamlFile(path) {
+			return nil
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		out := b
+		for i := range patterns {
+			out = patterns[i].ReplaceAll(out, []byte("${1}"+images[i].Image+"${2}"))
+		}
+		if bytes.Equal(out, b) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, out, info.Mode())
+	})
+}
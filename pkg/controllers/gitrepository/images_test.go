@@ -0,0 +1,61 @@
+package gitrepository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceImages(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: my-app
+      - name: sidecar
+        image: "my-app:latest"
+      - image: my-app-worker:v1
+        name: worker
+      initContainers:
+        - image: 'my-app@sha256:0123abcd'
+          name: init
+`
+	expected := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: gitea.cnoe.localtest.me:8443/giteaadmin/my-app@sha256:ffff
+      - name: sidecar
+        image: "gitea.cnoe.localtest.me:8443/giteaadmin/my-app@sha256:ffff"
+      - image: my-app-worker:v1
+        name: worker
+      initContainers:
+        - image: 'gitea.cnoe.localtest.me:8443/giteaadmin/my-app@sha256:ffff'
+          name: init
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "deployment.yaml"), []byte(manifest), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("image: my-app\n"), 0644))
+
+	err := replaceImages(dir, []v1alpha1.ImageReplacement{
+		{Name: "my-app", Image: "gitea.cnoe.localtest.me:8443/giteaadmin/my-app@sha256:ffff"},
+	})
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(filepath.Join(dir, "deployment.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(b))
+	b, err = os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "image: my-app\n", string(b))
+}
@@ -0,0 +1,486 @@
+package gitrepository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	wipCommitMessage = "WIP: uncommitted changes"
+
+	// git config section of the target repository clone that records the last split.
+	splitStateSection    = "idpbuilder"
+	splitStateSubsection = "split"
+)
+
+// mirrorLocalRepoContent replaces the history of the target repository with commits from the source repository that touched the source path.
+// It is similar to `git subtree split` following first parents only. Uncommitted changes and image replacements are added as a commit on top.
+// It returns false without making changes if the source path has no git history.
+func mirrorLocalRepoContent(ctx context.Context, repo *v1alpha1.GitRepository, tgtRepository *git.Repository, tgtCloneDir string, creds gitProviderCredentials) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	srcRepository, err := git.PlainOpenWithOptions(repo.Spec.Source.Path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			logger.V(1).Info("source is not in a git repository. pushing snapshot", "path", repo.Spec.Source.Path)
+			return false, nil
+		}
+		return false, fmt.Errorf("opening source repository at %s: %w", repo.Spec.Source.Path, err)
+	}
+
+	prefix, err := subtreePrefix(srcRepository, repo.Spec.Source.Path)
+	if err != nil {
+		return false, err
+	}
+
+	ignore, err := util.IgnorePatterns(repo.Spec.Source.Path)
+	if err != nil {
+		return false, fmt.Errorf("reading ignore files: %w", err)
+	}
+
+	prev, err := readSplitState(tgtRepository, splitKey(prefix, ignore))
+	if err != nil {
+		return false, err
+	}
+	state, err := splitSubtree(srcRepository, tgtRepository, prefix, ignore, prev)
+	if err != nil {
+		return false, fmt.Errorf("splitting history of %s: %w", repo.Spec.Source.Path, err)
+	}
+	err = writeSplitState(tgtRepository, state)
+	if err != nil {
+		return false, err
+	}
+	splitHead := state.split
+	if splitHead.IsZero() {
+		logger.V(1).Info("source path has no commits. pushing snapshot", "path", repo.Spec.Source.Path)
+		return false, nil
+	}
+
+	head, err := tgtRepository.Head()
+	if err != nil {
+		return false, fmt.Errorf("getting head: %w", err)
+	}
+	branch := head.Name()
+
+	err = tgtRepository.Storer.SetReference(plumbing.NewHashReference(branch, splitHead))
+	if err != nil {
+		return false, fmt.Errorf("updating %s: %w", branch, err)
+	}
+
+	wt, err := tgtRepository.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("getting git worktree: %w", err)
+	}
+	err = wt.Reset(&git.ResetOptions{Commit: splitHead, Mode: git.HardReset})
+	if err != nil {
+		return false, fmt.Errorf("resetting to %s: %w", splitHead, err)
+	}
+
+	err = clearWorktree(tgtCloneDir)
+	if err != nil {
+		return false, fmt.Errorf("clearing worktree %s: %w", tgtCloneDir, err)
+	}
+	err = copyLocalSource(ctx, repo.Spec.Source.Path, tgtCloneDir)
+	if err != nil {
+		return false, err
+	}
+	// mirrored commits keep the original manifests. images are pinned in the commit on top.
+	err = replaceImages(tgtCloneDir, repo.Spec.Source.Images)
+	if err != nil {
+		return false, fmt.Errorf("replacing images: %w", err)
+	}
+
+	hash, err := commitUncommitted(tgtRepository, splitHead)
+	if err != nil {
+		return false, err
+	}
+
+	remoteRef, err := tgtRepository.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch.Short()), true)
+	if err == nil && remoteRef.Hash() == hash {
+		repo.Status.LatestCommit.Hash = hash.String()
+		return true, nil
+	}
+
+	logger.V(1).Info("pushing mirrored history", "path", repo.Spec.Source.Path, "commit", hash.String())
+	auth, err := getBasicAuth(creds)
+	if err != nil {
+		return false, fmt.Errorf("getting basic auth: %w", err)
+	}
+	err = tgtRepository.PushContext(ctx, &git.PushOptions{
+		Auth:            &auth,
+		InsecureSkipTLS: true,
+		// history is rewritten when the source repository is rebased or when switching from snapshots.
+		RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", branch, branch))},
+		Force:    true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return false, fmt.Errorf("pushing to git: %w", err)
+	}
+
+	repo.Status.LatestCommit.Hash = hash.String()
+	return true, nil
+}
+
+// subtreePrefix returns the path relative to the root of the worktree.
+func subtreePrefix(repo *git.Repository, path string) (string, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("getting source worktree: %w", err)
+	}
+
+	root, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", wt.Filesystem.Root(), err)
+	}
+	p, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", path, err)
+	}
+
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return "", fmt.Errorf("getting relative path of %s: %w", p, err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// splitState maps a source commit to the split commit created for it.
+type splitState struct {
+	// key identifies everything besides the source history the split depends on.
+	key    string
+	source plumbing.Hash
+	split  plumbing.Hash
+}
+
+// splitKey identifies the prefix and ignore patterns a split was made with.
+func splitKey(prefix string, ignore []gitignore.Pattern) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", prefix)
+	// patterns do not expose the lines they were parsed from. their printed fields identify them.
+	for i := range ignore {
+		fmt.Fprintf(h, "%v\n", ignore[i])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readSplitState returns the state recorded by the last split in the target repository clone.
+// The zero state is returned if the split was made with a different key or its commits are gone.
+func readSplitState(repo *git.Repository, key string) (splitState, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return splitState{}, fmt.Errorf("reading git config: %w", err)
+	}
+	sub := cfg.Raw.Section(splitStateSection).Subsection(splitStateSubsection)
+	s := splitState{
+		key:    sub.Option("key"),
+		source: plumbing.NewHash(sub.Option("source")),
+		split:  plumbing.NewHash(sub.Option("split")),
+	}
+	if s.key != key || s.source.IsZero() {
+		return splitState{key: key}, nil
+	}
+	if !s.split.IsZero() {
+		if _, cErr := repo.CommitObject(s.split); cErr != nil {
+			return splitState{key: key}, nil
+		}
+	}
+	return s, nil
+}
+
+// writeSplitState records the state in the git config of the target repository clone.
+func writeSplitState(repo *git.Repository, s splitState) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("reading git config: %w", err)
+	}
+	cfg.Raw.Section(splitStateSection).Subsection(splitStateSubsection).
+		SetOption("key", s.key).
+		SetOption("source", s.source.String()).
+		SetOption("split", s.split.String())
+	err = repo.SetConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("writing git config: %w", err)
+	}
+	return nil
+}
+
+// splitSubtree creates commits in tgt for each first parent commit in src that changed the tree at prefix.
+// Created commits keep the author, committer, and message of the original, so the result is the same for the same source history.
+// Paths matched by ignore are left out of every commit. Commits up to prev.source were split before and are not processed again.
+// It returns the state for the source head. The split commit is zero if prefix never existed.
+func splitSubtree(src, tgt *git.Repository, prefix string, ignore []gitignore.Pattern, prev splitState) (splitState, error) {
+	head, err := src.Head()
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return splitState{key: prev.key}, nil
+		}
+		return splitState{}, fmt.Errorf("getting source head: %w", err)
+	}
+
+	c, err := src.CommitObject(head.Hash())
+	if err != nil {
+		return splitState{}, fmt.Errorf("getting commit %s: %w", head.Hash(), err)
+	}
+
+	var commits []*object.Commit
+	found := false
+	for {
+		if !prev.source.IsZero() && c.Hash == prev.source {
+			found = true
+			break
+		}
+		commits = append(commits, c)
+		if c.NumParents() == 0 {
+			break
+		}
+		c, err = c.Parent(0)
+		if err != nil {
+			// shallow clones do not have the complete history
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				break
+			}
+			return splitState{}, fmt.Errorf("getting parent commit: %w", err)
+		}
+	}
+
+	parent, parentTree := plumbing.ZeroHash, plumbing.ZeroHash
+	// continue from the last split unless the source history was rewritten since.
+	if found && !prev.split.IsZero() {
+		p, pErr := tgt.CommitObject(prev.split)
+		if pErr != nil {
+			return splitState{}, fmt.Errorf("getting commit %s: %w", prev.split, pErr)
+		}
+		parent, parentTree = p.Hash, p.TreeHash
+	}
+
+	filter := &treeFilter{
+		src:      src.Storer,
+		tgt:      tgt.Storer,
+		matcher:  gitignore.NewMatcher(ignore),
+		filtered: make(map[string]plumbing.Hash),
+	}
+	var prefixPath []string
+	if prefix != "." && prefix != "" {
+		prefixPath = strings.Split(prefix, "/")
+	}
+
+	for i := len(commits) - 1; i >= 0; i-- {
+		tree, tErr := subtree(commits[i], prefix)
+		if tErr != nil {
+			return splitState{}, tErr
+		}
+		if tree == nil {
+			continue
+		}
+
+		treeHash, cErr := filter.copyTree(tree, prefixPath)
+		if cErr != nil {
+			return splitState{}, fmt.Errorf("copying tree %s: %w", tree.Hash, cErr)
+		}
+		if treeHash.IsZero() || treeHash == parentTree {
+			continue
+		}
+
+		newCommit := &object.Commit{
+			Author:    commits[i].Author,
+			Committer: commits[i].Committer,
+			Message:   commits[i].Message,
+			TreeHash:  treeHash,
+		}
+		if !parent.IsZero() {
+			newCommit.ParentHashes = []plumbing.Hash{parent}
+		}
+
+		h, sErr := storeCommit(tgt.Storer, newCommit)
+		if sErr != nil {
+			return splitState{}, sErr
+		}
+		parent, parentTree = h, treeHash
+	}
+	return splitState{key: prev.key, source: head.Hash(), split: parent}, nil
+}
+
+func subtree(c *object.Commit, prefix string) (*object.Tree, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("getting tree of %s: %w", c.Hash, err)
+	}
+	if prefix == "." || prefix == "" {
+		return tree, nil
+	}
+
+	t, err := tree.Tree(prefix)
+	if err != nil {
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting tree %s of %s: %w", prefix, c.Hash, err)
+	}
+	return t, nil
+}
+
+// treeFilter copies trees to the target store leaving out paths matched by ignore patterns,
+// so that ignored files do not reach the git server through history either.
+type treeFilter struct {
+	src, tgt storer.EncodedObjectStorer
+	matcher  gitignore.Matcher
+	// filtered maps path and hash of source trees to the trees stored in the target.
+	filtered map[string]plumbing.Hash
+}
+
+// copyTree copies the tree at path and everything it references, except ignored entries. Submodules are not copied.
+// It returns the hash of the stored tree, or zero hash if nothing is left after filtering.
+func (f *treeFilter) copyTree(tree *object.Tree, path []string) (plumbing.Hash, error) {
+	key := fmt.Sprintf("%s:%s", strings.Join(path, "/"), tree.Hash)
+	if h, ok := f.filtered[key]; ok {
+		return h, nil
+	}
+
+	changed := false
+	entries := make([]object.TreeEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		entryPath := append(append([]string{}, path...), e.Name)
+		if f.matcher.Match(entryPath, e.Mode == filemode.Dir) {
+			changed = true
+			continue
+		}
+
+		switch e.Mode {
+		case filemode.Submodule:
+		case filemode.Dir:
+			t, err := object.GetTree(f.src, e.Hash)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			h, err := f.copyTree(t, entryPath)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			if h.IsZero() {
+				changed = true
+				continue
+			}
+			if h != e.Hash {
+				changed = true
+				e.Hash = h
+			}
+		default:
+			err := copyObject(f.src, f.tgt, plumbing.BlobObject, e.Hash)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	h := tree.Hash
+	switch {
+	case len(entries) == 0:
+		// git does not track empty directories
+		h = plumbing.ZeroHash
+	case changed:
+		o := f.tgt.NewEncodedObject()
+		err := (&object.Tree{Entries: entries}).Encode(o)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("encoding tree: %w", err)
+		}
+		h, err = f.tgt.SetEncodedObject(o)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("storing tree: %w", err)
+		}
+	default:
+		// unchanged trees keep their hash so that history is the same as without filtering.
+		err := copyObject(f.src, f.tgt, plumbing.TreeObject, tree.Hash)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	f.filtered[key] = h
+	return h, nil
+}
+
+func copyObject(src, tgt storer.EncodedObjectStorer, t plumbing.ObjectType, h plumbing.Hash) error {
+	if tgt.HasEncodedObject(h) == nil {
+		return nil
+	}
+	o, err := src.EncodedObject(t, h)
+	if err != nil {
+		return fmt.Errorf("getting object %s: %w", h, err)
+	}
+	_, err = tgt.SetEncodedObject(o)
+	if err != nil {
+		return fmt.Errorf("storing object %s: %w", h, err)
+	}
+	return nil
+}
+
+func storeCommit(s storer.EncodedObjectStorer, c *object.Commit) (plumbing.Hash, error) {
+	o := s.NewEncodedObject()
+	err := c.Encode(o)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("encoding commit: %w", err)
+	}
+	h, err := s.SetEncodedObject(o)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("storing commit: %w", err)
+	}
+	return h, nil
+}
+
+// commitUncommitted commits changes in the worktree on top of parent. The commit time is taken from parent
+// so that the same changes result in the same commit and are not pushed again on every reconcile.
+func commitUncommitted(repo *git.Repository, parent plumbing.Hash) (plumbing.Hash, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("getting git worktree: %w", err)
+	}
+
+	// the worktree holds the copied source only. files ignored by .idpbuilderignore were not copied.
+	err = wt.AddGlob("*")
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("adding git files: %w", err)
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("getting git status: %w", err)
+	}
+	if status.IsClean() {
+		return parent, nil
+	}
+
+	p, err := repo.CommitObject(parent)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("getting commit %s: %w", parent, err)
+	}
+	sig := &object.Signature{
+		Name:  gitCommitAuthorName,
+		Email: gitCommitAuthorEmail,
+		When:  p.Committer.When,
+	}
+	h, err := wt.Commit(wipCommitMessage, &git.CommitOptions{
+		All:       true,
+		Author:    sig,
+		Committer: sig,
+	})
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("committing: %w", err)
+	}
+	return h, nil
+}
@@ -0,0 +1,231 @@
+package gitrepository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func commitSourceFile(t *testing.T, repo *git.Repository, root, path, content, msg string, when time.Time) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content), 0644))
+	wt, err := repo.Worktree()
+	assert.NoError(t, err)
+	_, err = wt.Add(path)
+	assert.NoError(t, err)
+	_, err = wt.Commit(msg, &git.CommitOptions{
+		Author: &object.Signature{Name: "developer", Email: "dev@cnoe.io", When: when},
+	})
+	assert.NoError(t, err)
+}
+
+func targetLog(t *testing.T, remote string) []*object.Commit {
+	t.Helper()
+	repo, err := git.PlainClone(t.TempDir(), false, &git.CloneOptions{URL: remote})
+	assert.NoError(t, err)
+	iter, err := repo.Log(&git.LogOptions{})
+	assert.NoError(t, err)
+	var out []*object.Commit
+	assert.NoError(t, iter.ForEach(func(c *object.Commit) error {
+		out = append(out, c)
+		return nil
+	}))
+	return out
+}
+
+func TestGitRepositoryMirrorHistory(t *testing.T) {
+	ctx := context.Background()
+	tgtDir, _, err := setUpLocalRepo()
+	defer os.RemoveAll(tgtDir)
+	assert.NoError(t, err)
+
+	srcDir := t.TempDir()
+	src, err := git.PlainInit(srcDir, false)
+	assert.NoError(t, err)
+	now := time.Now().Truncate(time.Second)
+	commitSourceFile(t, src, srcDir, "pkg/app.yaml", "v1", "add app", now)
+	commitSourceFile(t, src, srcDir, "other/file", "x", "unrelated change", now.Add(time.Second))
+	commitSourceFile(t, src, srcDir, "pkg/app.yaml", "v2", "update app", now.Add(2*time.Second))
+
+	resource := v1alpha1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: v1alpha1.GitRepositorySpec{
+			Source: v1alpha1.GitRepositorySource{
+				Path:          filepath.Join(srcDir, "pkg"),
+				Type:          v1alpha1.SourceTypeLocal,
+				MirrorHistory: true,
+			},
+		},
+	}
+	p := giteaProvider{Client: &fakeClient{}, giteaClient: mockGitea{}}
+	cloneDir := t.TempDir()
+	repoMap := util.NewRepoLock()
+
+	err = p.updateRepoContent(ctx, &resource, repoInfo{cloneUrl: tgtDir}, gitProviderCredentials{}, util.GitAuthOptions{}, cloneDir, repoMap)
+	assert.NoError(t, err)
+
+	commits := targetLog(t, tgtDir)
+	assert.Len(t, commits, 2)
+	assert.Equal(t, "update app", commits[0].Message)
+	assert.Equal(t, "add app", commits[1].Message)
+	assert.Equal(t, "developer", commits[0].Author.Name)
+	assert.Equal(t, commits[0].Hash.String(), resource.Status.LatestCommit.Hash)
+	_, err = commits[0].File("app.yaml")
+	assert.NoError(t, err)
+
+	// uncommitted changes go in as a commit on top
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "pkg", "app.yaml"), []byte("v3"), 0644))
+	err = p.updateRepoContent(ctx, &resource, repoInfo{cloneUrl: tgtDir}, gitProviderCredentials{}, util.GitAuthOptions{}, cloneDir, repoMap)
+	assert.NoError(t, err)
+
+	commits = targetLog(t, tgtDir)
+	assert.Len(t, commits, 3)
+	assert.Equal(t, wipCommitMessage, commits[0].Message)
+	wipHash := commits[0].Hash
+
+	// same changes result in the same commit
+	err = p.updateRepoContent(ctx, &resource, repoInfo{cloneUrl: tgtDir}, gitProviderCredentials{}, util.GitAuthOptions{}, cloneDir, repoMap)
+	assert.NoError(t, err)
+	assert.Equal(t, wipHash.String(), resource.Status.LatestCommit.Hash)
+
+	// committing the change replaces the work in progress commit
+	commitSourceFile(t, src, srcDir, "pkg/app.yaml", "v3", "release v3", now.Add(3*time.Second))
+	err = p.updateRepoContent(ctx, &resource, repoInfo{cloneUrl: tgtDir}, gitProviderCredentials{}, util.GitAuthOptions{}, cloneDir, repoMap)
+	assert.NoError(t, err)
+
+	commits = targetLog(t, tgtDir)
+	assert.Len(t, commits, 3)
+	assert.Equal(t, "release v3", commits[0].Message)
+}
+
+func TestGitRepositoryMirrorHistoryImages(t *testing.T) {
+	ctx := context.Background()
+	tgtDir, _, err := setUpLocalRepo()
+	defer os.RemoveAll(tgtDir)
+	assert.NoError(t, err)
+
+	srcDir := t.TempDir()
+	src, err := git.PlainInit(srcDir, false)
+	assert.NoError(t, err)
+	commitSourceFile(t, src, srcDir, "pkg/app.yaml", "image: my-app:latest\n", "add app", time.Now().Truncate(time.Second))
+
+	resource := v1alpha1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: v1alpha1.GitRepositorySpec{
+			Source: v1alpha1.GitRepositorySource{
+				Path:          filepath.Join(srcDir, "pkg"),
+				Type:          v1alpha1.SourceTypeLocal,
+				MirrorHistory: true,
+				Images:        []v1alpha1.ImageReplacement{{Name: "my-app", Image: "gitea.cnoe.localtest.me:8443/giteaadmin/my-app@sha256:abc"}},
+			},
+		},
+	}
+	p := giteaProvider{Client: &fakeClient{}, giteaClient: mockGitea{}}
+	err = p.updateRepoContent(ctx, &resource, repoInfo{cloneUrl: tgtDir}, gitProviderCredentials{}, util.GitAuthOptions{}, t.TempDir(), util.NewRepoLock())
+	assert.NoError(t, err)
+
+	commits := targetLog(t, tgtDir)
+	assert.Len(t, commits, 2)
+	// the mirrored commit is unchanged. the pinned image is in the commit on top.
+	assert.Equal(t, wipCommitMessage, commits[0].Message)
+	assert.Equal(t, "add app", commits[1].Message)
+	f, err := commits[0].File("app.yaml")
+	assert.NoError(t, err)
+	content, err := f.Contents()
+	assert.NoError(t, err)
+	assert.Equal(t, "image: gitea.cnoe.localtest.me:8443/giteaadmin/my-app@sha256:abc\n", content)
+}
+
+func TestSplitSubtreeIncremental(t *testing.T) {
+	srcDir := t.TempDir()
+	src, err := git.PlainInit(srcDir, false)
+	assert.NoError(t, err)
+	now := time.Now().Truncate(time.Second)
+	commitSourceFile(t, src, srcDir, "pkg/app.yaml", "v1", "add app", now)
+	commitSourceFile(t, src, srcDir, "other/file", "x", "unrelated change", now.Add(time.Second))
+
+	tgt, err := git.PlainInit(t.TempDir(), false)
+	assert.NoError(t, err)
+
+	first, err := splitSubtree(src, tgt, "pkg", nil, splitState{key: "pkg"})
+	assert.NoError(t, err)
+	assert.False(t, first.split.IsZero())
+	assert.NoError(t, writeSplitState(tgt, first))
+
+	prev, err := readSplitState(tgt, "pkg")
+	assert.NoError(t, err)
+	assert.Equal(t, first, prev)
+	// state recorded for another prefix is not used
+	other, err := readSplitState(tgt, "other")
+	assert.NoError(t, err)
+	assert.Equal(t, splitState{key: "other"}, other)
+
+	commitSourceFile(t, src, srcDir, "pkg/app.yaml", "v2", "update app", now.Add(2*time.Second))
+
+	// only the new commit is split and the result matches a split of the whole history
+	incremental, err := splitSubtree(src, tgt, "pkg", nil, prev)
+	assert.NoError(t, err)
+	full, err := splitSubtree(src, tgt, "pkg", nil, splitState{key: "pkg"})
+	assert.NoError(t, err)
+	assert.Equal(t, full, incremental)
+
+	c, err := tgt.CommitObject(incremental.split)
+	assert.NoError(t, err)
+	assert.Equal(t, "update app", c.Message)
+	assert.Equal(t, []plumbing.Hash{first.split}, c.ParentHashes)
+}
+
+func TestGitRepositoryMirrorHistoryIgnored(t *testing.T) {
+	// avoid picking up global excludes from the environment running the test.
+	t.Setenv("HOME", t.TempDir())
+	ctx := context.Background()
+	tgtDir, _, err := setUpLocalRepo()
+	defer os.RemoveAll(tgtDir)
+	assert.NoError(t, err)
+
+	srcDir := t.TempDir()
+	src, err := git.PlainInit(srcDir, false)
+	assert.NoError(t, err)
+	now := time.Now().Truncate(time.Second)
+	commitSourceFile(t, src, srcDir, "pkg/app.yaml", "v1", "add app", now)
+	commitSourceFile(t, src, srcDir, "pkg/.env", "TOKEN=secret", "add env", now.Add(time.Second))
+	commitSourceFile(t, src, srcDir, "pkg/secrets/key", "secret", "add key", now.Add(2*time.Second))
+	commitSourceFile(t, src, srcDir, "pkg/.idpbuilderignore", ".env\nsecrets/\n", "ignore secrets", now.Add(3*time.Second))
+
+	resource := v1alpha1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: v1alpha1.GitRepositorySpec{
+			Source: v1alpha1.GitRepositorySource{
+				Path:          filepath.Join(srcDir, "pkg"),
+				Type:          v1alpha1.SourceTypeLocal,
+				MirrorHistory: true,
+			},
+		},
+	}
+	p := giteaProvider{Client: &fakeClient{}, giteaClient: mockGitea{}}
+	err = p.updateRepoContent(ctx, &resource, repoInfo{cloneUrl: tgtDir}, gitProviderCredentials{}, util.GitAuthOptions{}, t.TempDir(), util.NewRepoLock())
+	assert.NoError(t, err)
+
+	// commits that only touched ignored files are dropped and no commit contains them
+	commits := targetLog(t, tgtDir)
+	assert.Len(t, commits, 2)
+	assert.Equal(t, "ignore secrets", commits[0].Message)
+	assert.Equal(t, "add app", commits[1].Message)
+	for _, c := range commits {
+		_, fErr := c.File(".env")
+		assert.ErrorIs(t, fErr, object.ErrFileNotFound, c.Message)
+		_, fErr = c.File("secrets/key")
+		assert.ErrorIs(t, fErr, object.ErrFileNotFound, c.Message)
+	}
+}
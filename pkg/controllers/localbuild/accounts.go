@@ -0,0 +1,348 @@
+package localbuild
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	argoCDConfigMapName     = "argocd-cm"
+	argoCDRBACConfigMapName = "argocd-rbac-cm"
+	argoCDSecretName        = "argocd-secret"
+	// argocd concatenates policy.*.csv keys of the rbac configmap with policy.csv.
+	argoCDAccountsPolicyKey = "policy.idpbuilder.csv"
+	argoCDDefaultUserRole   = "role:readonly"
+	giteaDefaultPermission  = gitea.AccessModeRead
+	// argoCDAdminPasswordSetAnnotation marks the initial admin secret once the configured admin password is set.
+	// The password is not set again so rotated passwords are kept.
+	argoCDAdminPasswordSetAnnotation = "cnoe.io/admin-password-set"
+)
+
+// accountPasswords are the passwords in the secret referenced by the accounts, keyed as described in AccountsSpec.
+type accountPasswords map[string][]byte
+
+// getAccountPasswords reads the secret referenced by the accounts. There are no passwords when no secret is referenced.
+func (r *LocalbuildReconciler) getAccountPasswords(ctx context.Context, spec v1alpha1.AccountsSpec) (accountPasswords, error) {
+	if spec.SecretRef == nil {
+		return accountPasswords{}, nil
+	}
+	secret := corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: spec.SecretRef.Name, Namespace: spec.SecretRef.Namespace}, &secret)
+	if err != nil {
+		return nil, fmt.Errorf("getting accounts secret %s: %w", spec.SecretRef.Name, err)
+	}
+	return secret.Data, nil
+}
+
+// required returns the password stored under key. Users must have a password.
+func (p accountPasswords) required(key string) (string, error) {
+	v, ok := p[key]
+	if !ok || len(v) == 0 {
+		return "", fmt.Errorf("%s not found in accounts secret", key)
+	}
+	return string(v), nil
+}
+
+var giteaTeamUnits = []gitea.RepoUnitType{
+	gitea.RepoUnitCode,
+	gitea.RepoUnitIssues,
+	gitea.RepoUnitPulls,
+	gitea.RepoUnitReleases,
+	gitea.RepoUnitWiki,
+	gitea.RepoUnitPackages,
+}
+
+// giteaAccountsClient is the part of the Gitea client used to manage accounts.
+type giteaAccountsClient interface {
+	AdminCreateUser(opt gitea.CreateUserOption) (*gitea.User, *gitea.Response, error)
+	AdminEditUser(user string, opt gitea.EditUserOption) (*gitea.Response, error)
+	GetUserInfo(user string) (*gitea.User, *gitea.Response, error)
+	GetOrg(orgname string) (*gitea.Organization, *gitea.Response, error)
+	CreateOrg(opt gitea.CreateOrgOption) (*gitea.Organization, *gitea.Response, error)
+	ListOrgTeams(org string, opt gitea.ListTeamsOptions) ([]*gitea.Team, *gitea.Response, error)
+	CreateTeam(org string, opt gitea.CreateTeamOption) (*gitea.Team, *gitea.Response, error)
+	AddTeamMember(id int64, user string) (*gitea.Response, error)
+}
+
+func newGiteaClient(ctx context.Context, baseUrl, username, password string) *gitea.Client {
+	c := gitea.NewClientWithHTTP(baseUrl, util.GetHttpClient())
+	c.SetBasicAuth(username, password)
+	c.SetContext(ctx)
+	return c
+}
+
+// reconcileGiteaAccounts creates missing users, organizations and teams. Existing ones are not modified.
+func reconcileGiteaAccounts(c giteaAccountsClient, spec v1alpha1.GiteaAccountsSpec, passwords accountPasswords, emailDomain string) error {
+	for i := range spec.Users {
+		u := spec.Users[i]
+		_, resp, err := c.GetUserInfo(u.Username)
+		if err == nil {
+			continue
+		}
+		if !isNotFound(resp) {
+			return fmt.Errorf("getting gitea user %s: %w", u.Username, err)
+		}
+		password, err := passwords.required(v1alpha1.GiteaUserPasswordKey(u.Username))
+		if err != nil {
+			return fmt.Errorf("creating gitea user %s: %w", u.Username, err)
+		}
+
+		email := u.Email
+		if email == "" {
+			email = fmt.Sprintf("%s@%s", u.Username, emailDomain)
+		}
+		mustChangePassword := false
+		_, _, err = c.AdminCreateUser(gitea.CreateUserOption{
+			LoginName:          u.Username,
+			Username:           u.Username,
+			Email:              email,
+			Password:           password,
+			MustChangePassword: &mustChangePassword,
+		})
+		if err != nil {
+			return fmt.Errorf("creating gitea user %s: %w", u.Username, err)
+		}
+		if u.Admin {
+			admin := true
+			_, err = c.AdminEditUser(u.Username, gitea.EditUserOption{LoginName: u.Username, Admin: &admin})
+			if err != nil {
+				return fmt.Errorf("making gitea user %s an administrator: %w", u.Username, err)
+			}
+		}
+	}
+
+	for i := range spec.Organizations {
+		if err := reconcileGiteaOrganization(c, spec.Organizations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reconcileGiteaOrganization(c giteaAccountsClient, org v1alpha1.GiteaOrganization) error {
+	_, resp, err := c.GetOrg(org.Name)
+	if err != nil {
+		if !isNotFound(resp) {
+			return fmt.Errorf("getting gitea organization %s: %w", org.Name, err)
+		}
+		_, _, err = c.CreateOrg(gitea.CreateOrgOption{Name: org.Name, Visibility: gitea.VisibleTypePublic})
+		if err != nil {
+			return fmt.Errorf("creating gitea organization %s: %w", org.Name, err)
+		}
+	}
+	if len(org.Teams) == 0 {
+		return nil
+	}
+
+	teams, _, err := c.ListOrgTeams(org.Name, gitea.ListTeamsOptions{})
+	if err != nil {
+		return fmt.Errorf("listing teams of gitea organization %s: %w", org.Name, err)
+	}
+	existing := make(map[string]*gitea.Team, len(teams))
+	for i := range teams {
+		existing[teams[i].Name] = teams[i]
+	}
+
+	for i := range org.Teams {
+		t := org.Teams[i]
+		team, ok := existing[t.Name]
+		if !ok {
+			permission := gitea.AccessMode(t.Permission)
+			if permission == "" {
+				permission = giteaDefaultPermission
+			}
+			team, _, err = c.CreateTeam(org.Name, gitea.CreateTeamOption{
+				Name:                    t.Name,
+				Permission:              permission,
+				IncludesAllRepositories: true,
+				Units:                   giteaTeamUnits,
+			})
+			if err != nil {
+				return fmt.Errorf("creating team %s in gitea organization %s: %w", t.Name, org.Name, err)
+			}
+		}
+		for _, m := range t.Members {
+			if _, err = c.AddTeamMember(team.ID, m); err != nil {
+				return fmt.Errorf("adding %s to team %s in gitea organization %s: %w", m, t.Name, org.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func isNotFound(resp *gitea.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+// reconcileArgoCDAccounts configures local users of ArgoCD through its configmaps and secret.
+// The admin password is set once, like the Gitea admin password, and changes afterwards are kept.
+func (r *LocalbuildReconciler) reconcileArgoCDAccounts(ctx context.Context, spec v1alpha1.ArgoCDAccountsSpec, passwords accountPasswords) error {
+	adminPassword := string(passwords[v1alpha1.ArgoCDAdminPasswordKey])
+	if adminPassword != "" {
+		set, err := r.isArgoCDAdminPasswordSet(ctx)
+		if err != nil {
+			return err
+		}
+		if set {
+			adminPassword = ""
+		}
+	}
+	if adminPassword == "" && len(spec.Users) == 0 {
+		return nil
+	}
+
+	if len(spec.Users) > 0 {
+		accounts := make(map[string]string, len(spec.Users))
+		policy := make([]string, 0, len(spec.Users))
+		for i := range spec.Users {
+			u := spec.Users[i]
+			accounts["accounts."+u.Username] = "login"
+			role := u.Role
+			if role == "" {
+				role = argoCDDefaultUserRole
+			}
+			policy = append(policy, fmt.Sprintf("g, %s, %s", u.Username, role))
+		}
+		sort.Strings(policy)
+
+		if err := r.patchConfigMapData(ctx, argoCDConfigMapName, accounts); err != nil {
+			return err
+		}
+		err := r.patchConfigMapData(ctx, argoCDRBACConfigMapName, map[string]string{
+			argoCDAccountsPolicyKey: strings.Join(policy, "\n") + "\n",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	secret := corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: argoCDSecretName, Namespace: globals.ArgoCDNamespace}, &secret)
+	if err != nil {
+		return fmt.Errorf("getting secret %s: %w", argoCDSecretName, err)
+	}
+	patch := client.MergeFrom(secret.DeepCopy())
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	changed := false
+	if adminPassword != "" {
+		c, sErr := setArgoCDPassword(secret.Data, "admin.", adminPassword)
+		if sErr != nil {
+			return sErr
+		}
+		changed = changed || c
+	}
+	for i := range spec.Users {
+		username := spec.Users[i].Username
+		password, pErr := passwords.required(v1alpha1.ArgoCDUserPasswordKey(username))
+		if pErr != nil {
+			return fmt.Errorf("setting password of argocd user %s: %w", username, pErr)
+		}
+		c, sErr := setArgoCDPassword(secret.Data, fmt.Sprintf("accounts.%s.", username), password)
+		if sErr != nil {
+			return sErr
+		}
+		changed = changed || c
+	}
+	if changed {
+		if err = r.Client.Patch(ctx, &secret, patch); err != nil {
+			return fmt.Errorf("updating secret %s: %w", argoCDSecretName, err)
+		}
+	}
+
+	if adminPassword != "" {
+		return r.setArgoCDInitialAdminPassword(ctx, adminPassword)
+	}
+	return nil
+}
+
+// setArgoCDPassword stores the bcrypt hash of password under <prefix>password. The hash is kept if it matches to not invalidate sessions.
+func setArgoCDPassword(data map[string][]byte, prefix, password string) (bool, error) {
+	hashKey := prefix + "password"
+	if hash, ok := data[hashKey]; ok && bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil {
+		return false, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, fmt.Errorf("hashing password: %w", err)
+	}
+	data[hashKey] = hash
+	data[prefix+"passwordMtime"] = []byte(time.Now().UTC().Format(time.RFC3339))
+	return true, nil
+}
+
+// isArgoCDAdminPasswordSet reports whether the configured admin password was set before.
+func (r *LocalbuildReconciler) isArgoCDAdminPasswordSet(ctx context.Context) (bool, error) {
+	secret := corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: argoCDInitialAdminSecretName, Namespace: globals.ArgoCDNamespace}, &secret)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("getting secret %s: %w", argoCDInitialAdminSecretName, err)
+	}
+	_, ok := secret.Annotations[argoCDAdminPasswordSetAnnotation]
+	return ok, nil
+}
+
+// setArgoCDInitialAdminPassword stores the configured password in the initial admin secret, which get secrets reads,
+// and marks it so the password is not set again.
+func (r *LocalbuildReconciler) setArgoCDInitialAdminPassword(ctx context.Context, password string) error {
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      argoCDInitialAdminSecretName,
+			Namespace: globals.ArgoCDNamespace,
+		},
+	}
+	_, err := controllerutil.CreateOrPatch(ctx, r.Client, &secret, func() error {
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[argoCDAdminPasswordSetAnnotation] = "true"
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data["password"] = []byte(password)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("storing password in secret %s: %w", argoCDInitialAdminSecretName, err)
+	}
+	return nil
+}
+
+func (r *LocalbuildReconciler) patchConfigMapData(ctx context.Context, name string, data map[string]string) error {
+	cm := corev1.ConfigMap{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: name, Namespace: globals.ArgoCDNamespace}, &cm)
+	if err != nil {
+		return fmt.Errorf("getting configmap %s: %w", name, err)
+	}
+	patch := client.MergeFrom(cm.DeepCopy())
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	for k, v := range data {
+		cm.Data[k] = v
+	}
+	if err = r.Client.Patch(ctx, &cm, patch); err != nil {
+		return fmt.Errorf("updating configmap %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package localbuild
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeGiteaAccountsClient struct {
+	mock.Mock
+}
+
+func (f *fakeGiteaAccountsClient) AdminCreateUser(opt gitea.CreateUserOption) (*gitea.User, *gitea.Response, error) {
+	args := f.Called(opt)
+	return nil, nil, args.Error(0)
+}
+
+func (f *fakeGiteaAccountsClient) AdminEditUser(user string, opt gitea.EditUserOption) (*gitea.Response, error) {
+	args := f.Called(user, opt)
+	return nil, args.Error(0)
+}
+
+func (f *fakeGiteaAccountsClient) GetUserInfo(user string) (*gitea.User, *gitea.Response, error) {
+	args := f.Called(user)
+	return nil, args.Get(0).(*gitea.Response), args.Error(1)
+}
+
+func (f *fakeGiteaAccountsClient) GetOrg(orgname string) (*gitea.Organization, *gitea.Response, error) {
+	args := f.Called(orgname)
+	return nil, args.Get(0).(*gitea.Response), args.Error(1)
+}
+
+func (f *fakeGiteaAccountsClient) CreateOrg(opt gitea.CreateOrgOption) (*gitea.Organization, *gitea.Response, error) {
+	args := f.Called(opt)
+	return nil, nil, args.Error(0)
+}
+
+func (f *fakeGiteaAccountsClient) ListOrgTeams(org string, opt gitea.ListTeamsOptions) ([]*gitea.Team, *gitea.Response, error) {
+	args := f.Called(org, opt)
+	return args.Get(0).([]*gitea.Team), nil, args.Error(1)
+}
+
+func (f *fakeGiteaAccountsClient) CreateTeam(org string, opt gitea.CreateTeamOption) (*gitea.Team, *gitea.Response, error) {
+	args := f.Called(org, opt)
+	return args.Get(0).(*gitea.Team), nil, args.Error(1)
+}
+
+func (f *fakeGiteaAccountsClient) AddTeamMember(id int64, user string) (*gitea.Response, error) {
+	args := f.Called(id, user)
+	return nil, args.Error(0)
+}
+
+func TestReconcileGiteaAccounts(t *testing.T) {
+	notFound := &gitea.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	found := &gitea.Response{Response: &http.Response{StatusCode: http.StatusOK}}
+	mustChangePassword := false
+	admin := true
+
+	c := new(fakeGiteaAccountsClient)
+	c.On("GetUserInfo", "dev").Return(found, nil)
+	c.On("GetUserInfo", "ops").Return(notFound, assert.AnError)
+	c.On("AdminCreateUser", gitea.CreateUserOption{
+		LoginName:          "ops",
+		Username:           "ops",
+		Email:              "ops@cnoe.localtest.me",
+		Password:           "ops-password",
+		MustChangePassword: &mustChangePassword,
+	}).Return(nil)
+	c.On("AdminEditUser", "ops", gitea.EditUserOption{LoginName: "ops", Admin: &admin}).Return(nil)
+	c.On("GetOrg", "platform").Return(notFound, assert.AnError)
+	c.On("CreateOrg", gitea.CreateOrgOption{Name: "platform", Visibility: gitea.VisibleTypePublic}).Return(nil)
+	c.On("ListOrgTeams", "platform", gitea.ListTeamsOptions{}).Return([]*gitea.Team{{ID: 1, Name: "Owners"}}, nil)
+	c.On("CreateTeam", "platform", gitea.CreateTeamOption{
+		Name:                    "viewers",
+		Permission:              gitea.AccessModeRead,
+		IncludesAllRepositories: true,
+		Units:                   giteaTeamUnits,
+	}).Return(&gitea.Team{ID: 2, Name: "viewers"}, nil)
+	c.On("AddTeamMember", int64(1), "ops").Return(nil)
+	c.On("AddTeamMember", int64(2), "dev").Return(nil)
+
+	err := reconcileGiteaAccounts(c, v1alpha1.GiteaAccountsSpec{
+		Users: []v1alpha1.GiteaUser{
+			{Username: "dev"},
+			{Username: "ops", Admin: true},
+		},
+		Organizations: []v1alpha1.GiteaOrganization{
+			{
+				Name: "platform",
+				Teams: []v1alpha1.GiteaTeam{
+					{Name: "Owners", Members: []string{"ops"}},
+					{Name: "viewers", Members: []string{"dev"}},
+				},
+			},
+		},
+	}, accountPasswords{
+		v1alpha1.GiteaUserPasswordKey("ops"): []byte("ops-password"),
+	}, "cnoe.localtest.me")
+	assert.NoError(t, err)
+	c.AssertExpectations(t)
+}
+
+func TestSetArgoCDPassword(t *testing.T) {
+	data := map[string][]byte{}
+	changed, err := setArgoCDPassword(data, "accounts.dev.", "dev-password")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NoError(t, bcrypt.CompareHashAndPassword(data["accounts.dev.password"], []byte("dev-password")))
+	assert.NotEmpty(t, data["accounts.dev.passwordMtime"])
+
+	hash := data["accounts.dev.password"]
+	changed, err = setArgoCDPassword(data, "accounts.dev.", "dev-password")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, hash, data["accounts.dev.password"])
+
+	changed, err = setArgoCDPassword(data, "accounts.dev.", "new-password")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+}
+
+func TestReconcileArgoCDAdminPasswordOnce(t *testing.T) {
+	ctx := context.Background()
+	argoSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: argoCDSecretName, Namespace: globals.ArgoCDNamespace}}
+	initialSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: argoCDInitialAdminSecretName, Namespace: globals.ArgoCDNamespace},
+		Data:       map[string][]byte{"password": []byte("generated")},
+	}
+	c := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(argoSecret, initialSecret).Build()
+	r := LocalbuildReconciler{Client: c}
+	passwords := accountPasswords{v1alpha1.ArgoCDAdminPasswordKey: []byte("changeme123")}
+
+	// the password generated by argocd is replaced by the configured one
+	assert.NoError(t, r.reconcileArgoCDAccounts(ctx, v1alpha1.ArgoCDAccountsSpec{}, passwords))
+	assert.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(initialSecret), initialSecret))
+	assert.Equal(t, "changeme123", string(initialSecret.Data["password"]))
+	assert.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(argoSecret), argoSecret))
+	assert.NoError(t, bcrypt.CompareHashAndPassword(argoSecret.Data["admin.password"], []byte("changeme123")))
+
+	// a rotated password is kept
+	initialSecret.Data["password"] = []byte("rotated")
+	assert.NoError(t, c.Update(ctx, initialSecret))
+	assert.NoError(t, r.reconcileArgoCDAccounts(ctx, v1alpha1.ArgoCDAccountsSpec{}, passwords))
+	assert.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(initialSecret), initialSecret))
+	assert.Equal(t, "rotated", string(initialSecret.Data["password"]))
+	hash := argoSecret.Data["admin.password"]
+	assert.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(argoSecret), argoSecret))
+	assert.Equal(t, hash, argoSecret.Data["admin.password"])
+}
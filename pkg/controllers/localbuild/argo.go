@@ -3,13 +3,24 @@ package localbuild
 import (
 	"context"
 	"embed"
+	"fmt"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// argocd verifies webhooks from gitea with the gogs secret.
+	argoCDWebhookSecretKey = "webhook.gogs.secret"
+	argoCDWebhookURL       = "%s://argocd-server.argocd.svc.cluster.local/api/webhook"
 )
 
 //go:embed resources/argo/*
@@ -54,6 +65,58 @@ func (r *LocalbuildReconciler) ReconcileArgo(ctx context.Context, req ctrl.Reque
 		return result, err
 	}
 
+	passwords, err := r.getAccountPasswords(ctx, resource.Spec.PackageConfigs.Accounts)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err = r.reconcileArgoCDAccounts(ctx, resource.Spec.PackageConfigs.Accounts.ArgoCD, passwords); err != nil {
+		return ctrl.Result{}, fmt.Errorf("reconciling argocd accounts: %w", err)
+	}
+
+	if err := r.reconcileArgoCDWebhookSecret(ctx); err != nil {
+		return ctrl.Result{}, fmt.Errorf("reconciling argocd webhook secret: %w", err)
+	}
+
 	resource.Status.ArgoCD.Available = true
 	return ctrl.Result{}, nil
 }
+
+// reconcileArgoCDWebhookSecret generates the secret Gitea signs webhooks with. It is generated once.
+func (r *LocalbuildReconciler) reconcileArgoCDWebhookSecret(ctx context.Context) error {
+	secret, err := GetArgoCDWebhookSecret(ctx, r.Client)
+	if err != nil {
+		return err
+	}
+	if secret != "" {
+		return nil
+	}
+
+	secret, err = util.GeneratePassword()
+	if err != nil {
+		return fmt.Errorf("generating webhook secret: %w", err)
+	}
+	return updateSecretData(ctx, r.Client, client.ObjectKey{Name: argoCDSecretName, Namespace: globals.ArgoCDNamespace},
+		map[string][]byte{argoCDWebhookSecretKey: []byte(secret)})
+}
+
+// GetArgoCDWebhookSecret returns the secret ArgoCD verifies Gitea webhooks with. It is empty if ArgoCD does not have one.
+func GetArgoCDWebhookSecret(ctx context.Context, kubeClient client.Client) (string, error) {
+	secret := corev1.Secret{}
+	err := kubeClient.Get(ctx, client.ObjectKey{Name: argoCDSecretName, Namespace: globals.ArgoCDNamespace}, &secret)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("getting secret %s: %w", argoCDSecretName, err)
+	}
+	return string(secret.Data[argoCDWebhookSecretKey]), nil
+}
+
+// ArgoCDWebhookURL returns the webhook endpoint of the ArgoCD server reachable within the cluster.
+func ArgoCDWebhookURL(config v1alpha1.BuildCustomizationSpec) string {
+	// the server only serves plain http with path routing.
+	if config.UsePathRouting {
+		return fmt.Sprintf(argoCDWebhookURL, "http")
+	}
+	return fmt.Sprintf(argoCDWebhookURL, "https")
+}
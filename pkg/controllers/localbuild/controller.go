@@ -48,6 +48,11 @@ type LocalbuildReconciler struct {
 	Config         v1alpha1.BuildCustomizationSpec
 	TempDir        string
 	RepoMap        *util.RepoMap
+	// GitAuth is used to authenticate against remote package repositories.
+	GitAuth util.GitAuthOptions
+	// WaitForHealthy delays exiting on sync until all ArgoCD applications are synced and healthy.
+	WaitForHealthy   bool
+	refreshRequested bool
 }
 
 type subReconciler func(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error)
@@ -89,13 +94,26 @@ func (r *LocalbuildReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
+	requeueAfter := defaultRequeueTime
+	if r.Config.SSO {
+		// not fatal. argocd and gitea keep working with local users until sso is configured.
+		// the CLI does not exit until it is, see shouldShutDown.
+		ssoResult, sErr := r.ReconcileSSO(ctx, req, &localBuild)
+		if sErr != nil {
+			logger.Error(sErr, "failed configuring sso. will try again")
+			requeueAfter = errRequeueTime
+		} else if ssoResult.RequeueAfter > 0 && ssoResult.RequeueAfter < requeueAfter {
+			requeueAfter = ssoResult.RequeueAfter
+		}
+	}
+
 	logger.V(1).Info("done installing core packages. passing control to argocd")
 	_, err = r.ReconcileArgoAppsWithGitea(ctx, req, &localBuild)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{RequeueAfter: defaultRequeueTime}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *LocalbuildReconciler) installCorePackages(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild, errChan chan error) {
@@ -247,6 +265,7 @@ func (r *LocalbuildReconciler) reconcileEmbeddedApp(ctx context.Context, appName
 			defaultArgoCDProjectName,
 			appName,
 			nil,
+			resource.Spec.PackageConfigs.GetSyncPolicy(appName),
 		)
 		err = r.Client.Create(ctx, app)
 		if err != nil {
@@ -261,6 +280,7 @@ func (r *LocalbuildReconciler) reconcileEmbeddedApp(ctx context.Context, appName
 		defaultArgoCDProjectName,
 		appName,
 		nil,
+		resource.Spec.PackageConfigs.GetSyncPolicy(appName),
 	)
 	err = r.Client.Update(ctx, app)
 	if err != nil {
@@ -339,6 +359,36 @@ func (r *LocalbuildReconciler) shouldShutDown(ctx context.Context, resource *v1a
 		}
 	}
 
+	if r.Config.SSO && !resource.Status.SSO.Available {
+		logger.Info("Waiting for SSO to be configured")
+		return false, nil
+	}
+
+	if !r.WaitForHealthy {
+		return true, nil
+	}
+
+	// contents are in the git server. have argocd look at them now instead of at its next poll.
+	if !r.refreshRequested {
+		err = r.requestArgoCDAppRefresh(ctx)
+		if err != nil {
+			return false, err
+		}
+		err = r.requestArgoCDAppSetRefresh(ctx)
+		if err != nil {
+			return false, err
+		}
+		r.refreshRequested = true
+	}
+
+	unhealthy, err := GetUnhealthyApplications(ctx, r.Client)
+	if err != nil {
+		return false, err
+	}
+	if len(unhealthy) != 0 {
+		logger.Info("Waiting for ArgoCD applications to be synced and healthy", "remaining", len(unhealthy))
+		return false, nil
+	}
 	return true, nil
 }
 
@@ -380,12 +430,17 @@ func (r *LocalbuildReconciler) reconcileCustomPkg(
 
 			customPkg.Spec = v1alpha1.CustomPackageSpec{
 				Replicate:           true,
+				MirrorHistory:       resource.Spec.PackageConfigs.MirrorHistory,
+				Branch:              resource.Spec.PackageConfigs.PackageBranch,
+				ScopedProject:       resource.Spec.PackageConfigs.ScopedProjects,
+				SyncPolicy:          customPkgSyncPolicy(resource.Spec.PackageConfigs, o),
 				GitServerURL:        resource.Status.Gitea.ExternalURL,
 				InternalGitServeURL: resource.Status.Gitea.InternalURL,
 				GitServerAuthSecretRef: v1alpha1.SecretReference{
 					Name:      resource.Status.Gitea.AdminUserSecretName,
 					Namespace: resource.Status.Gitea.AdminUserSecretNamespace,
 				},
+				GitServerOrganization: giteaAdminUser(resource),
 				ArgoCD: v1alpha1.ArgoCDPackageSpec{
 					ApplicationFile: filePath,
 					Name:            appName,
@@ -424,11 +479,18 @@ func (r *LocalbuildReconciler) reconcileCustomPkgUrl(ctx context.Context, resour
 		Path:            remote.Path(),
 	}
 
+	auth, err := r.GitAuth.AuthMethod(ctx, rs.Url)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting credentials for %s: %w", rs.Url, err)
+	}
+
 	cloneDir := util.RepoDir(rs.Url, r.TempDir)
 	st := r.RepoMap.LoadOrStore(rs.Url, cloneDir)
-	st.MU.Lock()
-	defer st.MU.Unlock()
-	wt, _, err := util.CloneRemoteRepoToDir(ctx, rs, 1, false, cloneDir, "")
+	if err = st.Lock(); err != nil {
+		return ctrl.Result{}, fmt.Errorf("locking %s: %w", cloneDir, err)
+	}
+	defer st.Unlock()
+	wt, _, err := util.CloneRemoteRepoToDir(ctx, rs, 1, false, auth, cloneDir, "")
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("cloning repo, %s: %w", pkgUrl, err)
 	}
@@ -514,7 +576,7 @@ func (r *LocalbuildReconciler) reconcileGitRepo(ctx context.Context, resource *v
 				Name:             v1alpha1.GitProviderGitea,
 				GitURL:           resource.Status.Gitea.ExternalURL,
 				InternalGitURL:   resource.Status.Gitea.InternalURL,
-				OrganizationName: v1alpha1.GiteaAdminUserName,
+				OrganizationName: giteaAdminUser(resource),
 			},
 			SecretRef: v1alpha1.SecretReference{
 				Name:      resource.Status.Gitea.AdminUserSecretName,
@@ -616,6 +678,23 @@ func isSupportedArgoCDTypes(gvk *schema.GroupVersionKind) bool {
 	return gvk.Group == argocdapp.Group && (gvk.Kind == argocdapp.ApplicationKind || gvk.Kind == argocdapp.ApplicationSetKind)
 }
 
+// customPkgSyncPolicy returns the sync policy configured for the package. The default policy only applies when the package file does not specify one.
+func customPkgSyncPolicy(cfg v1alpha1.PackageConfigsSpec, o *unstructured.Unstructured) *v1alpha1.SyncPolicySpec {
+	if s, ok := cfg.PackageSyncPolicies[o.GetName()]; ok {
+		return &s
+	}
+
+	path := []string{"spec", "syncPolicy"}
+	if o.GetKind() == argocdapp.ApplicationSetKind {
+		path = []string{"spec", "template", "spec", "syncPolicy"}
+	}
+	_, found, _ := unstructured.NestedFieldNoCopy(o.Object, path...)
+	if found {
+		return nil
+	}
+	return cfg.SyncPolicy
+}
+
 func GetEmbeddedRawInstallResources(name string, templateData any, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
 	switch name {
 	case v1alpha1.ArgoCDPackageName:
@@ -0,0 +1,71 @@
+package localbuild
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCustomPkgSyncPolicy(t *testing.T) {
+	defaultPolicy := &v1alpha1.SyncPolicySpec{Prune: true}
+	pkgPolicy := v1alpha1.SyncPolicySpec{Manual: true}
+	cfg := v1alpha1.PackageConfigsSpec{
+		SyncPolicy:          defaultPolicy,
+		PackageSyncPolicies: map[string]v1alpha1.SyncPolicySpec{"configured": pkgPolicy},
+	}
+
+	obj := func(kind, name string, spec map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       kind,
+			"metadata":   map[string]any{"name": name},
+			"spec":       spec,
+		}}
+	}
+	withPolicy := map[string]any{"syncPolicy": map[string]any{}}
+
+	cases := map[string]struct {
+		obj      *unstructured.Unstructured
+		expected *v1alpha1.SyncPolicySpec
+	}{
+		"default":                 {obj: obj("Application", "app", map[string]any{}), expected: defaultPolicy},
+		"packagePolicy":           {obj: obj("Application", "configured", withPolicy), expected: &pkgPolicy},
+		"fileHasPolicy":           {obj: obj("Application", "app", withPolicy), expected: nil},
+		"appSetDefault":           {obj: obj("ApplicationSet", "app", withPolicy), expected: defaultPolicy},
+		"appSetTemplateHasPolicy": {obj: obj("ApplicationSet", "app", map[string]any{"template": map[string]any{"spec": withPolicy}}), expected: nil},
+	}
+
+	for k := range cases {
+		assert.Equal(t, cases[k].expected, customPkgSyncPolicy(cfg, cases[k].obj), k)
+	}
+}
+
+func TestShouldShutDownWaitsForSSO(t *testing.T) {
+	ctx := context.Background()
+	resource := &v1alpha1.Localbuild{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "localdev",
+			Annotations: map[string]string{v1alpha1.CliStartTimeAnnotation: "now"},
+		},
+	}
+	r := LocalbuildReconciler{
+		Client:     fake.NewClientBuilder().WithScheme(k8s.GetScheme()).Build(),
+		ExitOnSync: true,
+		Config:     v1alpha1.BuildCustomizationSpec{SSO: true},
+	}
+
+	shutdown, err := r.shouldShutDown(ctx, resource)
+	assert.NoError(t, err)
+	assert.False(t, shutdown)
+
+	resource.Status.SSO.Available = true
+	shutdown, err = r.shouldShutDown(ctx, resource)
+	assert.NoError(t, err)
+	assert.True(t, shutdown)
+}
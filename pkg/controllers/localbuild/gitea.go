@@ -34,6 +34,8 @@ const (
 	// this is the URL accessible within cluster for ArgoCD to fetch resources.
 	// resolves to cluster ip
 	giteaSvcURL = "%s://%s%s:%s%s"
+	// this is the URL of the gitea service. used in existing clusters where coredns is not configured by us.
+	giteaClusterLocalURL = "http://my-gitea-http.gitea.svc.cluster.local:3000"
 )
 
 //go:embed resources/gitea/k8s/*
@@ -56,19 +58,34 @@ func giteaAdminSecretObject() corev1.Secret {
 	}
 }
 
-func newGiteaAdminSecret() (corev1.Secret, error) {
-	pass, err := util.GeneratePassword()
-	if err != nil {
-		return corev1.Secret{}, err
+func newGiteaAdminSecret(admin v1alpha1.AccountCredential, pass string) (corev1.Secret, error) {
+	user := admin.Username
+	if user == "" {
+		user = v1alpha1.GiteaAdminUserName
+	}
+	if pass == "" {
+		p, err := util.GeneratePassword()
+		if err != nil {
+			return corev1.Secret{}, err
+		}
+		pass = p
 	}
 	obj := giteaAdminSecretObject()
 	obj.StringData = map[string]string{
-		"username": v1alpha1.GiteaAdminUserName,
+		"username": user,
 		"password": pass,
 	}
 	return obj, nil
 }
 
+// giteaAdminUser returns the name of the Gitea admin user recorded in status.
+func giteaAdminUser(resource *v1alpha1.Localbuild) string {
+	if resource.Status.Gitea.AdminUser != "" {
+		return resource.Status.Gitea.AdminUser
+	}
+	return v1alpha1.GiteaAdminUserName
+}
+
 func (r *LocalbuildReconciler) ReconcileGitea(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
 	logger := log.FromContext(ctx, "installer", "gitea")
 	gitea := EmbeddedInstallation{
@@ -85,15 +102,20 @@ func (r *LocalbuildReconciler) ReconcileGitea(ctx context.Context, req ctrl.Requ
 		},
 	}
 
+	passwords, err := r.getAccountPasswords(ctx, resource.Spec.PackageConfigs.Accounts)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	sec := giteaAdminSecretObject()
-	err := r.Client.Get(ctx, types.NamespacedName{
+	err = r.Client.Get(ctx, types.NamespacedName{
 		Namespace: sec.GetNamespace(),
 		Name:      sec.GetName(),
 	}, &sec)
 
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			giteaCreds, err := newGiteaAdminSecret()
+			giteaCreds, err := newGiteaAdminSecret(resource.Spec.PackageConfigs.Accounts.Gitea.Admin, string(passwords[v1alpha1.GiteaAdminPasswordKey]))
 			if err != nil {
 				return ctrl.Result{}, fmt.Errorf("generating gitea admin secret: %w", err)
 			}
@@ -134,10 +156,17 @@ func (r *LocalbuildReconciler) ReconcileGitea(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, fmt.Errorf("creating gitea token: %w", err)
 	}
 
+	giteaClient := newGiteaClient(ctx, baseUrl, string(sec.Data["username"]), string(sec.Data["password"]))
+	err = reconcileGiteaAccounts(giteaClient, resource.Spec.PackageConfigs.Accounts.Gitea, passwords, r.Config.Host)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("reconciling gitea accounts: %w", err)
+	}
+
 	resource.Status.Gitea.ExternalURL = baseUrl
 	resource.Status.Gitea.InternalURL = giteaInternalBaseUrl(r.Config)
 	resource.Status.Gitea.AdminUserSecretName = giteaAdminSecret
 	resource.Status.Gitea.AdminUserSecretNamespace = giteaNamespace
+	resource.Status.Gitea.AdminUser = string(sec.Data["username"])
 	resource.Status.Gitea.Available = true
 	return ctrl.Result{}, nil
 }
@@ -210,12 +239,34 @@ func getGiteaToken(ctx context.Context, baseUrl, username, password string) (str
 	return token.Token, nil
 }
 
+// gitea URL used by the CLI. kind clusters map the port to localhost.
+// existing clusters are reached through the address of the ingress service, which the host name must resolve to.
 func giteaBaseUrl(config v1alpha1.BuildCustomizationSpec) string {
+	if config.IngressServiceType != "" {
+		return giteaRootUrl(config)
+	}
 	return fmt.Sprintf(giteaIngressURL, config.Protocol, config.Port)
 }
 
-// gitea URL reachable within the cluster with proper coredns config. Mainly for argocd
+// gitea URL reachable within the cluster. Mainly for argocd
+// kind clusters resolve the external host name with proper coredns config. existing clusters use the service.
 func giteaInternalBaseUrl(config v1alpha1.BuildCustomizationSpec) string {
+	if config.IngressServiceType != "" {
+		return giteaClusterLocalURL
+	}
+	return giteaRootUrl(config)
+}
+
+// giteaHost returns the host name in the URL gitea is configured with.
+func giteaHost(config v1alpha1.BuildCustomizationSpec) string {
+	if config.UsePathRouting {
+		return config.Host
+	}
+	return "gitea." + config.Host
+}
+
+// giteaRootUrl returns the URL gitea is configured with. It is the URL users open in browsers.
+func giteaRootUrl(config v1alpha1.BuildCustomizationSpec) string {
 	if config.UsePathRouting {
 		return fmt.Sprintf(giteaSvcURL, config.Protocol, "", config.Host, config.Port, "/gitea")
 	}
@@ -20,4 +20,12 @@ func TestGiteaInternalBaseUrl(t *testing.T) {
 	c.UsePathRouting = true
 	s = giteaInternalBaseUrl(c)
 	assert.Equal(t, "http://cnoe.localtest.me:8080/gitea", s)
+
+	// existing clusters use the service in cluster and the ingress from outside
+	c.IngressServiceType = "LoadBalancer"
+	assert.Equal(t, giteaClusterLocalURL, giteaInternalBaseUrl(c))
+	assert.Equal(t, "http://cnoe.localtest.me:8080/gitea", giteaBaseUrl(c))
+	c.UsePathRouting = false
+	c.Host = "idp.example.com"
+	assert.Equal(t, "http://gitea.idp.example.com:8080", giteaBaseUrl(c))
 }
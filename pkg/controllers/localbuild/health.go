@@ -0,0 +1,103 @@
+package localbuild
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const healthStatusHealthy = "Healthy"
+
+// UnhealthyApplication is an ArgoCD application that is not synced or not healthy.
+type UnhealthyApplication struct {
+	Name          string
+	SyncStatus    string
+	HealthStatus  string
+	HealthMessage string
+	Resources     []UnhealthyResource
+}
+
+// UnhealthyResource is a resource of an application that is not synced or not healthy.
+type UnhealthyResource struct {
+	Kind          string
+	Namespace     string
+	Name          string
+	SyncStatus    string
+	HealthStatus  string
+	HealthMessage string
+}
+
+func (u UnhealthyApplication) String() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "application %s: sync=%s health=%s", u.Name, u.SyncStatus, u.HealthStatus)
+	if u.HealthMessage != "" {
+		fmt.Fprintf(&b, " message=%q", u.HealthMessage)
+	}
+	for _, r := range u.Resources {
+		fmt.Fprintf(&b, "\n  %s %s/%s: sync=%s health=%s", r.Kind, r.Namespace, r.Name, r.SyncStatus, r.HealthStatus)
+		if r.HealthMessage != "" {
+			fmt.Fprintf(&b, " message=%q", r.HealthMessage)
+		}
+	}
+	return b.String()
+}
+
+// isAppSyncedAndHealthy returns false while a requested refresh is pending, because the status may be from before the refresh.
+func isAppSyncedAndHealthy(app *argov1alpha1.Application) bool {
+	if _, ok := app.GetAnnotations()[argoCDApplicationAnnotationKeyRefresh]; ok {
+		return false
+	}
+	return app.Status.Sync.Status == argov1alpha1.SyncStatusCodeSynced && app.Status.Health.Status == healthStatusHealthy
+}
+
+// GetUnhealthyApplications returns ArgoCD applications that are not synced and healthy, along with their resources that are not.
+func GetUnhealthyApplications(ctx context.Context, kubeClient client.Client) ([]UnhealthyApplication, error) {
+	apps := &argov1alpha1.ApplicationList{}
+	err := kubeClient.List(ctx, apps, client.InNamespace(globals.ArgoCDNamespace))
+	if err != nil {
+		return nil, fmt.Errorf("listing argocd apps: %w", err)
+	}
+
+	out := make([]UnhealthyApplication, 0)
+	for i := range apps.Items {
+		app := apps.Items[i]
+		if isAppSyncedAndHealthy(&app) {
+			continue
+		}
+
+		u := UnhealthyApplication{
+			Name:          app.Name,
+			SyncStatus:    string(app.Status.Sync.Status),
+			HealthStatus:  string(app.Status.Health.Status),
+			HealthMessage: app.Status.Health.Message,
+		}
+		for _, res := range app.Status.Resources {
+			r := UnhealthyResource{
+				Kind:       res.Kind,
+				Namespace:  res.Namespace,
+				Name:       res.Name,
+				SyncStatus: string(res.Status),
+			}
+			if res.Health != nil {
+				r.HealthStatus = string(res.Health.Status)
+				r.HealthMessage = res.Health.Message
+			}
+			// resources without health checks, e.g. ConfigMaps, do not have health status.
+			if res.Status == argov1alpha1.SyncStatusCodeSynced && (res.Health == nil || r.HealthStatus == healthStatusHealthy) {
+				continue
+			}
+			u.Resources = append(u.Resources, r)
+		}
+		out = append(out, u)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
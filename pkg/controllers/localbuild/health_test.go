@@ -0,0 +1,69 @@
+package localbuild
+
+import (
+	"context"
+	"testing"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGetUnhealthyApplications(t *testing.T) {
+	ctx := context.Background()
+
+	healthy := argov1alpha1.ApplicationStatus{
+		Sync:   argov1alpha1.SyncStatus{Status: argov1alpha1.SyncStatusCodeSynced},
+		Health: argov1alpha1.HealthStatus{Status: healthStatusHealthy},
+	}
+	apps := []argov1alpha1.Application{
+		{ObjectMeta: metav1.ObjectMeta{Name: "healthy"}, Status: healthy},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "refreshing", Annotations: map[string]string{argoCDApplicationAnnotationKeyRefresh: "normal"}},
+			Status:     healthy,
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "degraded"},
+			Status: argov1alpha1.ApplicationStatus{
+				Sync:   argov1alpha1.SyncStatus{Status: argov1alpha1.SyncStatusCodeSynced},
+				Health: argov1alpha1.HealthStatus{Status: "Degraded"},
+				Resources: []argov1alpha1.ResourceStatus{
+					{Kind: "ConfigMap", Namespace: "a", Name: "cm", Status: argov1alpha1.SyncStatusCodeSynced},
+					{Kind: "Deployment", Namespace: "a", Name: "app", Status: argov1alpha1.SyncStatusCodeSynced,
+						Health: &argov1alpha1.HealthStatus{Status: "Degraded", Message: "Deployment exceeded its progress deadline"}},
+					{Kind: "Service", Namespace: "a", Name: "app", Status: argov1alpha1.SyncStatusCodeOutOfSync,
+						Health: &argov1alpha1.HealthStatus{Status: healthStatusHealthy}},
+				},
+			},
+		},
+	}
+
+	fClient := new(fakeKubeClient)
+	fClient.On("List", ctx, mock.Anything, []client.ListOption{client.InNamespace(globals.ArgoCDNamespace)}).
+		Run(func(args mock.Arguments) {
+			l := args.Get(1).(*argov1alpha1.ApplicationList)
+			l.Items = apps
+		}).Return(nil)
+
+	unhealthy, err := GetUnhealthyApplications(ctx, fClient)
+	assert.NoError(t, err)
+	assert.Equal(t, []UnhealthyApplication{
+		{
+			Name:         "degraded",
+			SyncStatus:   "Synced",
+			HealthStatus: "Degraded",
+			Resources: []UnhealthyResource{
+				{Kind: "Deployment", Namespace: "a", Name: "app", SyncStatus: "Synced", HealthStatus: "Degraded", HealthMessage: "Deployment exceeded its progress deadline"},
+				{Kind: "Service", Namespace: "a", Name: "app", SyncStatus: "OutOfSync", HealthStatus: "Healthy"},
+			},
+		},
+		{Name: "refreshing", SyncStatus: "Synced", HealthStatus: "Healthy"},
+	}, unhealthy)
+
+	assert.Equal(t, "application degraded: sync=Synced health=Degraded\n"+
+		"  Deployment a/app: sync=Synced health=Degraded message=\"Deployment exceeded its progress deadline\"\n"+
+		"  Service a/app: sync=OutOfSync health=Healthy", unhealthy[0].String())
+}
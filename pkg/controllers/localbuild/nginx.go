@@ -3,15 +3,23 @@ package localbuild
 import (
 	"context"
 	"embed"
+	"fmt"
+	"net"
+	"slices"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+const nginxControllerName = "ingress-nginx-controller"
+
 //go:embed resources/nginx/k8s/*
 var installNginxFS embed.FS
 
@@ -20,13 +28,14 @@ func RawNginxInstallResources(templateData any, config v1alpha1.PackageCustomiza
 }
 
 func (r *LocalbuildReconciler) ReconcileNginx(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
+	logger := log.FromContext(ctx, "installer", "nginx")
 	nginx := EmbeddedInstallation{
 		name:         "Nginx",
 		resourcePath: "resources/nginx/k8s",
 		resourceFS:   installNginxFS,
 		namespace:    globals.NginxNamespace,
 		monitoredResources: map[string]schema.GroupVersionKind{
-			"ingress-nginx-controller": {
+			nginxControllerName: {
 				Group:   "apps",
 				Version: "v1",
 				Kind:    "Deployment",
@@ -43,6 +52,75 @@ func (r *LocalbuildReconciler) ReconcileNginx(ctx context.Context, req ctrl.Requ
 		return result, err
 	}
 
+	if r.Config.IngressServiceType != "" {
+		addrs, err := ingressAddresses(ctx, r.Client, r.Config.IngressServiceType)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if len(addrs) == 0 {
+			logger.Info("waiting for the ingress service to get an address")
+			return ctrl.Result{RequeueAfter: errRequeueTime}, nil
+		}
+		checkIngressHost(ctx, giteaHost(r.Config), addrs)
+	}
+
 	resource.Status.Nginx.Available = true
 	return ctrl.Result{}, nil
 }
+
+// ingressAddresses returns the addresses the ingress service of an existing cluster is reachable at.
+// Load balancers report theirs in the service status. Node ports are reachable at the addresses of the nodes.
+func ingressAddresses(ctx context.Context, kubeClient client.Client, svcType string) ([]string, error) {
+	var out []string
+	if svcType == string(corev1.ServiceTypeNodePort) {
+		nodes := corev1.NodeList{}
+		if err := kubeClient.List(ctx, &nodes); err != nil {
+			return nil, fmt.Errorf("listing nodes: %w", err)
+		}
+		for i := range nodes.Items {
+			for _, a := range nodes.Items[i].Status.Addresses {
+				if a.Type == corev1.NodeExternalIP || a.Type == corev1.NodeInternalIP {
+					out = append(out, a.Address)
+				}
+			}
+		}
+		return out, nil
+	}
+
+	svc := corev1.Service{}
+	err := kubeClient.Get(ctx, client.ObjectKey{Name: nginxControllerName, Namespace: globals.NginxNamespace}, &svc)
+	if err != nil {
+		return nil, fmt.Errorf("getting service %s: %w", nginxControllerName, err)
+	}
+	for _, in := range svc.Status.LoadBalancer.Ingress {
+		if in.IP != "" {
+			out = append(out, in.IP)
+		}
+		if in.Hostname != "" {
+			out = append(out, in.Hostname)
+		}
+	}
+	return out, nil
+}
+
+// checkIngressHost logs when host does not resolve to any of the ingress addresses.
+// Gitea, ArgoCD and the CLI reach the cluster through host, so it must point at the ingress service.
+func checkIngressHost(ctx context.Context, host string, addrs []string) {
+	logger := log.FromContext(ctx)
+	resolved, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		logger.Info("host name does not resolve. point it at the ingress service", "host", host, "addresses", addrs, "err", err.Error())
+		return
+	}
+	for _, a := range addrs {
+		if slices.Contains(resolved, a) {
+			return
+		}
+		if ips, err := net.DefaultResolver.LookupHost(ctx, a); err == nil && slices.ContainsFunc(ips, func(ip string) bool {
+			return slices.Contains(resolved, ip)
+		}) {
+			return
+		}
+	}
+	logger.Info("host name does not resolve to the ingress service", "host", host, "resolved", resolved, "addresses", addrs)
+}
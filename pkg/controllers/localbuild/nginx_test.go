@@ -0,0 +1,91 @@
+package localbuild
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNginxIngressServiceType(t *testing.T) {
+	cfg := v1alpha1.BuildCustomizationSpec{
+		Protocol: "https",
+		Host:     "cnoe.localtest.me",
+		Port:     "8443",
+	}
+
+	for _, svcType := range []string{"", "LoadBalancer", "NodePort"} {
+		cfg.IngressServiceType = svcType
+		nginx := EmbeddedInstallation{resourcePath: "resources/nginx/k8s", resourceFS: installNginxFS}
+		objs, err := nginx.installResources(k8s.GetScheme(), cfg)
+		assert.NoError(t, err)
+
+		found := 0
+		for i := range objs {
+			switch o := objs[i].(type) {
+			case *appsv1.Deployment:
+				if o.Name != "ingress-nginx-controller" {
+					continue
+				}
+				found++
+				_, pinned := o.Spec.Template.Spec.NodeSelector["ingress-ready"]
+				assert.Equal(t, svcType == "", pinned)
+				for _, p := range o.Spec.Template.Spec.Containers[0].Ports {
+					if p.Name == "http" || p.Name == "https" {
+						assert.Equal(t, svcType == "", p.HostPort != 0)
+					}
+				}
+			case *corev1.Service:
+				if o.Name != "ingress-nginx-controller" {
+					continue
+				}
+				found++
+				expected := corev1.ServiceTypeNodePort
+				if svcType != "" {
+					expected = corev1.ServiceType(svcType)
+				}
+				assert.Equal(t, expected, o.Spec.Type)
+				// the node port is pinned to the port in URLs
+				assert.Equal(t, svcType == "NodePort", o.Spec.Ports[0].NodePort == 8443)
+			}
+		}
+		assert.Equal(t, 2, found)
+	}
+}
+
+func TestIngressAddresses(t *testing.T) {
+	ctx := context.Background()
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: nginxControllerName, Namespace: globals.NginxNamespace},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeHostName, Address: "node"},
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+		}},
+	}
+	c := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(svc, node).Build()
+
+	// no address assigned yet
+	addrs, err := ingressAddresses(ctx, c, string(corev1.ServiceTypeLoadBalancer))
+	assert.NoError(t, err)
+	assert.Empty(t, addrs)
+
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "192.168.1.10"}, {Hostname: "lb.example.com"}}
+	assert.NoError(t, c.Status().Update(ctx, svc))
+	addrs, err = ingressAddresses(ctx, c, string(corev1.ServiceTypeLoadBalancer))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.168.1.10", "lb.example.com"}, addrs)
+
+	addrs, err = ingressAddresses(ctx, c, string(corev1.ServiceTypeNodePort))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+}
@@ -0,0 +1,182 @@
+package localbuild
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	argoCDInitialAdminSecretName = "argocd-initial-admin-secret"
+	argoCDAdminUsername          = "admin"
+	// argocd rejects passwords longer than 32 characters by default.
+	argoCDPasswordLength = 32
+	argoCDURL            = "%s://argocd.%s:%s"
+	argoCDPathRoutingURL = "%s://%s:%s/argocd"
+)
+
+// RotateGiteaAdminCredential changes the password of the Gitea admin user and replaces its access token.
+// The admin secret is updated right after each change so git repository reconciliation keeps working.
+func RotateGiteaAdminCredential(ctx context.Context, kubeClient client.Client, config v1alpha1.BuildCustomizationSpec) error {
+	secret := corev1.Secret{}
+	err := kubeClient.Get(ctx, client.ObjectKey{Name: giteaAdminSecret, Namespace: giteaNamespace}, &secret)
+	if err != nil {
+		return fmt.Errorf("getting gitea admin secret: %w", err)
+	}
+	user, pass := string(secret.Data["username"]), string(secret.Data["password"])
+	if user == "" || pass == "" {
+		return fmt.Errorf("username or password field not found in gitea secret")
+	}
+
+	newPass, err := util.GeneratePassword()
+	if err != nil {
+		return fmt.Errorf("generating password: %w", err)
+	}
+
+	baseUrl := giteaBaseUrl(config)
+	giteaClient := newGiteaClient(ctx, baseUrl, user, pass)
+	mustChangePassword := false
+	resp, err := giteaClient.AdminEditUser(user, gitea.EditUserOption{
+		LoginName:          user,
+		Password:           newPass,
+		MustChangePassword: &mustChangePassword,
+	})
+	if err != nil {
+		return fmt.Errorf("changing gitea admin password. status: %s error : %w", responseStatus(resp), err)
+	}
+
+	err = updateSecretData(ctx, kubeClient, client.ObjectKeyFromObject(&secret), map[string][]byte{"password": []byte(newPass)})
+	if err != nil {
+		return fmt.Errorf("storing gitea admin password: %w", err)
+	}
+
+	token, err := getGiteaToken(ctx, baseUrl, user, newPass)
+	if err != nil {
+		return fmt.Errorf("getting gitea token: %w", err)
+	}
+	err = updateSecretData(ctx, kubeClient, client.ObjectKeyFromObject(&secret), map[string][]byte{giteaAdminTokenFieldName: []byte(token)})
+	if err != nil {
+		return fmt.Errorf("storing gitea token: %w", err)
+	}
+	return nil
+}
+
+// RotateArgoCDAdminPassword changes the password of the Argo CD admin user and stores it in the initial admin secret.
+func RotateArgoCDAdminPassword(ctx context.Context, kubeClient client.Client, config v1alpha1.BuildCustomizationSpec) error {
+	secret := corev1.Secret{}
+	err := kubeClient.Get(ctx, client.ObjectKey{Name: argoCDInitialAdminSecretName, Namespace: globals.ArgoCDNamespace}, &secret)
+	if err != nil {
+		return fmt.Errorf("getting argocd initial admin secret: %w", err)
+	}
+	pass := string(secret.Data["password"])
+	if pass == "" {
+		return fmt.Errorf("password field not found in argocd initial admin secret")
+	}
+
+	newPass, err := util.GeneratePasswordWithLength(argoCDPasswordLength)
+	if err != nil {
+		return fmt.Errorf("generating password: %w", err)
+	}
+
+	baseUrl := argoCDBaseUrl(config)
+	session := struct {
+		Token string `json:"token"`
+	}{}
+	err = argoCDRequest(ctx, http.MethodPost, baseUrl+"/api/v1/session", "", map[string]string{
+		"username": argoCDAdminUsername,
+		"password": pass,
+	}, &session)
+	if err != nil {
+		return fmt.Errorf("logging in to argocd: %w", err)
+	}
+
+	err = argoCDRequest(ctx, http.MethodPut, baseUrl+"/api/v1/account/password", session.Token, map[string]string{
+		"name":            argoCDAdminUsername,
+		"currentPassword": pass,
+		"newPassword":     newPass,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("changing argocd admin password: %w", err)
+	}
+
+	err = updateSecretData(ctx, kubeClient, client.ObjectKeyFromObject(&secret), map[string][]byte{"password": []byte(newPass)})
+	if err != nil {
+		return fmt.Errorf("storing argocd admin password: %w", err)
+	}
+	return nil
+}
+
+// argocd URL reachable from the host
+func argoCDBaseUrl(config v1alpha1.BuildCustomizationSpec) string {
+	if config.UsePathRouting {
+		return fmt.Sprintf(argoCDPathRoutingURL, config.Protocol, config.Host, config.Port)
+	}
+	return fmt.Sprintf(argoCDURL, config.Protocol, config.Host, config.Port)
+}
+
+func argoCDRequest(ctx context.Context, method, url, token string, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshalling request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := util.GetHttpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status: %s body: %s", resp.Status, respBody)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func updateSecretData(ctx context.Context, kubeClient client.Client, key client.ObjectKey, data map[string][]byte) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret := corev1.Secret{}
+		if err := kubeClient.Get(ctx, key, &secret); err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		for k, v := range data {
+			secret.Data[k] = v
+		}
+		return kubeClient.Update(ctx, &secret)
+	})
+}
+
+func responseStatus(resp *gitea.Response) string {
+	if resp == nil {
+		return ""
+	}
+	return resp.Status
+}
@@ -0,0 +1,60 @@
+package localbuild
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgoCDBaseUrl(t *testing.T) {
+	c := v1alpha1.BuildCustomizationSpec{
+		Protocol: "https",
+		Port:     "8443",
+		Host:     "cnoe.localtest.me",
+	}
+
+	assert.Equal(t, "https://argocd.cnoe.localtest.me:8443", argoCDBaseUrl(c))
+	c.UsePathRouting = true
+	assert.Equal(t, "https://cnoe.localtest.me:8443/argocd", argoCDBaseUrl(c))
+}
+
+func TestArgoCDRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/session":
+			body := map[string]string{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["password"] != "old" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"abc"}`))
+		case "/api/v1/account/password":
+			if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer abc" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	session := struct {
+		Token string `json:"token"`
+	}{}
+	err := argoCDRequest(ctx, http.MethodPost, server.URL+"/api/v1/session", "", map[string]string{"password": "old"}, &session)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", session.Token)
+
+	err = argoCDRequest(ctx, http.MethodPut, server.URL+"/api/v1/account/password", session.Token, map[string]string{}, nil)
+	assert.NoError(t, err)
+
+	err = argoCDRequest(ctx, http.MethodPost, server.URL+"/api/v1/session", "", map[string]string{"password": "wrong"}, &session)
+	assert.Error(t, err)
+}
@@ -0,0 +1,399 @@
+package localbuild
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"golang.org/x/crypto/bcrypt"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	argoCDDexDeploymentName = "argocd-dex-server"
+	argoCDDexConfigKey      = "dex.config"
+	argoCDSSOPolicyKey      = "policy.sso.csv"
+	argoCDSSOAdminRole      = "role:admin"
+	// argocd replaces values starting with $ in dex.config with the value of the key in argocd-secret.
+	argoCDGiteaClientSecretKey = "dex.gitea.clientSecret"
+
+	ssoCredentialSecretName = "idpbuilder-sso-credential"
+	ssoDefaultUsername      = "idpbuilder"
+	ssoPackageName          = "sso"
+
+	giteaDeploymentName         = "my-gitea"
+	giteaSSOClientID            = "gitea"
+	giteaSSOClientSecretName    = "gitea-sso-client"
+	giteaSSOClientSecretKey     = "clientSecret"
+	giteaSSOSourceName          = "idpbuilder"
+	giteaSSOInitContainerName   = "configure-sso"
+	giteaCACertConfigMapName    = "idpbuilder-ca"
+	giteaCACertMountPath        = "/etc/idpbuilder/certs"
+	giteaAppIniInitContainer    = "init-app-ini"
+	giteaConfigureInitContainer = "configure-gitea"
+
+	// registers the auth source once. gitea has no api for auth sources.
+	giteaSSOScript = `set -euo pipefail
+if gitea admin auth list | awk 'NR > 1 {print $2}' | grep -qx "${SSO_SOURCE_NAME}"; then
+  echo "auth source ${SSO_SOURCE_NAME} exists"
+  exit 0
+fi
+gitea admin auth add-oauth --name "${SSO_SOURCE_NAME}" --provider openidConnect \
+  --key "${SSO_CLIENT_ID}" --secret "${SSO_CLIENT_SECRET}" \
+  --auto-discover-url "${SSO_DISCOVERY_URL}" --scopes email --scopes profile --skip-local-2fa
+`
+)
+
+// ssoUser is a configured user with its password from the accounts secret.
+type ssoUser struct {
+	v1alpha1.SSOUser
+	Password string
+}
+
+type dexConfig struct {
+	EnablePasswordDB bool                `json:"enablePasswordDB"`
+	StaticPasswords  []dexStaticPassword `json:"staticPasswords"`
+	StaticClients    []dexStaticClient   `json:"staticClients"`
+}
+
+type dexStaticPassword struct {
+	Email    string `json:"email"`
+	Hash     string `json:"hash"`
+	Username string `json:"username"`
+	UserID   string `json:"userID"`
+}
+
+type dexStaticClient struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Secret       string   `json:"secret"`
+	RedirectURIs []string `json:"redirectURIs"`
+}
+
+// ReconcileSSO configures the Dex server bundled with ArgoCD with static users and makes Gitea log in with it.
+// ArgoCD logs in with its bundled Dex server once the url is set.
+func (r *LocalbuildReconciler) ReconcileSSO(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
+	logger := log.FromContext(ctx, "installer", "sso")
+
+	passwords, err := r.getAccountPasswords(ctx, resource.Spec.PackageConfigs.Accounts)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	users, err := r.ssoUsers(ctx, resource.Spec.PackageConfigs.Accounts.SSO.Users, passwords)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	clientSecret, err := r.getOrCreateGiteaSSOClientSecret(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	err = updateSecretData(ctx, r.Client, client.ObjectKey{Name: argoCDSecretName, Namespace: globals.ArgoCDNamespace},
+		map[string][]byte{argoCDGiteaClientSecretKey: []byte(clientSecret)})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("storing gitea client secret in %s: %w", argoCDSecretName, err)
+	}
+
+	cm := corev1.ConfigMap{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: argoCDConfigMapName, Namespace: globals.ArgoCDNamespace}, &cm)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting configmap %s: %w", argoCDConfigMapName, err)
+	}
+	dexCfg, err := newDexConfig(users, cm.Data[argoCDDexConfigKey], giteaRootUrl(r.Config))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	err = r.patchConfigMapData(ctx, argoCDConfigMapName, map[string]string{
+		"url":              argoCDBaseUrl(r.Config),
+		argoCDDexConfigKey: dexCfg,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	err = r.patchConfigMapData(ctx, argoCDRBACConfigMapName, map[string]string{
+		"scopes":           "[groups, email]",
+		argoCDSSOPolicyKey: argoCDSSOPolicy(users),
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// gitea registers the auth source on start up and needs the discovery endpoint to be available by then.
+	dex := appsv1.Deployment{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: argoCDDexDeploymentName, Namespace: globals.ArgoCDNamespace}, &dex)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting deployment %s: %w", argoCDDexDeploymentName, err)
+	}
+	if dex.Status.AvailableReplicas < 1 {
+		logger.Info("waiting for dex to become available")
+		return ctrl.Result{RequeueAfter: errRequeueTime}, nil
+	}
+
+	issuer := argoCDBaseUrl(r.Config) + "/api/dex"
+	if err = r.configureGiteaSSO(ctx, issuer+"/.well-known/openid-configuration"); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	resource.Status.SSO.IssuerURL = issuer
+	resource.Status.SSO.Available = true
+	return ctrl.Result{}, nil
+}
+
+// ssoUsers returns the configured users. A user with a random password is created when none are configured.
+func (r *LocalbuildReconciler) ssoUsers(ctx context.Context, users []v1alpha1.SSOUser, passwords accountPasswords) ([]ssoUser, error) {
+	if len(users) == 0 {
+		secret := corev1.Secret{}
+		err := r.Client.Get(ctx, client.ObjectKey{Name: ssoCredentialSecretName, Namespace: globals.ArgoCDNamespace}, &secret)
+		if err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return nil, fmt.Errorf("getting secret %s: %w", ssoCredentialSecretName, err)
+			}
+			secret, err = r.newSSOCredentialSecret()
+			if err != nil {
+				return nil, err
+			}
+			if err = r.Client.Create(ctx, &secret); err != nil {
+				return nil, fmt.Errorf("creating secret %s: %w", ssoCredentialSecretName, err)
+			}
+		}
+		return []ssoUser{{
+			SSOUser: v1alpha1.SSOUser{
+				Username: string(secret.Data["username"]),
+				Email:    string(secret.Data["email"]),
+				Admin:    true,
+			},
+			Password: string(secret.Data["password"]),
+		}}, nil
+	}
+
+	out := make([]ssoUser, len(users))
+	for i := range users {
+		password, err := passwords.required(v1alpha1.SSOUserPasswordKey(users[i].Username))
+		if err != nil {
+			return nil, fmt.Errorf("configuring sso user %s: %w", users[i].Username, err)
+		}
+		out[i] = ssoUser{SSOUser: users[i], Password: password}
+		if out[i].Email == "" {
+			out[i].Email = fmt.Sprintf("%s@%s", users[i].Username, r.Config.Host)
+		}
+	}
+	return out, nil
+}
+
+func (r *LocalbuildReconciler) newSSOCredentialSecret() (corev1.Secret, error) {
+	pass, err := util.GeneratePassword()
+	if err != nil {
+		return corev1.Secret{}, fmt.Errorf("generating password: %w", err)
+	}
+	return corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ssoCredentialSecretName,
+			Namespace: globals.ArgoCDNamespace,
+			Labels: map[string]string{
+				v1alpha1.CLISecretLabelKey:   v1alpha1.CLISecretLabelValue,
+				v1alpha1.PackageNameLabelKey: ssoPackageName,
+			},
+		},
+		Data: map[string][]byte{
+			"username": []byte(ssoDefaultUsername),
+			"email":    []byte(fmt.Sprintf("%s@%s", ssoDefaultUsername, r.Config.Host)),
+			"password": []byte(pass),
+		},
+	}, nil
+}
+
+func (r *LocalbuildReconciler) getOrCreateGiteaSSOClientSecret(ctx context.Context) (string, error) {
+	secret := corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: giteaSSOClientSecretName, Namespace: giteaNamespace}, &secret)
+	if err == nil {
+		return string(secret.Data[giteaSSOClientSecretKey]), nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return "", fmt.Errorf("getting secret %s: %w", giteaSSOClientSecretName, err)
+	}
+
+	pass, err := util.GeneratePassword()
+	if err != nil {
+		return "", fmt.Errorf("generating client secret: %w", err)
+	}
+	secret = corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      giteaSSOClientSecretName,
+			Namespace: giteaNamespace,
+		},
+		Data: map[string][]byte{giteaSSOClientSecretKey: []byte(pass)},
+	}
+	if err = r.Client.Create(ctx, &secret); err != nil {
+		return "", fmt.Errorf("creating secret %s: %w", giteaSSOClientSecretName, err)
+	}
+	return pass, nil
+}
+
+// newDexConfig returns the dex configuration with static users and Gitea as client.
+// Hashes in the current configuration are kept if they match to not restart dex on every reconcile.
+func newDexConfig(users []ssoUser, current, giteaURL string) (string, error) {
+	hashes := map[string]string{}
+	if current != "" {
+		cur := dexConfig{}
+		if err := yaml.Unmarshal([]byte(current), &cur); err != nil {
+			return "", fmt.Errorf("parsing current dex config: %w", err)
+		}
+		for i := range cur.StaticPasswords {
+			hashes[cur.StaticPasswords[i].Email] = cur.StaticPasswords[i].Hash
+		}
+	}
+
+	cfg := dexConfig{
+		EnablePasswordDB: true,
+		StaticPasswords:  make([]dexStaticPassword, 0, len(users)),
+		StaticClients: []dexStaticClient{{
+			ID:           giteaSSOClientID,
+			Name:         "Gitea",
+			Secret:       "$" + argoCDGiteaClientSecretKey,
+			RedirectURIs: []string{fmt.Sprintf("%s/user/oauth2/%s/callback", giteaURL, giteaSSOSourceName)},
+		}},
+	}
+	for i := range users {
+		u := users[i]
+		hash, ok := hashes[u.Email]
+		if !ok || bcrypt.CompareHashAndPassword([]byte(hash), []byte(u.Password)) != nil {
+			h, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return "", fmt.Errorf("hashing password: %w", err)
+			}
+			hash = string(h)
+		}
+		cfg.StaticPasswords = append(cfg.StaticPasswords, dexStaticPassword{
+			Email:    u.Email,
+			Hash:     hash,
+			Username: u.Username,
+			UserID:   u.Username,
+		})
+	}
+
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("marshalling dex config: %w", err)
+	}
+	return string(b), nil
+}
+
+// argoCDSSOPolicy assigns roles to SSO users. argocd identifies them by the email scope.
+func argoCDSSOPolicy(users []ssoUser) string {
+	policy := make([]string, 0, len(users))
+	for i := range users {
+		role := argoCDDefaultUserRole
+		if users[i].Admin {
+			role = argoCDSSOAdminRole
+		}
+		policy = append(policy, fmt.Sprintf("g, %s, %s", users[i].Email, role))
+	}
+	sort.Strings(policy)
+	return strings.Join(policy, "\n") + "\n"
+}
+
+// configureGiteaSSO makes Gitea trust the idpbuilder certificate and registers Dex as auth source.
+func (r *LocalbuildReconciler) configureGiteaSSO(ctx context.Context, discoveryURL string) error {
+	cm := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      giteaCACertConfigMapName,
+			Namespace: giteaNamespace,
+		},
+		Data: map[string]string{globals.SelfSignedCertCMKeyName: r.Config.SelfSignedCert},
+	}
+	err := r.Client.Create(ctx, &cm)
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("creating configmap %s: %w", giteaCACertConfigMapName, err)
+	}
+
+	deploy := appsv1.Deployment{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: giteaDeploymentName, Namespace: giteaNamespace}, &deploy)
+	if err != nil {
+		return fmt.Errorf("getting deployment %s: %w", giteaDeploymentName, err)
+	}
+	patch := client.MergeFrom(deploy.DeepCopy())
+	if !setGiteaSSOPodSpec(&deploy.Spec.Template.Spec, discoveryURL) {
+		return nil
+	}
+	if err = r.Client.Patch(ctx, &deploy, patch); err != nil {
+		return fmt.Errorf("updating deployment %s: %w", giteaDeploymentName, err)
+	}
+	return nil
+}
+
+// setGiteaSSOPodSpec adds the auth source init container, oauth settings and the idpbuilder certificate to the Gitea pod.
+// It returns false if the pod is already configured.
+func setGiteaSSOPodSpec(spec *corev1.PodSpec, discoveryURL string) bool {
+	for i := range spec.InitContainers {
+		if spec.InitContainers[i].Name == giteaSSOInitContainerName {
+			return false
+		}
+	}
+
+	var configure *corev1.Container
+	for i := range spec.InitContainers {
+		c := &spec.InitContainers[i]
+		switch c.Name {
+		case giteaAppIniInitContainer:
+			// users are created on first log in and linked to existing users with the same email.
+			c.Env = append(c.Env,
+				corev1.EnvVar{Name: "GITEA__OAUTH2_CLIENT__ENABLE_AUTO_REGISTRATION", Value: "true"},
+				corev1.EnvVar{Name: "GITEA__OAUTH2_CLIENT__ACCOUNT_LINKING", Value: "auto"},
+				corev1.EnvVar{Name: "GITEA__OAUTH2_CLIENT__USERNAME", Value: "email"},
+			)
+		case giteaConfigureInitContainer:
+			configure = c
+		}
+	}
+	if configure == nil {
+		return false
+	}
+
+	sso := corev1.Container{
+		Name:            giteaSSOInitContainerName,
+		Image:           configure.Image,
+		ImagePullPolicy: configure.ImagePullPolicy,
+		Command:         []string{"/bin/bash", "-c", giteaSSOScript},
+		SecurityContext: configure.SecurityContext,
+		VolumeMounts:    configure.VolumeMounts,
+		Env: append(append([]corev1.EnvVar{}, configure.Env...),
+			corev1.EnvVar{Name: "SSO_SOURCE_NAME", Value: giteaSSOSourceName},
+			corev1.EnvVar{Name: "SSO_CLIENT_ID", Value: giteaSSOClientID},
+			corev1.EnvVar{Name: "SSO_DISCOVERY_URL", Value: discoveryURL},
+			corev1.EnvVar{Name: "SSO_CLIENT_SECRET", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: giteaSSOClientSecretName},
+					Key:                  giteaSSOClientSecretKey,
+				},
+			}},
+		),
+	}
+	spec.InitContainers = append(spec.InitContainers, sso)
+
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: giteaCACertConfigMapName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: giteaCACertConfigMapName},
+			},
+		},
+	})
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: giteaCACertConfigMapName, MountPath: giteaCACertMountPath, ReadOnly: true})
+		// go reads certificates from every directory in SSL_CERT_DIR.
+		c.Env = append(c.Env, corev1.EnvVar{Name: "SSL_CERT_DIR", Value: "/etc/ssl/certs:" + giteaCACertMountPath})
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package localbuild
+
+import (
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestNewDexConfig(t *testing.T) {
+	users := []ssoUser{
+		{SSOUser: v1alpha1.SSOUser{Username: "dev", Email: "dev@cnoe.localtest.me"}, Password: "dev-password"},
+		{SSOUser: v1alpha1.SSOUser{Username: "ops", Email: "ops@cnoe.localtest.me", Admin: true}, Password: "ops-password"},
+	}
+
+	out, err := newDexConfig(users, "", "https://gitea.cnoe.localtest.me:8443")
+	require.NoError(t, err)
+
+	cfg := dexConfig{}
+	require.NoError(t, yaml.Unmarshal([]byte(out), &cfg))
+	assert.True(t, cfg.EnablePasswordDB)
+	assert.Equal(t, []dexStaticClient{{
+		ID:           "gitea",
+		Name:         "Gitea",
+		Secret:       "$dex.gitea.clientSecret",
+		RedirectURIs: []string{"https://gitea.cnoe.localtest.me:8443/user/oauth2/idpbuilder/callback"},
+	}}, cfg.StaticClients)
+	require.Len(t, cfg.StaticPasswords, 2)
+	for i := range users {
+		p := cfg.StaticPasswords[i]
+		assert.Equal(t, users[i].Email, p.Email)
+		assert.Equal(t, users[i].Username, p.Username)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(p.Hash), []byte(users[i].Password)))
+	}
+
+	// hashes of unchanged passwords are kept
+	users[1].Password = "new-password"
+	next, err := newDexConfig(users, out, "https://gitea.cnoe.localtest.me:8443")
+	require.NoError(t, err)
+	nextCfg := dexConfig{}
+	require.NoError(t, yaml.Unmarshal([]byte(next), &nextCfg))
+	assert.Equal(t, cfg.StaticPasswords[0].Hash, nextCfg.StaticPasswords[0].Hash)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(nextCfg.StaticPasswords[1].Hash), []byte("new-password")))
+}
+
+func TestArgoCDSSOPolicy(t *testing.T) {
+	policy := argoCDSSOPolicy([]ssoUser{
+		{SSOUser: v1alpha1.SSOUser{Username: "ops", Email: "ops@cnoe.localtest.me", Admin: true}},
+		{SSOUser: v1alpha1.SSOUser{Username: "dev", Email: "dev@cnoe.localtest.me"}},
+	})
+	assert.Equal(t, "g, dev@cnoe.localtest.me, role:readonly\ng, ops@cnoe.localtest.me, role:admin\n", policy)
+}
+
+func TestSetGiteaSSOPodSpec(t *testing.T) {
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{Name: "init-app-ini", Image: "gitea/gitea:1.22.0-rootless"},
+			{
+				Name:         "configure-gitea",
+				Image:        "gitea/gitea:1.22.0-rootless",
+				Env:          []corev1.EnvVar{{Name: "GITEA_APP_INI", Value: "/data/gitea/conf/app.ini"}},
+				VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+			},
+		},
+		Containers: []corev1.Container{{Name: "gitea"}},
+	}
+
+	url := "https://argocd.cnoe.localtest.me:8443/api/dex/.well-known/openid-configuration"
+	assert.True(t, setGiteaSSOPodSpec(&spec, url))
+
+	require.Len(t, spec.InitContainers, 3)
+	sso := spec.InitContainers[2]
+	assert.Equal(t, "configure-sso", sso.Name)
+	assert.Equal(t, "gitea/gitea:1.22.0-rootless", sso.Image)
+	assert.Equal(t, spec.InitContainers[1].VolumeMounts, sso.VolumeMounts)
+	assert.Contains(t, sso.Env, corev1.EnvVar{Name: "GITEA_APP_INI", Value: "/data/gitea/conf/app.ini"})
+	assert.Contains(t, sso.Env, corev1.EnvVar{Name: "SSO_DISCOVERY_URL", Value: url})
+	assert.Len(t, spec.InitContainers[1].Env, 1)
+	assert.Contains(t, spec.InitContainers[0].Env, corev1.EnvVar{Name: "GITEA__OAUTH2_CLIENT__ENABLE_AUTO_REGISTRATION", Value: "true"})
+
+	require.Len(t, spec.Volumes, 1)
+	assert.Equal(t, "idpbuilder-ca", spec.Volumes[0].ConfigMap.Name)
+	assert.Contains(t, spec.Containers[0].Env, corev1.EnvVar{Name: "SSL_CERT_DIR", Value: "/etc/ssl/certs:/etc/idpbuilder/certs"})
+
+	// already configured
+	assert.False(t, setGiteaSSOPodSpec(&spec, url))
+	assert.Len(t, spec.InitContainers, 3)
+}
@@ -2,9 +2,11 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/pkg/controllers/custompackage"
+	"github.com/cnoe-io/idpbuilder/pkg/runtime"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 
 	"github.com/cnoe-io/idpbuilder/pkg/controllers/gitrepository"
@@ -19,8 +21,11 @@ func RunControllers(
 	exitCh chan error,
 	ctxCancel context.CancelFunc,
 	exitOnSync bool,
+	waitForHealthy bool,
 	cfg v1alpha1.BuildCustomizationSpec,
 	tmpDir string,
+	gitAuth util.GitAuthOptions,
+	sourceTypes []string,
 ) error {
 	logger := log.FromContext(ctx)
 
@@ -28,40 +33,29 @@ func RunControllers(
 
 	// Run Localbuild controller
 	if err := (&localbuild.LocalbuildReconciler{
-		Client:     mgr.GetClient(),
-		Scheme:     mgr.GetScheme(),
-		ExitOnSync: exitOnSync,
-		CancelFunc: ctxCancel,
-		Config:     cfg,
-		TempDir:    tmpDir,
-		RepoMap:    repoMap,
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		ExitOnSync:     exitOnSync,
+		WaitForHealthy: waitForHealthy,
+		CancelFunc:     ctxCancel,
+		Config:         cfg,
+		TempDir:        tmpDir,
+		RepoMap:        repoMap,
+		GitAuth:        gitAuth,
 	}).SetupWithManager(mgr); err != nil {
 		logger.Error(err, "unable to create localbuild controller")
 		return err
 	}
 
-	err := (&gitrepository.RepositoryReconciler{
-		Client:          mgr.GetClient(),
-		Scheme:          mgr.GetScheme(),
-		Recorder:        mgr.GetEventRecorderFor("gitrepository-controller"),
-		Config:          cfg,
-		GitProviderFunc: gitrepository.GetGitProvider,
-		TempDir:         tmpDir,
-		RepoMap:         repoMap,
-	}).SetupWithManager(mgr, nil)
+	// images of packages are built with the runtime running the cluster
+	rt, err := runtime.DetectRuntime()
 	if err != nil {
-		logger.Error(err, "unable to create repo controller")
+		logger.V(1).Info("container runtime not detected, images of packages will not be built", "err", err)
 	}
 
-	err = (&custompackage.Reconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("custompackage-controller"),
-		TempDir:  tmpDir,
-		RepoMap:  repoMap,
-	}).SetupWithManager(mgr)
+	err = SetupPackageControllers(mgr, cfg, tmpDir, repoMap, gitAuth, sourceTypes, rt)
 	if err != nil {
-		logger.Error(err, "unable to create custom package controller")
+		logger.Error(err, "unable to create package controllers")
 	}
 
 	// Start our manager in another goroutine
@@ -76,3 +70,46 @@ func RunControllers(
 
 	return nil
 }
+
+// SetupPackageControllers adds the GitRepository and CustomPackage controllers to the manager.
+// Only objects with the given source types are reconciled. All are reconciled when sourceTypes is empty.
+// Images declared by packages are built with containerRuntime. It may be nil when packages do not declare images.
+func SetupPackageControllers(
+	mgr manager.Manager,
+	cfg v1alpha1.BuildCustomizationSpec,
+	tmpDir string,
+	repoMap *util.RepoMap,
+	gitAuth util.GitAuthOptions,
+	sourceTypes []string,
+	containerRuntime runtime.IRuntime,
+) error {
+	err := (&gitrepository.RepositoryReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Recorder:        mgr.GetEventRecorderFor("gitrepository-controller"),
+		Config:          cfg,
+		GitProviderFunc: gitrepository.GetGitProvider,
+		TempDir:         tmpDir,
+		RepoMap:         repoMap,
+		GitAuth:         gitAuth,
+		SourceTypes:     sourceTypes,
+	}).SetupWithManager(mgr, nil)
+	if err != nil {
+		return fmt.Errorf("creating repo controller: %w", err)
+	}
+
+	err = (&custompackage.Reconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		Recorder:         mgr.GetEventRecorderFor("custompackage-controller"),
+		TempDir:          tmpDir,
+		RepoMap:          repoMap,
+		GitAuth:          gitAuth,
+		SourceTypes:      sourceTypes,
+		ContainerRuntime: containerRuntime,
+	}).SetupWithManager(mgr)
+	if err != nil {
+		return fmt.Errorf("creating custom package controller: %w", err)
+	}
+	return nil
+}
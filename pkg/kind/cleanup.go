@@ -0,0 +1,110 @@
+package kind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeadmConfigPath is where kind writes the kubeadm config of a node. It has the labels of the node.
+const kubeadmConfigPath = "/kind/kubeadm.conf"
+
+var nodeLabelsRegex = regexp.MustCompile(`(?m)^\s*node-labels:\s*"?([^"\n]*?)"?\s*$`)
+
+// IsCreatedByIdpbuilder reports whether the named kind cluster has nodes labeled by idpbuilder.
+// Labels are read from the node containers, so clusters that are stopped or failed to start are checked too.
+func IsCreatedByIdpbuilder(ctx context.Context, rt runtime.IRuntime, provider IProvider, name string) (bool, error) {
+	allNodes, err := provider.ListNodes(name)
+	if err != nil {
+		return false, fmt.Errorf("listing nodes of %s: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, clusterQueryTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, n := range allNodes {
+		labels, lErr := nodeLabels(ctx, rt, n.String())
+		if lErr != nil {
+			errs = append(errs, lErr)
+			continue
+		}
+		if labels[v1alpha1.CreatedByLabelKey] == v1alpha1.CreatedByLabelValue {
+			return true, nil
+		}
+	}
+	return false, errors.Join(errs...)
+}
+
+// nodeLabels returns the labels kind configured for the node in the named container.
+func nodeLabels(ctx context.Context, rt runtime.IRuntime, container string) (map[string]string, error) {
+	b, err := rt.ReadContainerFile(ctx, container, kubeadmConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading kubeadm config of node %s: %w", container, err)
+	}
+
+	labels := map[string]string{}
+	for _, m := range nodeLabelsRegex.FindAllSubmatch(b, -1) {
+		for _, l := range strings.Split(string(m[1]), ",") {
+			k, v, _ := strings.Cut(l, "=")
+			if k != "" {
+				labels[k] = v
+			}
+		}
+	}
+	return labels, nil
+}
+
+// kubeConfigName is the name of the context, cluster and user kind exports for the named cluster.
+func kubeConfigName(name string) string {
+	return fmt.Sprintf("kind-%s", name)
+}
+
+// HasKubeConfig reports whether the kubeconfig file at path has entries of the named kind cluster.
+func HasKubeConfig(path, name string) (bool, error) {
+	cfg, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("loading kubeconfig %s: %w", path, err)
+	}
+
+	key := kubeConfigName(name)
+	_, hasContext := cfg.Contexts[key]
+	_, hasCluster := cfg.Clusters[key]
+	_, hasUser := cfg.AuthInfos[key]
+	return hasContext || hasCluster || hasUser, nil
+}
+
+// RemoveKubeConfig removes entries of the named kind cluster from the kubeconfig file at path.
+// kind removes them when deleting a cluster. This also removes entries left behind by clusters deleted otherwise.
+func RemoveKubeConfig(path, name string) error {
+	ok, err := HasKubeConfig(path, name)
+	if err != nil || !ok {
+		return err
+	}
+
+	cfg, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return fmt.Errorf("loading kubeconfig %s: %w", path, err)
+	}
+	key := kubeConfigName(name)
+	delete(cfg.Contexts, key)
+	delete(cfg.Clusters, key)
+	delete(cfg.AuthInfos, key)
+	if cfg.CurrentContext == key {
+		cfg.CurrentContext = ""
+	}
+	if err = clientcmd.WriteToFile(*cfg, path); err != nil {
+		return fmt.Errorf("writing kubeconfig %s: %w", path, err)
+	}
+	return nil
+}
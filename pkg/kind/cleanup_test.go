@@ -0,0 +1,83 @@
+package kind
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+func TestRemoveKubeConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+
+	ok, err := HasKubeConfig(path, "localdev")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, RemoveKubeConfig(path, "localdev"))
+
+	cfg := clientcmdapi.NewConfig()
+	for _, n := range []string{"kind-localdev", "kind-other"} {
+		cfg.Clusters[n] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+		cfg.AuthInfos[n] = &clientcmdapi.AuthInfo{Token: "token"}
+		cfg.Contexts[n] = &clientcmdapi.Context{Cluster: n, AuthInfo: n}
+	}
+	cfg.CurrentContext = "kind-localdev"
+	require.NoError(t, clientcmd.WriteToFile(*cfg, path))
+
+	ok, err = HasKubeConfig(path, "localdev")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, RemoveKubeConfig(path, "localdev"))
+	out, err := clientcmd.LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, out.CurrentContext)
+	assert.NotContains(t, out.Contexts, "kind-localdev")
+	assert.NotContains(t, out.Clusters, "kind-localdev")
+	assert.NotContains(t, out.AuthInfos, "kind-localdev")
+	assert.Contains(t, out.Contexts, "kind-other")
+}
+
+func TestIsCreatedByIdpbuilder(t *testing.T) {
+	ctx := context.Background()
+	kubeadmConfig := func(labels string) []byte {
+		return []byte("kind: InitConfiguration\nnodeRegistration:\n  kubeletExtraArgs:\n    node-labels: \"" + labels + "\"\n" +
+			"---\nkind: JoinConfiguration\nnodeRegistration:\n  kubeletExtraArgs:\n    node-labels: \"" + labels + "\"\n")
+	}
+	node := func(name string) nodes.Node {
+		n := &NodeMock{}
+		n.On("String").Return(name)
+		return n
+	}
+
+	provider := &mockProvider{}
+	provider.On("ListNodes", "localdev").Return([]nodes.Node{node("localdev-control-plane")}, nil)
+	provider.On("ListNodes", "other").Return([]nodes.Node{node("other-control-plane")}, nil)
+	provider.On("ListNodes", "broken").Return([]nodes.Node{node("broken-control-plane")}, nil)
+
+	rt := &mockRuntime{}
+	rt.On("ReadContainerFile", mock.Anything, "localdev-control-plane", kubeadmConfigPath).
+		Return(kubeadmConfig("ingress-ready=true,cnoe.io/created-by=idpbuilder"), nil)
+	rt.On("ReadContainerFile", mock.Anything, "other-control-plane", kubeadmConfigPath).
+		Return(kubeadmConfig("ingress-ready=true"), nil)
+	rt.On("ReadContainerFile", mock.Anything, "broken-control-plane", kubeadmConfigPath).
+		Return([]byte(nil), errors.New("no such file"))
+
+	ok, err := IsCreatedByIdpbuilder(ctx, rt, provider, "localdev")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = IsCreatedByIdpbuilder(ctx, rt, provider, "other")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = IsCreatedByIdpbuilder(ctx, rt, provider, "broken")
+	assert.ErrorContains(t, err, "broken-control-plane")
+}
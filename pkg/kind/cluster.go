@@ -38,6 +38,8 @@ type Cluster struct {
 	kindConfigPath    string
 	extraPortsMapping string
 	cfg               v1alpha1.BuildCustomizationSpec
+	// buildName is set for extra clusters. It is the name of the build the cluster belongs to.
+	buildName string
 }
 
 type PortMapping struct {
@@ -52,12 +54,14 @@ type IProvider interface {
 	Delete(string, string) error
 	Create(string, ...cluster.CreateOption) error
 	ExportKubeConfig(string, string, bool) error
+	KubeConfig(string, bool) (string, error)
 }
 
 type TemplateConfig struct {
 	v1alpha1.BuildCustomizationSpec
 	KubernetesVersion string
 	ExtraPortsMapping []PortMapping
+	BuildName         string
 }
 
 //go:embed resources/*
@@ -68,7 +72,9 @@ func (c *Cluster) getConfig() ([]byte, error) {
 	var rawConfigTempl []byte
 	var err error
 
-	if c.kindConfigPath != "" {
+	if c.buildName != "" {
+		rawConfigTempl, err = fs.ReadFile(configFS, "resources/kind-workload.yaml.tmpl")
+	} else if c.kindConfigPath != "" {
 		rawConfigTempl, err = os.ReadFile(c.kindConfigPath)
 	} else {
 		rawConfigTempl, err = fs.ReadFile(configFS, "resources/kind.yaml.tmpl")
@@ -98,11 +104,12 @@ func (c *Cluster) getConfig() ([]byte, error) {
 		BuildCustomizationSpec: c.cfg,
 		KubernetesVersion:      c.kubeVersion,
 		ExtraPortsMapping:      portMappingPairs,
+		BuildName:              c.buildName,
 	}); err != nil {
 		return nil, err
 	}
 
-	if c.kindConfigPath != "" {
+	if c.kindConfigPath != "" && c.buildName == "" {
 		parsedCluster, err := c.ensureCorrectConfig(retBuff)
 		if err != nil {
 			return nil, fmt.Errorf("ensuring custom kind config is correct: %w", err)
@@ -118,13 +125,24 @@ func (c *Cluster) getConfig() ([]byte, error) {
 	return retBuff, nil
 }
 
-func NewCluster(name, kubeVersion, kubeConfigPath, kindConfigPath, extraPortsMapping string, cfg v1alpha1.BuildCustomizationSpec) (*Cluster, error) {
-	detectOpt, err := cluster.DetectNodeProvider()
+// NewProvider returns the kind provider of the selected container runtime. It fails if the runtime cannot be reached.
+func NewProvider(ctx context.Context) (*cluster.Provider, error) {
+	if err := runtime.CheckAvailable(ctx); err != nil {
+		return nil, err
+	}
+	providerOpt, err := runtime.NodeProvider()
+	if err != nil {
+		return nil, err
+	}
+	return cluster.NewProvider(providerOpt), nil
+}
 
+func NewCluster(name, kubeVersion, kubeConfigPath, kindConfigPath, extraPortsMapping string, cfg v1alpha1.BuildCustomizationSpec) (*Cluster, error) {
+	providerOpt, err := runtime.NodeProvider()
 	if err != nil {
 		return nil, err
 	}
-	provider := cluster.NewProvider(detectOpt)
+	provider := cluster.NewProvider(providerOpt)
 
 	rt, err := runtime.DetectRuntime()
 	if err != nil {
@@ -193,6 +211,10 @@ func (c *Cluster) Reconcile(ctx context.Context, recreate bool) error {
 		if recreate {
 			setupLog.Info("Existing cluster found. Deleting.", "cluster", c.name)
 			c.provider.Delete(c.name, "")
+		} else if c.buildName != "" {
+			// extra clusters do not expose ports
+			setupLog.Info("Cluster already exists", "cluster", c.name)
+			return nil
 		} else {
 			rightPort, err := c.RunsOnRightPort(ctx)
 			if err != nil {
@@ -235,6 +257,20 @@ func (c *Cluster) ExportKubeConfig(name string, internal bool) error {
 	return c.provider.ExportKubeConfig(name, c.kubeConfigPath, internal)
 }
 
+// KubeConfig returns the kubeconfig of the cluster. Internal kubeconfig uses the address of the control plane on the docker network.
+func (c *Cluster) KubeConfig(internal bool) (string, error) {
+	return c.provider.KubeConfig(c.name, internal)
+}
+
+// Config returns the rendered kind config of the cluster.
+func (c *Cluster) Config() ([]byte, error) {
+	return c.getConfig()
+}
+
+func (c *Cluster) Name() string {
+	return c.name
+}
+
 func (c *Cluster) ensureCorrectConfig(in []byte) (kindv1alpha4.Cluster, error) {
 	// see pkg/kind/resources/kind.yaml.tmpl and pkg/controllers/localbuild/resources/nginx/k8s/ingress-nginx.yaml
 	// defines which container port we should be looking for.
@@ -303,6 +339,10 @@ nodes:
 		}
 		parsedCluster.Nodes[nodePosition].Labels[ingressNginxNodeLabelKey] = ingressNginxNodeLabelValue
 	}
+	if parsedCluster.Nodes[nodePosition].Labels == nil {
+		parsedCluster.Nodes[nodePosition].Labels = make(map[string]string)
+	}
+	parsedCluster.Nodes[nodePosition].Labels[v1alpha1.CreatedByLabelKey] = v1alpha1.CreatedByLabelValue
 
 	return parsedCluster, nil
 }
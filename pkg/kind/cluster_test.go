@@ -39,6 +39,7 @@ nodes:
   image: "kindest/node:v1.26.3"
   labels:
     ingress-ready: "true"
+    cnoe.io/created-by: "idpbuilder"
   extraPortMappings:
   - containerPort: 443
     hostPort: 8443
@@ -62,6 +63,7 @@ nodes:
   image: "kindest/node:v1.26.3"
   labels:
     ingress-ready: "true"
+    cnoe.io/created-by: "idpbuilder"
   extraPortMappings:
   - containerPort: 443
     hostPort: 8443
@@ -114,6 +116,7 @@ nodes:
   image: "kindest/node:v1.26.3"
   labels:
     ingress-ready: "true"
+    cnoe.io/created-by: "idpbuilder"
   extraPortMappings:
   - containerPort: 443
     hostPort: 8443
@@ -131,6 +134,41 @@ containerdConfigPatches:
 	assert.YAMLEq(t, expectConfig, string(cfg))
 }
 
+func TestGetConfigWorkload(t *testing.T) {
+	cluster, err := NewWorkloadCluster("localdev", "staging", "v1.26.3", "", v1alpha1.BuildCustomizationSpec{
+		Protocol: "https",
+		Host:     "cnoe.localtest.me",
+		Port:     "8443",
+	})
+	if err != nil {
+		t.Fatalf("Initializing cluster resource: %v", err)
+	}
+	assert.Equal(t, "localdev-staging", cluster.Name())
+
+	cfg, err := cluster.getConfig()
+	if err != nil {
+		t.Errorf("Error getting kind config: %v", err)
+	}
+
+	expectConfig := `
+kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+nodes:
+- role: control-plane
+  image: "kindest/node:v1.26.3"
+  labels:
+    cnoe.io/build-name: "localdev"
+    cnoe.io/created-by: "idpbuilder"
+containerdConfigPatches:
+- |-
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors."gitea.cnoe.localtest.me:8443"]
+    endpoint = ["https://gitea.cnoe.localtest.me"]
+  [plugins."io.containerd.grpc.v1.cri".registry.configs."gitea.cnoe.localtest.me".tls]
+    insecure_skip_verify = true`
+
+	assert.YAMLEq(t, expectConfig, string(cfg))
+}
+
 func TestGetConfigCustom(t *testing.T) {
 
 	type testCase struct {
@@ -211,6 +249,11 @@ func (m *mockRuntime) ContainerWithPort(ctx context.Context, name string, port s
 	return args.Get(0).(bool), args.Error(1)
 }
 
+func (m *mockRuntime) ReadContainerFile(ctx context.Context, container, path string) ([]byte, error) {
+	args := m.Called(ctx, container, path)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
 // Mock Docker client for testing
 type DockerClientMock struct {
 	client.APIClient
@@ -0,0 +1,124 @@
+package kind
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+const clusterQueryTimeout = 5 * time.Second
+
+// WorkloadClusterName returns the name of the kind cluster for an extra cluster of the build.
+func WorkloadClusterName(buildName, name string) string {
+	return fmt.Sprintf("%s-%s", buildName, name)
+}
+
+// NewWorkloadCluster returns an extra cluster of the build. Extra clusters do not run core packages and do not expose ports.
+// Their nodes are labeled with the build name so they can be found later. cfg is the customization of the build and is used
+// to pull images from the build's registry.
+func NewWorkloadCluster(buildName, name, kubeVersion, kubeConfigPath string, cfg v1alpha1.BuildCustomizationSpec) (*Cluster, error) {
+	c, err := NewCluster(WorkloadClusterName(buildName, name), kubeVersion, kubeConfigPath, "", "", cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.buildName = buildName
+	return c, nil
+}
+
+// ResolveRegistryHost points the registry host name at the control plane node of the build cluster on the nodes of an extra cluster.
+// The host name resolves to the loopback address and the ingress controller only runs in the build cluster.
+func (c *Cluster) ResolveRegistryHost(ctx context.Context) error {
+	buildNodes, err := c.provider.ListNodes(c.buildName)
+	if err != nil {
+		return fmt.Errorf("listing nodes of %s: %w", c.buildName, err)
+	}
+	cpNodes, err := nodeutils.ControlPlaneNodes(buildNodes)
+	if err != nil {
+		return err
+	}
+	if len(cpNodes) == 0 {
+		return fmt.Errorf("control plane node of %s not found", c.buildName)
+	}
+	ip, _, err := cpNodes[0].IP()
+	if err != nil {
+		return fmt.Errorf("getting address of %s: %w", cpNodes[0].String(), err)
+	}
+
+	entry := fmt.Sprintf("%s %s", ip, registryHost(c.cfg))
+	allNodes, err := c.provider.ListNodes(c.name)
+	if err != nil {
+		return fmt.Errorf("listing nodes of %s: %w", c.name, err)
+	}
+	for _, node := range allNodes {
+		// /etc/hosts is recreated when the node restarts, so the entry is added every time the cluster is reconciled.
+		cmd := node.CommandContext(ctx, "sh", "-c", fmt.Sprintf("grep -qxF '%[1]s' /etc/hosts || echo '%[1]s' >> /etc/hosts", entry))
+		if err = cmd.Run(); err != nil {
+			return fmt.Errorf("updating hosts of %s: %w", node.String(), err)
+		}
+	}
+	return nil
+}
+
+func registryHost(cfg v1alpha1.BuildCustomizationSpec) string {
+	if cfg.UsePathRouting {
+		return cfg.Host
+	}
+	return "gitea." + cfg.Host
+}
+
+// GetBuildName returns the name of the build the named kind cluster was created for as an extra cluster.
+// Empty string is returned if it is not an extra cluster.
+func GetBuildName(ctx context.Context, provider IProvider, name string) (string, error) {
+	kubeClient, err := GetKubeClient(provider, name)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, clusterQueryTimeout)
+	defer cancel()
+
+	nodes := corev1.NodeList{}
+	err = kubeClient.List(ctx, &nodes, client.HasLabels{v1alpha1.BuildNameLabelKey})
+	if err != nil {
+		return "", fmt.Errorf("listing nodes of %s: %w", name, err)
+	}
+	if len(nodes.Items) == 0 {
+		return "", nil
+	}
+	return nodes.Items[0].Labels[v1alpha1.BuildNameLabelKey], nil
+}
+
+// GetRESTConfig returns the config to access the named kind cluster using its external kubeconfig.
+func GetRESTConfig(provider IProvider, name string) (*rest.Config, error) {
+	kubeConfig, err := provider.KubeConfig(name, false)
+	if err != nil {
+		return nil, fmt.Errorf("getting kubeconfig of %s: %w", name, err)
+	}
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeConfig))
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig of %s: %w", name, err)
+	}
+	return restConfig, nil
+}
+
+// GetKubeClient returns a client for the named kind cluster using its external kubeconfig.
+func GetKubeClient(provider IProvider, name string) (client.Client, error) {
+	restConfig, err := GetRESTConfig(provider, name)
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = clusterQueryTimeout
+
+	kubeClient, err := client.New(restConfig, client.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("creating client for %s: %w", name, err)
+	}
+	return kubeClient, nil
+}
@@ -2,13 +2,17 @@ package logger
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"runtime"
 	"slices"
+	"strings"
 	"sync"
 	"time"
+
+	"k8s.io/klog/v2"
 )
 
 // https://en.wikipedia.org/wiki/ANSI_escape_code
@@ -26,6 +30,13 @@ const (
 	// this mirrors the limit value from the internal slog package
 	maxBufferSize = 16384
 	dateFormat    = time.Stamp
+
+	// ObjectKey is the key of Kubernetes object references in JSON records, in namespace/name form.
+	ObjectKey = "object"
+	// ControllerKey is the key controller-runtime uses for the name of the controller.
+	ControllerKey = "controller"
+	// LoggerKey is the key logr uses for logger names.
+	LoggerKey = "logger"
 )
 
 var bufPool = sync.Pool{
@@ -40,6 +51,11 @@ type Options struct {
 	Colored    bool
 	Level      slog.Leveler
 	TimeFormat string
+	// JSON writes one JSON object per record. Colored is ignored and source is always added.
+	JSON bool
+	// ComponentLevels overrides Level for components. A component is the controller a record is logged for,
+	// or the first part of the logger name.
+	ComponentLevels map[string]slog.Leveler
 }
 
 // Handler is very similar to slog's commonHandler
@@ -48,6 +64,7 @@ type Handler struct {
 	json              bool
 	preformattedAttrs []byte
 	groupPrefix       string
+	component         string
 	groups            []string
 	unopenedGroups    []string
 	nOpenGroups       int
@@ -58,6 +75,7 @@ type Handler struct {
 func NewHandler(out io.Writer, opts Options) *Handler {
 	return &Handler{
 		opts:              opts,
+		json:              opts.JSON,
 		preformattedAttrs: make([]byte, 0),
 		unopenedGroups:    make([]string, 0),
 		nOpenGroups:       0,
@@ -72,6 +90,7 @@ func (h *Handler) clone() *Handler {
 		json:              h.json,
 		preformattedAttrs: slices.Clip(h.preformattedAttrs),
 		groupPrefix:       h.groupPrefix,
+		component:         h.component,
 		groups:            slices.Clip(h.groups),
 		nOpenGroups:       h.nOpenGroups,
 		w:                 h.w,
@@ -80,18 +99,58 @@ func (h *Handler) clone() *Handler {
 }
 
 func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
-	minLevel := slog.LevelInfo
-	if h.opts.Level != nil {
-		minLevel = h.opts.Level.Level()
+	if h.component != "" {
+		return level >= h.componentLevel(h.component)
+	}
+	// the logger name is only known when the record is handled
+	minLevel := h.defaultLevel()
+	for _, l := range h.opts.ComponentLevels {
+		minLevel = min(minLevel, l.Level())
 	}
 	return level >= minLevel
 }
 
+func (h *Handler) defaultLevel() slog.Level {
+	if h.opts.Level != nil {
+		return h.opts.Level.Level()
+	}
+	return slog.LevelInfo
+}
+
+func (h *Handler) componentLevel(component string) slog.Level {
+	if l, ok := h.opts.ComponentLevels[component]; ok {
+		return l.Level()
+	}
+	return h.defaultLevel()
+}
+
+// recordEnabled checks the level of the record against the level of its component.
+func (h *Handler) recordEnabled(record slog.Record) bool {
+	if len(h.opts.ComponentLevels) == 0 {
+		return true
+	}
+	component := h.component
+	if component == "" {
+		record.Attrs(func(a slog.Attr) bool {
+			if a.Key == ControllerKey || a.Key == LoggerKey {
+				component, _, _ = strings.Cut(a.Value.String(), "/")
+				return a.Key != ControllerKey
+			}
+			return true
+		})
+	}
+	return record.Level >= h.componentLevel(component)
+}
+
 func (h *Handler) WithGroup(name string) slog.Handler {
 	if name == "" {
 		return h
 	}
 	h2 := h.clone()
+	if h.json {
+		h2.groupPrefix = h.groupPrefix + name + "."
+		return h2
+	}
 	h2.unopenedGroups = make([]string, len(h.unopenedGroups)+1)
 	copy(h2.unopenedGroups, h.unopenedGroups)
 	h2.unopenedGroups[len(h2.unopenedGroups)-1] = name
@@ -107,12 +166,18 @@ func (h *Handler) WithAttrs(as []slog.Attr) slog.Handler {
 	h2.unopenedGroups = nil
 
 	for _, a := range as {
+		if a.Key == ControllerKey {
+			h2.component = a.Value.String()
+		}
 		h2.preformattedAttrs = h2.appendAttr(h2.preformattedAttrs, a)
 	}
 	return h2
 }
 
 func (h *Handler) appendUnopenedGroups(buf []byte) []byte {
+	if h.json {
+		return buf
+	}
 	for _, g := range h.unopenedGroups {
 		buf = fmt.Appendf(buf, "%s ", g)
 	}
@@ -120,6 +185,12 @@ func (h *Handler) appendUnopenedGroups(buf []byte) []byte {
 }
 
 func (h *Handler) appendAttr(buf []byte, a slog.Attr) []byte {
+	// object references resolve to a group. keep them as a single value so all records use the same key.
+	if h.json && (a.Value.Kind() == slog.KindAny || a.Value.Kind() == slog.KindLogValuer) {
+		if ref, ok := a.Value.Any().(klog.ObjectRef); ok {
+			return h.appendJSONKeyValuePair(buf, ObjectKey, ref.String())
+		}
+	}
 	a.Value = a.Value.Resolve()
 	if a.Equal(slog.Attr{}) {
 		return buf
@@ -146,6 +217,10 @@ func (h *Handler) appendAttr(buf []byte, a slog.Attr) []byte {
 }
 
 func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if !h.recordEnabled(record) {
+		return nil
+	}
+
 	bufp := bufPool.Get().(*[]byte)
 	buf := *bufp
 
@@ -154,6 +229,10 @@ func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
 		free(bufp)
 	}()
 
+	if h.json {
+		return h.handleJSON(buf, record)
+	}
+
 	// append time, level, then message.
 	if h.opts.Colored {
 		buf = fmt.Appendf(buf, WhiteDim)
@@ -200,7 +279,64 @@ func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
 	return err
 }
 
+func (h *Handler) handleJSON(buf []byte, record slog.Record) error {
+	buf = append(buf, '{')
+	buf = appendJSONString(buf, slog.TimeKey)
+	buf = append(buf, ':')
+	buf = appendJSONString(buf, record.Time.Format(time.RFC3339Nano))
+	buf = h.appendJSONKeyValuePair(buf, slog.LevelKey, record.Level.String())
+	buf = h.appendJSONKeyValuePair(buf, slog.MessageKey, record.Message)
+	if record.PC != 0 {
+		src := source(record)
+		buf = h.appendJSONKeyValuePair(buf, slog.SourceKey, fmt.Sprintf("%s:%d", src.File, src.Line))
+	}
+
+	buf = append(buf, h.preformattedAttrs...)
+	record.Attrs(func(a slog.Attr) bool {
+		buf = h.appendAttr(buf, a)
+		return true
+	})
+	buf = append(buf, "}\n"...)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf)
+	return err
+}
+
+// appendJSONKeyValuePair appends a comma, then the key and value. Values that cannot be marshalled are written as strings.
+func (h *Handler) appendJSONKeyValuePair(buf []byte, key string, value any) []byte {
+	if key != slog.TimeKey && key != slog.LevelKey && key != slog.MessageKey && key != slog.SourceKey {
+		key = h.groupPrefix + key
+	}
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case time.Time:
+		value = v.Format(time.RFC3339Nano)
+	case time.Duration:
+		value = v.String()
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprintf("%+v", value))
+	}
+	buf = append(buf, ',')
+	buf = appendJSONString(buf, key)
+	buf = append(buf, ':')
+	return append(buf, b...)
+}
+
+func appendJSONString(buf []byte, s string) []byte {
+	b, _ := json.Marshal(s)
+	return append(buf, b...)
+}
+
 func (h *Handler) appendKeyValuePair(buf []byte, a slog.Attr) []byte {
+	if h.json {
+		return h.appendJSONKeyValuePair(buf, a.Key, a.Value.Any())
+	}
 	if h.opts.Colored {
 		if a.Key == "err" {
 			return fmt.Appendf(buf, "%s%s=%v%s ", BrightRed, a.Key, a.Value.String(), Reset)
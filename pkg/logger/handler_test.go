@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/klog/v2"
+)
+
+func TestHandlerJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	l := logr.FromSlogHandler(NewHandler(out, Options{Level: slog.LevelDebug, JSON: true}))
+
+	// mirrors the values controller-runtime adds to reconciler loggers
+	l.WithName("gitrepository").
+		WithValues("controller", "gitrepository", "GitRepository", klog.KRef("argocd", "repo"), "namespace", "argocd", "reconcileID", "abc").
+		Info("reconciling", "count", 2, "ready", true)
+	l.Error(errors.New("boom"), "failed", "line", "a\nb \"quoted\"")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	first := map[string]any{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "INFO", first["level"])
+	assert.Equal(t, "reconciling", first["msg"])
+	assert.Equal(t, "gitrepository", first["controller"])
+	assert.Equal(t, "gitrepository", first["logger"])
+	assert.Equal(t, "argocd/repo", first[ObjectKey])
+	assert.Equal(t, "argocd", first["namespace"])
+	assert.Equal(t, "abc", first["reconcileID"])
+	assert.Equal(t, float64(2), first["count"])
+	assert.Equal(t, true, first["ready"])
+	assert.NotEmpty(t, first["time"])
+	assert.Contains(t, first["source"], "handler_test.go")
+
+	second := map[string]any{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "ERROR", second["level"])
+	assert.Equal(t, "boom", second["err"])
+	assert.Equal(t, "a\nb \"quoted\"", second["line"])
+}
+
+func TestHandlerComponentLevels(t *testing.T) {
+	out := &bytes.Buffer{}
+	l := logr.FromSlogHandler(NewHandler(out, Options{
+		Level:           slog.LevelInfo,
+		ComponentLevels: map[string]slog.Leveler{"gitrepository": slog.LevelDebug, "localbuild": slog.LevelError},
+	}))
+
+	l.WithValues("controller", "gitrepository").V(1).Info("clone debug")
+	l.WithValues("controller", "localbuild").Info("localbuild info")
+	l.WithValues("controller", "localbuild").Error(errors.New("boom"), "localbuild error")
+	l.WithName("gitrepository").WithName("gitea").V(1).Info("named debug")
+	l.WithName("setup").V(1).Info("setup debug")
+	l.WithName("setup").Info("setup info")
+
+	s := out.String()
+	assert.Contains(t, s, "clone debug")
+	assert.NotContains(t, s, "localbuild info")
+	assert.Contains(t, s, "localbuild error")
+	assert.Contains(t, s, "named debug")
+	assert.NotContains(t, s, "setup debug")
+	assert.Contains(t, s, "setup info")
+}
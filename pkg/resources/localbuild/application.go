@@ -2,6 +2,7 @@ package localbuild
 
 import (
 	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,7 +29,30 @@ func SetProjectSpec(project *argov1alpha1.AppProject) {
 	}
 }
 
-func SetApplicationSpec(app *argov1alpha1.Application, repoUrl, path, project, dstNS string, targetRevision *string) {
+// SetScopedProjectSpec permits only what is listed in perms. Namespace scoped kinds are permitted in the listed destinations.
+func SetScopedProjectSpec(project *argov1alpha1.AppProject, perms v1alpha1.ProjectPermissions) {
+	project.Spec.Description = "IDP Builder Project for " + perms.Name
+
+	project.Spec.ClusterResourceWhitelist = perms.ClusterResources
+	project.Spec.NamespaceResourceWhitelist = []v1.GroupKind{{
+		Group: "*",
+		Kind:  "*",
+	}}
+
+	project.Spec.Destinations = make([]argov1alpha1.ApplicationDestination, 0, len(perms.Destinations))
+	for i := range perms.Destinations {
+		project.Spec.Destinations = append(project.Spec.Destinations, argov1alpha1.ApplicationDestination{
+			Name:      perms.Destinations[i].Name,
+			Namespace: perms.Destinations[i].Namespace,
+			Server:    perms.Destinations[i].Server,
+		})
+	}
+
+	project.Spec.SourceRepos = perms.SourceRepos
+}
+
+// SetApplicationSpec sets the spec of embedded applications. The sync policy defaults to automated sync with self heal when syncPolicy is nil.
+func SetApplicationSpec(app *argov1alpha1.Application, repoUrl, path, project, dstNS string, targetRevision *string, syncPolicy *v1alpha1.SyncPolicySpec) {
 	headRev := "HEAD"
 	if targetRevision == nil {
 		targetRevision = &headRev
@@ -48,11 +72,69 @@ func SetApplicationSpec(app *argov1alpha1.Application, repoUrl, path, project, d
 	}
 
 	app.Spec.SyncPolicy = &argov1alpha1.SyncPolicy{
-		Automated: &argov1alpha1.SyncPolicyAutomated{
-			SelfHeal: true,
-		},
 		SyncOptions: argov1alpha1.SyncOptions{
 			"CreateNamespace=true",
 		},
 	}
+	app.Spec.IgnoreDifferences = nil
+	if syncPolicy == nil {
+		syncPolicy = &v1alpha1.SyncPolicySpec{}
+	}
+	SetSyncPolicy(&app.Spec, *syncPolicy)
+}
+
+// SetSyncPolicy replaces the automated sync, retry, and ignore differences settings of the application.
+// Existing sync options are kept.
+func SetSyncPolicy(spec *argov1alpha1.ApplicationSpec, policy v1alpha1.SyncPolicySpec) {
+	if spec.SyncPolicy == nil {
+		spec.SyncPolicy = &argov1alpha1.SyncPolicy{}
+	}
+
+	spec.SyncPolicy.Automated = nil
+	if !policy.Manual {
+		selfHeal := true
+		if policy.SelfHeal != nil {
+			selfHeal = *policy.SelfHeal
+		}
+		spec.SyncPolicy.Automated = &argov1alpha1.SyncPolicyAutomated{
+			Prune:    policy.Prune,
+			SelfHeal: selfHeal,
+		}
+	}
+
+	spec.SyncPolicy.Retry = nil
+	if policy.Retry != nil {
+		spec.SyncPolicy.Retry = &argov1alpha1.RetryStrategy{
+			Limit: policy.Retry.Limit,
+		}
+		if policy.Retry.Backoff != "" || policy.Retry.BackoffFactor != nil || policy.Retry.MaxBackoff != "" {
+			spec.SyncPolicy.Retry.Backoff = &argov1alpha1.Backoff{
+				Duration:    policy.Retry.Backoff,
+				Factor:      policy.Retry.BackoffFactor,
+				MaxDuration: policy.Retry.MaxBackoff,
+			}
+		}
+	}
+
+	if policy.ServerSideApply {
+		spec.SyncPolicy.SyncOptions = spec.SyncPolicy.SyncOptions.AddOption("ServerSideApply=true")
+	}
+
+	if len(policy.IgnoreDifferences) > 0 {
+		spec.IgnoreDifferences = make(argov1alpha1.IgnoreDifferences, 0, len(policy.IgnoreDifferences))
+		for i := range policy.IgnoreDifferences {
+			d := policy.IgnoreDifferences[i]
+			spec.IgnoreDifferences = append(spec.IgnoreDifferences, argov1alpha1.ResourceIgnoreDifferences{
+				Group:                 d.Group,
+				Kind:                  d.Kind,
+				Name:                  d.Name,
+				Namespace:             d.Namespace,
+				JSONPointers:          d.JSONPointers,
+				JQPathExpressions:     d.JQPathExpressions,
+				ManagedFieldsManagers: d.ManagedFieldsManagers,
+			})
+		}
+		// without this, ignored fields are still overwritten when a sync is triggered by other changes.
+		spec.SyncPolicy.SyncOptions = spec.SyncPolicy.SyncOptions.AddOption("RespectIgnoreDifferences=true")
+	}
 }
@@ -1,11 +1,16 @@
 package runtime
 
 import (
+	"archive/tar"
 	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/registry"
 	dockerClient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
 )
 
 type DockerRuntime struct {
@@ -84,3 +89,52 @@ func (p *DockerRuntime) ContainerWithPort(ctx context.Context, name, port string
 
 	return p.IsUsingPort(container, userPort), nil
 }
+
+func (p *DockerRuntime) BuildImage(ctx context.Context, opts BuildImageOptions) error {
+	buildContext := tarDirectory(opts.Context, opts.Dockerfile)
+	defer buildContext.Close()
+
+	resp, err := p.client.ImageBuild(ctx, buildContext, types.ImageBuildOptions{
+		Dockerfile: opts.Dockerfile,
+		Tags:       []string{opts.Tag},
+		Remove:     true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// errors during the build are only reported in the stream
+	return jsonmessage.DisplayJSONMessagesStream(resp.Body, io.Discard, 0, false, nil)
+}
+
+func (p *DockerRuntime) PushImage(ctx context.Context, image string, auth RegistryAuth) error {
+	encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		ServerAddress: strings.SplitN(image, "/", 2)[0],
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := p.client.ImagePush(ctx, image, types.ImagePushOptions{RegistryAuth: encoded})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return jsonmessage.DisplayJSONMessagesStream(body, io.Discard, 0, false, nil)
+}
+
+func (p *DockerRuntime) ReadContainerFile(ctx context.Context, container, path string) ([]byte, error) {
+	rc, _, err := p.client.CopyFromContainer(ctx, container, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	if _, err = tr.Next(); err != nil {
+		return nil, fmt.Errorf("reading %s from %s: %w", path, container, err)
+	}
+	return io.ReadAll(tr)
+}
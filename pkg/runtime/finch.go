@@ -6,7 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -87,3 +91,40 @@ func (f *FinchRuntime) ContainerWithPort(ctx context.Context, name string, port
 	logger.V(1).Info("existing cluster does not match the configuration", "container", name, "port", port)
 	return false, nil
 }
+
+func (f *FinchRuntime) BuildImage(ctx context.Context, opts BuildImageOptions) error {
+	return runFinch(ctx, nil, "build", "-f", filepath.Join(opts.Context, opts.Dockerfile), "-t", opts.Tag, opts.Context)
+}
+
+func (f *FinchRuntime) PushImage(ctx context.Context, image string, auth RegistryAuth) error {
+	host := strings.SplitN(image, "/", 2)[0]
+	err := runFinch(ctx, strings.NewReader(auth.Password), "login", host, "--username", auth.Username, "--password-stdin")
+	if err != nil {
+		return err
+	}
+	return runFinch(ctx, nil, "push", image)
+}
+
+func (f *FinchRuntime) ReadContainerFile(ctx context.Context, container, path string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "finch-cp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, filepath.Base(path))
+	if err = runFinch(ctx, nil, "cp", fmt.Sprintf("%s:%s", container, path), dst); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(dst)
+}
+
+func runFinch(ctx context.Context, stdin io.Reader, args ...string) error {
+	cmd := exec.CommandContext(ctx, "finch", args...)
+	cmd.Stdin = stdin
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("finch %s: %w: %s", args[0], err, out)
+	}
+	return nil
+}
@@ -14,4 +14,26 @@ type IRuntime interface {
 
 	// checks whether the container has the following
 	ContainerWithPort(ctx context.Context, name, port string) (bool, error)
+
+	// builds the image from the build context directory and tags it
+	BuildImage(ctx context.Context, opts BuildImageOptions) error
+
+	// pushes the tagged image to its registry
+	PushImage(ctx context.Context, image string, auth RegistryAuth) error
+
+	// reads the file at path in the container. the container does not need to be running
+	ReadContainerFile(ctx context.Context, container, path string) ([]byte, error)
+}
+
+type BuildImageOptions struct {
+	// Context is the path to the build context directory.
+	Context string
+	// Dockerfile is the path to the Dockerfile relative to Context.
+	Dockerfile string
+	Tag        string
+}
+
+type RegistryAuth struct {
+	Username string
+	Password string
 }
@@ -1,24 +1,106 @@
 package runtime
 
 import (
+	"archive/tar"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+	"sigs.k8s.io/kind/pkg/cluster"
+)
+
+const (
+	// ProviderEnvVar selects the runtime when the --runtime flag is not set. kind reads it too.
+	ProviderEnvVar = "KIND_EXPERIMENTAL_PROVIDER"
+
+	Docker = "docker"
+	Podman = "podman"
+	Finch  = "finch"
+)
+
+var (
+	// Selected is the runtime set with the --runtime flag. It takes precedence over ProviderEnvVar.
+	Selected string
+	// Supported runtimes in the order they are detected.
+	Supported = []string{Docker, Podman, Finch}
+
+	lookPath = exec.LookPath
 )
 
+// Name returns the name of the runtime to use: the --runtime flag, then ProviderEnvVar, then the first supported runtime found in PATH.
+func Name() string {
+	if Selected != "" {
+		return Selected
+	}
+	if p := os.Getenv(ProviderEnvVar); p != "" {
+		return p
+	}
+	for _, r := range Supported {
+		if _, err := lookPath(r); err == nil {
+			return r
+		}
+	}
+	return Docker
+}
+
 func DetectRuntime() (rt IRuntime, err error) {
-	switch p := os.Getenv("KIND_EXPERIMENTAL_PROVIDER"); p {
-	case "", "docker":
-		return NewDockerRuntime("docker")
-	case "podman":
-		return NewDockerRuntime("podman")
-	case "finch":
+	switch p := Name(); p {
+	case Docker:
+		return NewDockerRuntime(Docker)
+	case Podman:
+		return NewDockerRuntime(Podman)
+	case Finch:
 		return NewFinchRuntime()
 	default:
-		return nil, errors.New("runtime unknown or not supported")
+		return nil, unsupportedError(p)
+	}
+}
+
+// NodeProvider returns the kind option to manage cluster nodes with the runtime.
+func NodeProvider() (cluster.ProviderOption, error) {
+	switch p := Name(); p {
+	case Docker:
+		return cluster.ProviderWithDocker(), nil
+	case Podman:
+		return cluster.ProviderWithPodman(), nil
+	case Finch:
+		return cluster.ProviderWithNerdctl(Finch), nil
+	default:
+		return nil, unsupportedError(p)
 	}
 }
 
+// CheckAvailable returns an error explaining why the runtime cannot be used.
+func CheckAvailable(ctx context.Context) error {
+	name := Name()
+	if !slices.Contains(Supported, name) {
+		return unsupportedError(name)
+	}
+	if _, err := lookPath(name); err != nil {
+		return fmt.Errorf("container runtime %s not found in PATH. select another one with --runtime or %s. supported runtimes: %s",
+			name, ProviderEnvVar, strings.Join(Supported, ", "))
+	}
+	out, err := exec.CommandContext(ctx, name, "info").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("container runtime %s is not reachable, make sure it is running: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func unsupportedError(name string) error {
+	return fmt.Errorf("runtime %s unknown or not supported. supported runtimes: %s", name, strings.Join(Supported, ", "))
+}
+
 func toUint16(portString string) (uint16, error) {
 	// Convert port string to uint16
 	port, err := strconv.ParseUint(portString, 10, 16)
@@ -33,3 +115,110 @@ func toUint16(portString string) (uint16, error) {
 
 	return uint16(port), nil
 }
+
+// dockerignoreFile lists files left out of the build context.
+const dockerignoreFile = ".dockerignore"
+
+// WalkBuildContext calls fn for each file and directory in dir that is sent as build context. Files matching .dockerignore
+// and .git directories are left out. The Dockerfile and .dockerignore are always sent, as the docker CLI does.
+func WalkBuildContext(dir, dockerfile string, fn func(path, rel string, info fs.FileInfo) error) error {
+	pm, err := readDockerignore(dir)
+	if err != nil {
+		return err
+	}
+	keep := map[string]struct{}{
+		dockerignoreFile:           {},
+		filepath.Clean(dockerfile): {},
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if _, ok := keep[rel]; !ok {
+			excluded, mErr := pm.MatchesOrParentMatches(rel)
+			if mErr != nil {
+				return fmt.Errorf("matching %s against %s: %w", rel, dockerignoreFile, mErr)
+			}
+			if excluded {
+				// files in excluded directories may be included again with ! patterns
+				if d.IsDir() && !pm.Exclusions() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(path, filepath.ToSlash(rel), info)
+	})
+}
+
+func readDockerignore(dir string) (*patternmatcher.PatternMatcher, error) {
+	var patterns []string
+	f, err := os.Open(filepath.Join(dir, dockerignoreFile))
+	if err == nil {
+		defer f.Close()
+		patterns, err = ignorefile.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", dockerignoreFile, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("opening %s: %w", dockerignoreFile, err)
+	}
+	pm, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", dockerignoreFile, err)
+	}
+	return pm, nil
+}
+
+// tarDirectory streams the build context in dir as a tar archive, which is the format of build contexts.
+func tarDirectory(dir, dockerfile string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := WalkBuildContext(dir, dockerfile, func(path, rel string, info fs.FileInfo) error {
+			link := ""
+			if info.Mode()&fs.ModeSymlink != 0 {
+				var err error
+				if link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			}
+			hdr, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			hdr.Name = rel
+			if err = tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubLookPath(t *testing.T, found ...string) {
+	orig := lookPath
+	t.Cleanup(func() { lookPath = orig })
+	lookPath = func(file string) (string, error) {
+		for _, f := range found {
+			if f == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", exec.ErrNotFound
+	}
+}
+
+func selectRuntime(t *testing.T, name string) {
+	orig := Selected
+	t.Cleanup(func() { Selected = orig })
+	Selected = name
+}
+
+func TestName(t *testing.T) {
+	t.Setenv(ProviderEnvVar, "")
+	selectRuntime(t, "")
+
+	stubLookPath(t)
+	assert.Equal(t, Docker, Name())
+
+	stubLookPath(t, Podman, Finch)
+	assert.Equal(t, Podman, Name())
+
+	t.Setenv(ProviderEnvVar, Finch)
+	assert.Equal(t, Finch, Name())
+
+	// the flag takes precedence over the environment
+	selectRuntime(t, Docker)
+	assert.Equal(t, Docker, Name())
+}
+
+func TestNodeProvider(t *testing.T) {
+	for _, r := range Supported {
+		selectRuntime(t, r)
+		opt, err := NodeProvider()
+		assert.NoError(t, err, r)
+		assert.NotNil(t, opt, r)
+	}
+
+	selectRuntime(t, "lxc")
+	_, err := NodeProvider()
+	assert.ErrorContains(t, err, "supported runtimes: docker, podman, finch")
+	_, err = DetectRuntime()
+	assert.Error(t, err)
+}
+
+func TestCheckAvailable(t *testing.T) {
+	selectRuntime(t, "lxc")
+	err := CheckAvailable(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported")
+
+	stubLookPath(t)
+	selectRuntime(t, Podman)
+	err = CheckAvailable(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "container runtime podman not found in PATH")
+}
+
+func TestTarDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Dockerfile":        "FROM scratch\n",
+		".dockerignore":     "Dockerfile\n**/*.md\nvendor\n",
+		"main.go":           "package main\n",
+		"README.md":         "# readme\n",
+		"vendor/dep/dep.go": "package dep\n",
+		".git/HEAD":         "ref: refs/heads/main\n",
+		"cmd/app/app.go":    "package app\n",
+		"cmd/app/NOTES.md":  "notes\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	var names []string
+	tr := tar.NewReader(tarDirectory(dir, "Dockerfile"))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+	assert.ElementsMatch(t, []string{".dockerignore", "Dockerfile", "cmd", "cmd/app", "cmd/app/app.go", "main.go"}, names)
+}
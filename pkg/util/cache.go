@@ -0,0 +1,155 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/globals"
+)
+
+const (
+	repoCacheDirName = "repos"
+	// DefaultRepoCacheMaxSize is the default size limit of the repository cache in bytes.
+	DefaultRepoCacheMaxSize int64 = 2 * 1024 * 1024 * 1024
+)
+
+// CacheEntry is a cached repository clone.
+type CacheEntry struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// DefaultRepoCacheDir returns the directory used to cache repository clones across runs.
+func DefaultRepoCacheDir() (string, error) {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user cache directory: %w", err)
+	}
+	return filepath.Join(d, globals.ProjectName, repoCacheDirName), nil
+}
+
+// ListRepoCache returns cached repositories in the given directory ordered from least to most recently used.
+func ListRepoCache(dir string) ([]CacheEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading cache directory %s: %w", dir, err)
+	}
+
+	out := make([]CacheEntry, 0, len(entries))
+	for i := range entries {
+		if !entries[i].IsDir() {
+			continue
+		}
+		info, iErr := entries[i].Info()
+		if iErr != nil {
+			return nil, fmt.Errorf("getting info for %s: %w", entries[i].Name(), iErr)
+		}
+		p := filepath.Join(dir, entries[i].Name())
+		size, sErr := dirSize(p)
+		if sErr != nil {
+			return nil, fmt.Errorf("getting size of %s: %w", p, sErr)
+		}
+		out = append(out, CacheEntry{Path: p, Size: size, ModTime: info.ModTime()})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ModTime.Before(out[j].ModTime)
+	})
+	return out, nil
+}
+
+// PruneRepoCache removes least recently used repositories until the cache is at most maxSize bytes.
+// Repositories in use by other idpbuilder processes are kept. It returns the removed entries.
+func PruneRepoCache(dir string, maxSize int64) ([]CacheEntry, error) {
+	entries, err := ListRepoCache(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for i := range entries {
+		total += entries[i].Size
+	}
+
+	removed := make([]CacheEntry, 0)
+	for i := 0; i < len(entries) && total > maxSize; i++ {
+		ok, rErr := removeUnusedEntry(entries[i].Path)
+		if rErr != nil {
+			return removed, fmt.Errorf("removing %s: %w", entries[i].Path, rErr)
+		}
+		if !ok {
+			continue
+		}
+		total -= entries[i].Size
+		removed = append(removed, entries[i])
+	}
+	return removed, nil
+}
+
+// ClearRepoCache removes all repositories in the cache except those in use by other idpbuilder processes.
+// It returns the entries that were kept.
+func ClearRepoCache(dir string) ([]CacheEntry, error) {
+	entries, err := ListRepoCache(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]CacheEntry, 0)
+	for i := range entries {
+		ok, rErr := removeUnusedEntry(entries[i].Path)
+		if rErr != nil {
+			return kept, fmt.Errorf("removing %s: %w", entries[i].Path, rErr)
+		}
+		if !ok {
+			kept = append(kept, entries[i])
+		}
+	}
+	return kept, nil
+}
+
+// removeUnusedEntry removes the cached repository unless another process has it locked.
+func removeUnusedEntry(dir string) (bool, error) {
+	l, err := TryLockFile(repoLockPath(dir))
+	if err != nil {
+		if errors.Is(err, ErrLocked) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer l.Unlock()
+	// the lock file is left in place. removing it would let a waiting process lock a file nobody else sees.
+	return true, os.RemoveAll(dir)
+}
+
+// markUsed updates modification time of the directory so it is not evicted first.
+func markUsed(dir string) {
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, iErr := d.Info()
+			if iErr != nil {
+				return iErr
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneRepoCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// oldest first
+	names := []string{"a", "b", "c"}
+	for i, n := range names {
+		p := filepath.Join(dir, n)
+		assert.NoError(t, os.MkdirAll(filepath.Join(p, "sub"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(p, "sub", "file"), make([]byte, 100), 0644))
+		ts := now.Add(time.Duration(i-len(names)) * time.Hour)
+		assert.NoError(t, os.Chtimes(p, ts, ts))
+	}
+
+	entries, err := ListRepoCache(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	for i := range entries {
+		assert.Equal(t, filepath.Join(dir, names[i]), entries[i].Path)
+		assert.Equal(t, int64(100), entries[i].Size)
+	}
+
+	removed, err := PruneRepoCache(dir, 150)
+	assert.NoError(t, err)
+	assert.Len(t, removed, 2)
+	assert.Equal(t, filepath.Join(dir, "a"), removed[0].Path)
+	assert.Equal(t, filepath.Join(dir, "b"), removed[1].Path)
+	assert.True(t, Exists(filepath.Join(dir, "c")))
+
+	removed, err = PruneRepoCache(dir, 0)
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+
+	removed, err = PruneRepoCache(filepath.Join(dir, "does-not-exist"), 0)
+	assert.NoError(t, err)
+	assert.Len(t, removed, 0)
+}
+
+func TestPruneRepoCacheSkipsLockedEntries(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []string{"a", "b"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, n), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, n, "file"), make([]byte, 100), 0644))
+	}
+
+	st := NewRepoLock().LoadOrStore("a", filepath.Join(dir, "a"))
+	assert.NoError(t, st.Lock())
+
+	removed, err := PruneRepoCache(dir, 0)
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+	assert.Equal(t, filepath.Join(dir, "b"), removed[0].Path)
+	assert.True(t, Exists(filepath.Join(dir, "a")))
+
+	st.Unlock()
+	removed, err = PruneRepoCache(dir, 0)
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+	assert.False(t, Exists(filepath.Join(dir, "a")))
+}
+
+func TestClearRepoCache(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []string{"a", "b", "empty"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, n), 0755))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a", "file"), make([]byte, 100), 0644))
+
+	st := NewRepoLock().LoadOrStore("a", filepath.Join(dir, "a"))
+	assert.NoError(t, st.Lock())
+	defer st.Unlock()
+
+	kept, err := ClearRepoCache(dir)
+	assert.NoError(t, err)
+	assert.Len(t, kept, 1)
+	assert.Equal(t, filepath.Join(dir, "a"), kept[0].Path)
+	assert.False(t, Exists(filepath.Join(dir, "b")))
+	assert.False(t, Exists(filepath.Join(dir, "empty")))
+}
+
+func TestIsDirInUse(t *testing.T) {
+	dir := t.TempDir()
+	inUse, err := IsDirInUse(dir)
+	assert.NoError(t, err)
+	assert.False(t, inUse)
+
+	l, err := LockDir(dir)
+	assert.NoError(t, err)
+	inUse, err = IsDirInUse(dir)
+	assert.NoError(t, err)
+	assert.True(t, inUse)
+
+	assert.NoError(t, l.Unlock())
+	inUse, err = IsDirInUse(dir)
+	assert.NoError(t, err)
+	assert.False(t, inUse)
+}
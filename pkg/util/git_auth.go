@@ -0,0 +1,206 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"k8s.io/client-go/util/homedir"
+)
+
+const (
+	defaultSSHUser = "git"
+	netrcEnvVar    = "NETRC"
+)
+
+// GitAuthOptions controls how credentials are obtained when cloning remote package repositories.
+// Credentials are resolved at clone time and are never stored in custom resources.
+type GitAuthOptions struct {
+	// SSHPrivateKeyPath is the path to a private key used for SSH URLs. ssh-agent is used when empty.
+	SSHPrivateKeyPath string
+	// NetrcPath is the path to the netrc file used for HTTP(S) URLs. $NETRC or ~/.netrc is used when empty.
+	NetrcPath string
+}
+
+// AuthMethod returns the auth method to use for the given clone url.
+// SSH urls use the configured private key or ssh-agent.
+// HTTP(S) urls use credentials from git credential helpers or netrc. nil is returned when no credentials are found.
+func (o GitAuthOptions) AuthMethod(ctx context.Context, repoUrl string) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(repoUrl)
+	if err != nil {
+		return nil, fmt.Errorf("parsing repository url %s: %w", repoUrl, err)
+	}
+
+	switch ep.Protocol {
+	case "ssh":
+		user := ep.User
+		if user == "" {
+			user = defaultSSHUser
+		}
+		if o.SSHPrivateKeyPath != "" {
+			auth, kErr := gitssh.NewPublicKeysFromFile(user, o.SSHPrivateKeyPath, "")
+			if kErr != nil {
+				return nil, fmt.Errorf("reading ssh private key %s: %w", o.SSHPrivateKeyPath, kErr)
+			}
+			return auth, nil
+		}
+		auth, aErr := gitssh.NewSSHAgentAuth(user)
+		if aErr != nil {
+			return nil, fmt.Errorf("using ssh-agent for %s: %w", repoUrl, aErr)
+		}
+		return auth, nil
+	case "http", "https":
+		// credentials embedded in the url are used by go-git directly.
+		if ep.Password != "" {
+			return nil, nil
+		}
+		auth, hErr := credentialHelperAuth(ctx, ep)
+		if hErr == nil && auth != nil {
+			return auth, nil
+		}
+		auth, nErr := netrcAuth(o.NetrcPath, ep.Host)
+		if nErr != nil || auth == nil {
+			// avoid returning a typed nil. go-git treats any non-nil auth method as credentials.
+			return nil, nErr
+		}
+		return auth, nil
+	default:
+		return nil, nil
+	}
+}
+
+// credentialHelperAuth asks the git credential helpers configured by the user for credentials.
+// It returns nil if git is not installed or no helper returned credentials.
+func credentialHelperAuth(ctx context.Context, ep *transport.Endpoint) (*githttp.BasicAuth, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, nil
+	}
+
+	host := ep.Host
+	if ep.Port != 0 {
+		host = fmt.Sprintf("%s:%d", ep.Host, ep.Port)
+	}
+	in := fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n", ep.Protocol, host, strings.TrimPrefix(ep.Path, "/"))
+	if ep.User != "" {
+		in = fmt.Sprintf("%susername=%s\n", in, ep.User)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("%s\n", in))
+	// never prompt. idpbuilder may be running without a terminal.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never", "GIT_ASKPASS=")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running git credential fill: %w", err)
+	}
+
+	return parseCredentialHelperOutput(&stdout), nil
+}
+
+func parseCredentialHelperOutput(r io.Reader) *githttp.BasicAuth {
+	auth := githttp.BasicAuth{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		k, v, ok := strings.Cut(s.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "username":
+			auth.Username = v
+		case "password":
+			auth.Password = v
+		}
+	}
+	if auth.Password == "" {
+		return nil
+	}
+	return &auth
+}
+
+func netrcAuth(path, host string) (*githttp.BasicAuth, error) {
+	if path == "" {
+		path = os.Getenv(netrcEnvVar)
+	}
+	if path == "" {
+		path = filepath.Join(homedir.HomeDir(), ".netrc")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening netrc file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	login, password, found := parseNetrc(f, host)
+	if !found {
+		return nil, nil
+	}
+	return &githttp.BasicAuth{Username: login, Password: password}, nil
+}
+
+// parseNetrc returns the login and password for the given machine. The default entry is used if no machine matches.
+func parseNetrc(r io.Reader, host string) (string, string, bool) {
+	type entry struct {
+		login, password string
+	}
+
+	var (
+		machine *entry
+		def     *entry
+		current *entry
+	)
+
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanWords)
+	for s.Scan() {
+		switch s.Text() {
+		case "machine":
+			current = nil
+			if s.Scan() && s.Text() == host && machine == nil {
+				machine = &entry{}
+				current = machine
+			}
+		case "default":
+			current = nil
+			if def == nil {
+				def = &entry{}
+				current = def
+			}
+		case "login":
+			if s.Scan() && current != nil {
+				current.login = s.Text()
+			}
+		case "password":
+			if s.Scan() && current != nil {
+				current.password = s.Text()
+			}
+		case "account":
+			s.Scan()
+		case "macdef":
+			// macro definitions end with an empty line. they are not used for authentication.
+			current = nil
+		}
+	}
+
+	if machine != nil {
+		return machine.login, machine.password, true
+	}
+	if def != nil {
+		return def.login, def.password, true
+	}
+	return "", "", false
+}
@@ -8,6 +8,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -16,8 +18,11 @@ import (
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type RepoMap struct {
@@ -30,8 +35,35 @@ func (r *RepoMap) LoadOrStore(repoName, dir string) *RepoState {
 }
 
 type RepoState struct {
-	MU  sync.Mutex
-	Dir string
+	MU   sync.Mutex
+	Dir  string
+	lock *FileLock
+}
+
+// Lock locks the repository clone against other reconcilers in this process and, through a lock file next to Dir,
+// against other idpbuilder processes sharing the directory. e.g. the repository cache.
+func (r *RepoState) Lock() error {
+	r.MU.Lock()
+	l, err := LockFile(repoLockPath(r.Dir))
+	if err != nil {
+		r.MU.Unlock()
+		return err
+	}
+	r.lock = l
+	return nil
+}
+
+// Unlock releases the locks taken by Lock.
+func (r *RepoState) Unlock() {
+	_ = r.lock.Unlock()
+	r.lock = nil
+	r.MU.Unlock()
+}
+
+// repoLockPath returns the lock file guarding the repository clone at dir.
+// It is kept outside of dir so that the clone can be removed while holding the lock.
+func repoLockPath(dir string) string {
+	return filepath.Clean(dir) + ".lock"
 }
 
 func NewRepoLock() *RepoMap {
@@ -108,9 +140,10 @@ func ReadWorktreeFile(wt billy.Filesystem, path string) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
-func CloneRemoteRepoToMemory(ctx context.Context, remote v1alpha1.RemoteRepositorySpec, depth int, insecureSkipTLS bool) (billy.Filesystem, *git.Repository, error) {
+func CloneRemoteRepoToMemory(ctx context.Context, remote v1alpha1.RemoteRepositorySpec, depth int, insecureSkipTLS bool, auth transport.AuthMethod) (billy.Filesystem, *git.Repository, error) {
 	cloneOptions := &git.CloneOptions{
 		URL:               remote.Url,
+		Auth:              auth,
 		Depth:             depth,
 		ShallowSubmodules: true,
 		SingleBranch:      true,
@@ -138,43 +171,54 @@ func CloneRemoteRepoToMemory(ctx context.Context, remote v1alpha1.RemoteReposito
 	return wt, cloned, nil
 }
 
-func CloneRemoteRepoToDir(ctx context.Context, remote v1alpha1.RemoteRepositorySpec, depth int, insecureSkipTLS bool, dir, fallbackUrl string) (billy.Filesystem, *git.Repository, error) {
+// CloneRemoteRepoToDir clones the remote repository to dir. If dir already contains a clone, the latest changes are fetched instead.
+// auth is used for the remote url only and may be nil for anonymous access.
+func CloneRemoteRepoToDir(ctx context.Context, remote v1alpha1.RemoteRepositorySpec, depth int, insecureSkipTLS bool, auth transport.AuthMethod, dir, fallbackUrl string) (billy.Filesystem, *git.Repository, error) {
+	logger := log.FromContext(ctx)
+	offline := false
 	repo, err := git.PlainOpen(dir)
 	if err != nil {
-		if errors.Is(err, git.ErrRepositoryNotExists) {
-			cloneOptions := &git.CloneOptions{
-				URL:               remote.Url,
-				Depth:             depth,
-				ShallowSubmodules: true,
-				Tags:              git.AllTags,
-				InsecureSkipTLS:   insecureSkipTLS,
-			}
-			if remote.CloneSubmodules {
-				cloneOptions.RecurseSubmodules = git.DefaultSubmoduleRecursionDepth
+		if !errors.Is(err, git.ErrRepositoryNotExists) {
+			logger.Info("discarding unreadable clone", "dir", dir, "err", err)
+			if rErr := os.RemoveAll(dir); rErr != nil {
+				return nil, nil, fmt.Errorf("removing unreadable clone at %s: %w", dir, rErr)
 			}
-			repo, err = git.PlainCloneContext(ctx, dir, false, cloneOptions)
-			if err != nil {
-				if fallbackUrl != "" {
-					cloneOptions.URL = fallbackUrl
-					repo, err = git.PlainCloneContext(ctx, dir, false, cloneOptions)
-					if err != nil {
-						return nil, nil, fmt.Errorf("cloning repo with fall back url: %w", err)
-					}
-				} else {
-					return nil, nil, fmt.Errorf("cloning repo: %w", err)
-				}
+		}
+		repo = nil
+	} else {
+		fErr := fetchLatest(ctx, repo, depth, insecureSkipTLS, auth)
+		switch {
+		case fErr == nil:
+		case isNetworkError(fErr):
+			// keep working with what was fetched before, e.g. when offline.
+			logger.Info("cannot reach remote, using cached clone", "repoUrl", remote.Url, "dir", dir, "err", fErr)
+			offline = true
+		case isAccessError(fErr):
+			return nil, nil, fmt.Errorf("fetching latest changes of %s: %w", remote.Url, fErr)
+		default:
+			// the remote may have been re-created since the last run. e.g. gitea in a new cluster.
+			rErr := os.RemoveAll(dir)
+			if rErr != nil {
+				return nil, nil, fmt.Errorf("removing stale clone at %s: %w", dir, rErr)
 			}
-		} else {
-			return nil, nil, fmt.Errorf("opening repo at %s %w", dir, err)
+			repo = nil
+		}
+	}
+
+	if repo == nil {
+		repo, err = cloneToDir(ctx, remote, depth, insecureSkipTLS, auth, dir, fallbackUrl)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
+	markUsed(dir)
 
 	wt, err := repo.Worktree()
 	if err != nil {
 		return nil, nil, fmt.Errorf("getting repo worktree: %w", err)
 	}
 	if remote.Ref != "" {
-		cErr := checkoutCommitOrRef(ctx, wt, remote.Ref)
+		cErr := checkoutCommitOrRef(ctx, wt, remote.Ref, auth, !offline)
 		if cErr != nil {
 			return nil, nil, fmt.Errorf("checkout %s: %w", remote.Ref, cErr)
 		}
@@ -183,6 +227,91 @@ func CloneRemoteRepoToDir(ctx context.Context, remote v1alpha1.RemoteRepositoryS
 	return wt.Filesystem, repo, nil
 }
 
+func cloneToDir(ctx context.Context, remote v1alpha1.RemoteRepositorySpec, depth int, insecureSkipTLS bool, auth transport.AuthMethod, dir, fallbackUrl string) (*git.Repository, error) {
+	cloneOptions := &git.CloneOptions{
+		URL:               remote.Url,
+		Auth:              auth,
+		Depth:             depth,
+		ShallowSubmodules: true,
+		Tags:              git.AllTags,
+		InsecureSkipTLS:   insecureSkipTLS,
+	}
+	if remote.CloneSubmodules {
+		cloneOptions.RecurseSubmodules = git.DefaultSubmoduleRecursionDepth
+	}
+	repo, err := git.PlainCloneContext(ctx, dir, false, cloneOptions)
+	if err == nil {
+		return repo, nil
+	}
+	if fallbackUrl == "" {
+		return nil, fmt.Errorf("cloning repo: %w", err)
+	}
+
+	// failed clone may leave a partial repository behind
+	_ = os.RemoveAll(dir)
+	cloneOptions.URL = fallbackUrl
+	cloneOptions.Auth = nil
+	repo, err = git.PlainCloneContext(ctx, dir, false, cloneOptions)
+	if err != nil {
+		return nil, fmt.Errorf("cloning repo with fall back url: %w", err)
+	}
+	return repo, nil
+}
+
+// isNetworkError reports whether err means the remote could not be reached,
+// as opposed to the remote refusing the request or the clone being out of sync with the remote.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isAccessError reports whether the remote refused the credentials or no longer has the repository.
+func isAccessError(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		errors.Is(err, transport.ErrRepositoryNotFound)
+}
+
+// fetchLatest updates an existing clone so that its worktree matches the remote branch it tracks.
+func fetchLatest(ctx context.Context, repo *git.Repository, depth int, insecureSkipTLS bool, auth transport.AuthMethod) error {
+	err := repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName:      git.DefaultRemoteName,
+		RefSpecs:        []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		Depth:           depth,
+		Auth:            auth,
+		Tags:            git.AllTags,
+		Force:           true,
+		InsecureSkipTLS: insecureSkipTLS,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("fetching: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("getting head: %w", err)
+	}
+	// detached head. checkoutCommitOrRef takes care of it.
+	if !head.Name().IsBranch() {
+		return nil
+	}
+
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, head.Name().Short()), true)
+	if err != nil {
+		return fmt.Errorf("getting remote reference for %s: %w", head.Name().Short(), err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("getting repo worktree: %w", err)
+	}
+	err = wt.Reset(&git.ResetOptions{Commit: remoteRef.Hash(), Mode: git.HardReset})
+	if err != nil {
+		return fmt.Errorf("resetting to %s: %w", remoteRef.Hash(), err)
+	}
+	return wt.Clean(&git.CleanOptions{Dir: true})
+}
+
 func CopyTreeToTree(srcWT, dstWT billy.Filesystem, srcPath, dstPath string) error {
 	files, err := srcWT.ReadDir(srcPath)
 	if err != nil {
@@ -232,7 +361,7 @@ func CopyWTFile(srcWT, dstWT billy.Filesystem, srcFile, dstFile string) error {
 }
 
 // ref could be anything. Check if hash, tag, or branch in that order
-func checkoutCommitOrRef(ctx context.Context, wt *git.Worktree, ref string) error {
+func checkoutCommitOrRef(ctx context.Context, wt *git.Worktree, ref string, auth transport.AuthMethod, pull bool) error {
 	var refName plumbing.ReferenceName
 	opts := &git.CheckoutOptions{
 		Hash: plumbing.NewHash(ref),
@@ -258,9 +387,10 @@ func checkoutCommitOrRef(ctx context.Context, wt *git.Worktree, ref string) erro
 	}
 	pullOpts := &git.PullOptions{
 		RemoteName: "origin",
+		Auth:       auth,
 	}
 
-	if opts.Hash.IsZero() {
+	if pull && opts.Hash.IsZero() {
 		pullOpts.ReferenceName = refName
 		err = wt.PullContext(ctx, pullOpts)
 		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
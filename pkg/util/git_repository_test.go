@@ -6,11 +6,14 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/stretchr/testify/assert"
 )
@@ -25,7 +28,7 @@ func TestCloneRemoteRepoToDir(t *testing.T) {
 	dir, _ := os.MkdirTemp("", "TestCopyToDir")
 	defer os.RemoveAll(dir)
 	// new clone
-	_, _, err := CloneRemoteRepoToDir(context.Background(), spec, 0, false, dir, "")
+	_, _, err := CloneRemoteRepoToDir(context.Background(), spec, 0, false, nil, dir, "")
 	assert.Nil(t, err)
 	testDir, _ := os.MkdirTemp("", "TestCopyToDir")
 	defer os.RemoveAll(testDir)
@@ -41,7 +44,7 @@ func TestCloneRemoteRepoToDir(t *testing.T) {
 	testDir2, _ := os.MkdirTemp("", "TestCopyToDir")
 	defer os.RemoveAll(testDir2)
 
-	_, _, err = CloneRemoteRepoToDir(context.Background(), spec, 0, false, dir, "")
+	_, _, err = CloneRemoteRepoToDir(context.Background(), spec, 0, false, nil, dir, "")
 	repo, err = git.PlainClone(testDir2, false, &git.CloneOptions{URL: dir})
 	assert.Nil(t, err)
 	ref, err = repo.Head()
@@ -60,7 +63,7 @@ func TestCopyTreeToTree(t *testing.T) {
 	}
 
 	dst := memfs.New()
-	src, _, err := CloneRemoteRepoToMemory(context.Background(), spec, 1, false)
+	src, _, err := CloneRemoteRepoToMemory(context.Background(), spec, 1, false, nil)
 	assert.Nil(t, err)
 
 	err = CopyTreeToTree(src, dst, spec.Path, ".")
@@ -114,3 +117,99 @@ func TestGetWorktreeYamlFiles(t *testing.T) {
 	assert.Equal(t, nil, err)
 	assert.Equal(t, 0, len(paths))
 }
+
+func commitFile(t *testing.T, repo *git.Repository, dir, name, content string) {
+	t.Helper()
+	err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	assert.NoError(t, err)
+	wt, err := repo.Worktree()
+	assert.NoError(t, err)
+	_, err = wt.Add(name)
+	assert.NoError(t, err)
+	_, err = wt.Commit(name, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@cnoe.io", When: time.Now()},
+	})
+	assert.NoError(t, err)
+}
+
+func TestCloneRemoteRepoToDirCached(t *testing.T) {
+	srcDir := t.TempDir()
+	src, err := git.PlainInit(srcDir, false)
+	assert.NoError(t, err)
+	commitFile(t, src, srcDir, "file1", "one")
+
+	spec := v1alpha1.RemoteRepositorySpec{Url: srcDir}
+	dir := filepath.Join(t.TempDir(), "clone")
+
+	wt, _, err := CloneRemoteRepoToDir(context.Background(), spec, 1, false, nil, dir, "")
+	assert.NoError(t, err)
+	_, err = wt.Stat("file1")
+	assert.NoError(t, err)
+
+	// new commits in the remote are fetched into the existing clone
+	commitFile(t, src, srcDir, "file2", "two")
+	err = os.WriteFile(filepath.Join(dir, "untracked"), []byte("x"), 0644)
+	assert.NoError(t, err)
+
+	wt, _, err = CloneRemoteRepoToDir(context.Background(), spec, 1, false, nil, dir, "")
+	assert.NoError(t, err)
+	_, err = wt.Stat("file2")
+	assert.NoError(t, err)
+	_, err = wt.Stat("untracked")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// remote re-created with unrelated history
+	assert.NoError(t, os.RemoveAll(srcDir))
+	src, err = git.PlainInit(srcDir, false)
+	assert.NoError(t, err)
+	commitFile(t, src, srcDir, "file3", "three")
+
+	wt, _, err = CloneRemoteRepoToDir(context.Background(), spec, 1, false, nil, dir, "")
+	assert.NoError(t, err)
+	_, err = wt.Stat("file3")
+	assert.NoError(t, err)
+	_, err = wt.Stat("file1")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCloneRemoteRepoToDirUnreachable(t *testing.T) {
+	srcDir := t.TempDir()
+	src, err := git.PlainInit(srcDir, false)
+	assert.NoError(t, err)
+	commitFile(t, src, srcDir, "file1", "one")
+
+	spec := v1alpha1.RemoteRepositorySpec{Url: srcDir}
+	dir := filepath.Join(t.TempDir(), "clone")
+
+	_, repo, err := CloneRemoteRepoToDir(context.Background(), spec, 1, false, nil, dir, "")
+	assert.NoError(t, err)
+
+	// the cached clone is used when the remote cannot be reached
+	cfg, err := repo.Config()
+	assert.NoError(t, err)
+	cfg.Remotes[git.DefaultRemoteName].URLs = []string{"http://127.0.0.1:1/repo.git"}
+	assert.NoError(t, repo.SetConfig(cfg))
+
+	wt, _, err := CloneRemoteRepoToDir(context.Background(), spec, 1, false, nil, dir, "")
+	assert.NoError(t, err)
+	_, err = wt.Stat("file1")
+	assert.NoError(t, err)
+}
+
+func TestCloneRemoteRepoToDirNotFound(t *testing.T) {
+	srcDir := t.TempDir()
+	src, err := git.PlainInit(srcDir, false)
+	assert.NoError(t, err)
+	commitFile(t, src, srcDir, "file1", "one")
+
+	spec := v1alpha1.RemoteRepositorySpec{Url: srcDir}
+	dir := filepath.Join(t.TempDir(), "clone")
+
+	_, _, err = CloneRemoteRepoToDir(context.Background(), spec, 1, false, nil, dir, "")
+	assert.NoError(t, err)
+
+	// a repository that no longer exists is reported instead of served from the cache
+	assert.NoError(t, os.RemoveAll(srcDir))
+	_, _, err = CloneRemoteRepoToDir(context.Background(), spec, 1, false, nil, dir, "")
+	assert.ErrorIs(t, err, transport.ErrRepositoryNotFound)
+}
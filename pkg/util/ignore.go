@@ -0,0 +1,236 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+const (
+	GitIgnoreFileName = ".gitignore"
+	// IgnoreFileName is the idpbuilder specific ignore file. It uses the same format as .gitignore.
+	IgnoreFileName = ".idpbuilderignore"
+
+	// DefaultLargeFileThreshold is the size in bytes above which copied files are reported as large.
+	DefaultLargeFileThreshold int64 = 1024 * 1024
+
+	gitInfoExcludeFile = "info/exclude"
+)
+
+// CopyReport contains files of interest found while copying a directory. Paths are relative to the source directory.
+type CopyReport struct {
+	Ignored    []string
+	LargeFiles []string
+}
+
+type ignoreCopier struct {
+	report             CopyReport
+	srcRoot            string
+	largeFileThreshold int64
+}
+
+// CopyDirectoryWithIgnore copies srcDir to dest skipping files matched by .gitignore and .idpbuilderignore files in srcDir.
+// If srcDir is in a git repository, .gitignore files in its parent directories, the repository exclude file,
+// and the global excludes file are honoured as well. The .git directory is never copied.
+// Files larger than largeFileThreshold are copied and listed in the report. Set it to 0 to disable the check.
+func CopyDirectoryWithIgnore(srcDir, dest string, largeFileThreshold int64) (CopyReport, error) {
+	patterns, domain, err := parentIgnorePatterns(srcDir)
+	if err != nil {
+		return CopyReport{}, err
+	}
+
+	c := &ignoreCopier{
+		srcRoot:            srcDir,
+		largeFileThreshold: largeFileThreshold,
+	}
+	err = c.copyDir(srcDir, dest, domain, patterns)
+	return c.report, err
+}
+
+func (c *ignoreCopier) copyDir(srcDir, dest string, domain []string, patterns []gitignore.Pattern) error {
+	for _, name := range []string{GitIgnoreFileName, IgnoreFileName} {
+		ps, err := readIgnoreFile(filepath.Join(srcDir, name), domain)
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, ps...)
+	}
+	matcher := gitignore.NewMatcher(patterns)
+
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == git.GitDirName {
+			continue
+		}
+
+		sourcePath := filepath.Join(srcDir, entry.Name())
+		destPath := filepath.Join(dest, entry.Name())
+		rel, _ := filepath.Rel(c.srcRoot, sourcePath)
+
+		fileInfo, err := os.Stat(sourcePath)
+		if err != nil {
+			return err
+		}
+
+		entryPath := append(append([]string{}, domain...), entry.Name())
+		if matcher.Match(entryPath, fileInfo.IsDir()) {
+			c.report.Ignored = append(c.report.Ignored, rel)
+			continue
+		}
+
+		switch fileInfo.Mode() & os.ModeType {
+		case os.ModeDir:
+			if err := CreateIfNotExists(destPath, 0755); err != nil {
+				return err
+			}
+			if err := c.copyDir(sourcePath, destPath, entryPath, patterns); err != nil {
+				return err
+			}
+		default:
+			if c.largeFileThreshold > 0 && fileInfo.Size() > c.largeFileThreshold {
+				c.report.LargeFiles = append(c.report.LargeFiles, rel)
+			}
+			if err := Copy(sourcePath, destPath); err != nil {
+				return err
+			}
+		}
+
+		if err := os.Chmod(destPath, fileInfo.Mode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IgnorePatterns returns the patterns CopyDirectoryWithIgnore applies when copying dir, including those of ignore files in subdirectories.
+// Patterns match paths relative to the root of the git worktree dir is in, or to dir if it is not in a git repository.
+func IgnorePatterns(dir string) ([]gitignore.Pattern, error) {
+	patterns, domain, err := parentIgnorePatterns(dir)
+	if err != nil {
+		return nil, err
+	}
+	return collectIgnorePatterns(dir, domain, patterns)
+}
+
+func collectIgnorePatterns(dir string, domain []string, patterns []gitignore.Pattern) ([]gitignore.Pattern, error) {
+	for _, name := range []string{GitIgnoreFileName, IgnoreFileName} {
+		ps, err := readIgnoreFile(filepath.Join(dir, name), domain)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, ps...)
+	}
+	matcher := gitignore.NewMatcher(patterns)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Name() == git.GitDirName {
+			continue
+		}
+		fileInfo, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entryPath := append(append([]string{}, domain...), entry.Name())
+		if !fileInfo.IsDir() || matcher.Match(entryPath, true) {
+			continue
+		}
+		patterns, err = collectIgnorePatterns(filepath.Join(dir, entry.Name()), entryPath, patterns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return patterns, nil
+}
+
+// parentIgnorePatterns returns patterns that apply to dir from outside of it, and the path of dir relative to the root of the worktree.
+func parentIgnorePatterns(dir string) ([]gitignore.Pattern, []string, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		// not in a git repository. only ignore files in dir apply.
+		return nil, nil, nil
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, nil, nil
+	}
+
+	root, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving %s: %w", wt.Filesystem.Root(), err)
+	}
+	absDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving %s: %w", dir, err)
+	}
+	rel, err := filepath.Rel(root, absDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting relative path of %s: %w", dir, err)
+	}
+
+	var patterns []gitignore.Pattern
+	// best effort. a broken global git config should not prevent copying.
+	global, gErr := gitignore.LoadGlobalPatterns(osfs.New("/"))
+	if gErr == nil {
+		patterns = append(patterns, global...)
+	}
+
+	exclude, err := readIgnoreFile(filepath.Join(root, git.GitDirName, gitInfoExcludeFile), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	patterns = append(patterns, exclude...)
+
+	var domain []string
+	if rel != "." {
+		domain = strings.Split(filepath.ToSlash(rel), "/")
+	}
+
+	// .gitignore files from the root down to the parent of dir. files in dir are read while copying.
+	for i := 0; i < len(domain); i++ {
+		p := filepath.Join(append([]string{root}, domain[:i]...)...)
+		ps, rErr := readIgnoreFile(filepath.Join(p, GitIgnoreFileName), domain[:i])
+		if rErr != nil {
+			return nil, nil, rErr
+		}
+		patterns = append(patterns, ps...)
+	}
+	return patterns, domain, nil
+}
+
+func readIgnoreFile(path string, domain []string) ([]gitignore.Pattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var ps []gitignore.Pattern
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "#") || len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		ps = append(ps, gitignore.ParsePattern(line, domain))
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return ps, nil
+}
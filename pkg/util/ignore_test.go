@@ -0,0 +1,79 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+}
+
+func TestCopyDirectoryWithIgnore(t *testing.T) {
+	// avoid picking up global excludes from the environment running the test.
+	t.Setenv("HOME", t.TempDir())
+
+	root := t.TempDir()
+	_, err := git.PlainInit(root, false)
+	assert.NoError(t, err)
+
+	writeTestFiles(t, root, map[string]string{
+		".gitignore":                        "*.swp\n/build\n",
+		".git/info/exclude":                 "local-only\n",
+		"pkg/.gitignore":                    "node_modules/\n",
+		"pkg/.idpbuilderignore":             "# secrets\n.env\n",
+		"pkg/app.yaml":                      "app",
+		"pkg/app.yaml.swp":                  "swap",
+		"pkg/.env":                          "TOKEN=secret",
+		"pkg/local-only":                    "x",
+		"pkg/build/keep.yaml":               "anchored to the repository root",
+		"pkg/node_modules/dep/index.js":     "dep",
+		"pkg/sub/.env":                      "TOKEN=secret",
+		"pkg/sub/.gitignore":                "*.tmp\n",
+		"pkg/sub/keep.yaml":                 "keep",
+		"pkg/sub/scratch.tmp":               "tmp",
+		"pkg/large.yaml":                    string(make([]byte, 2048)),
+		"build/artifact":                    "not copied because it is outside of the source",
+		"pkg/sub/.git/should-not-be-copied": "x",
+		"pkg/sub/nested/deep/values.yaml":   "values",
+	})
+
+	dst := t.TempDir()
+	report, err := CopyDirectoryWithIgnore(filepath.Join(root, "pkg"), dst, 1024)
+	assert.NoError(t, err)
+
+	for _, f := range []string{"app.yaml", ".gitignore", ".idpbuilderignore", "build/keep.yaml", "sub/keep.yaml", "sub/nested/deep/values.yaml", "large.yaml"} {
+		assert.True(t, Exists(filepath.Join(dst, f)), f)
+	}
+	for _, f := range []string{"app.yaml.swp", ".env", "local-only", "node_modules", "sub/.env", "sub/scratch.tmp", "sub/.git"} {
+		assert.False(t, Exists(filepath.Join(dst, f)), f)
+	}
+
+	sort.Strings(report.Ignored)
+	assert.Equal(t, []string{".env", "app.yaml.swp", "local-only", "node_modules", "sub/.env", "sub/scratch.tmp"}, report.Ignored)
+	assert.Equal(t, []string{"large.yaml"}, report.LargeFiles)
+
+	// ignore files apply without a git repository too
+	noGit := t.TempDir()
+	writeTestFiles(t, noGit, map[string]string{
+		".idpbuilderignore": "*.key\n",
+		"tls.key":           "key",
+		"app.yaml":          "app",
+	})
+	dst = t.TempDir()
+	report, err = CopyDirectoryWithIgnore(noGit, dst, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tls.key"}, report.Ignored)
+	assert.True(t, Exists(filepath.Join(dst, "app.yaml")))
+	assert.Empty(t, report.LargeFiles)
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// ErrLocked is returned by TryLockFile when another process holds the lock.
+var ErrLocked = errors.New("locked by another process")
+
+// FileLock is an advisory lock shared by idpbuilder processes on the same host.
+// The lock is released when the process exits, so crashed runs do not leave it behind.
+type FileLock struct {
+	f *os.File
+}
+
+// LockFile takes an exclusive lock on the file at path, waiting for other processes to release it.
+// The file is created if it does not exist.
+func LockFile(path string) (*FileLock, error) {
+	return lockFile(path, syscall.LOCK_EX)
+}
+
+// TryLockFile takes an exclusive lock on the file at path. It returns ErrLocked if another process holds it.
+func TryLockFile(path string) (*FileLock, error) {
+	return lockFile(path, syscall.LOCK_EX|syscall.LOCK_NB)
+}
+
+func lockFile(path string, how int) (*FileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file %s: %w", path, err)
+	}
+	err = syscall.Flock(int(f.Fd()), how)
+	if err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	// closing the file releases the lock
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// dirLockName is the lock file held in a temporary directory while a process uses it.
+// It does not end in .lock, so it cannot be the lock file of a repository cloned to the directory.
+const dirLockName = ".in-use"
+
+// LockDir marks dir as in use by this process until the lock is released.
+func LockDir(dir string) (*FileLock, error) {
+	return LockFile(filepath.Join(dir, dirLockName))
+}
+
+// IsDirInUse reports whether another process holds the lock taken by LockDir on dir.
+func IsDirInUse(dir string) (bool, error) {
+	path := filepath.Join(dir, dirLockName)
+	if !Exists(path) {
+		return false, nil
+	}
+	l, err := TryLockFile(path)
+	if err != nil {
+		if errors.Is(err, ErrLocked) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, l.Unlock()
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// SourceTypePredicate filters events to objects whose source type is one of types.
+// All objects pass when types is empty.
+func SourceTypePredicate(types []string, sourceType func(client.Object) string) predicate.Predicate {
+	allowed := make(map[string]struct{}, len(types))
+	for i := range types {
+		allowed[types[i]] = struct{}{}
+	}
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		if len(allowed) == 0 {
+			return true
+		}
+		_, ok := allowed[sourceType(obj)]
+		return ok
+	})
+}
+
+// ValidateSourceTypes returns an error if types contains a value that is not a known source type.
+func ValidateSourceTypes(types []string) error {
+	for i := range types {
+		switch types[i] {
+		case v1alpha1.SourceTypeLocal, v1alpha1.SourceTypeRemote, v1alpha1.SourceTypeEmbedded:
+		default:
+			return fmt.Errorf("source type %s is not supported. must be one of %s, %s, %s",
+				types[i], v1alpha1.SourceTypeLocal, v1alpha1.SourceTypeRemote, v1alpha1.SourceTypeEmbedded)
+		}
+	}
+	return nil
+}
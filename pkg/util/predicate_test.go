@@ -0,0 +1,32 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestSourceTypePredicate(t *testing.T) {
+	repo := func(sourceType string) *v1alpha1.GitRepository {
+		return &v1alpha1.GitRepository{
+			Spec: v1alpha1.GitRepositorySpec{Source: v1alpha1.GitRepositorySource{Type: sourceType}},
+		}
+	}
+	sourceType := func(obj client.Object) string {
+		return obj.(*v1alpha1.GitRepository).Spec.Source.Type
+	}
+
+	p := SourceTypePredicate([]string{v1alpha1.SourceTypeRemote}, sourceType)
+	assert.True(t, p.Create(event.CreateEvent{Object: repo(v1alpha1.SourceTypeRemote)}))
+	assert.False(t, p.Create(event.CreateEvent{Object: repo(v1alpha1.SourceTypeLocal)}))
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: repo(v1alpha1.SourceTypeEmbedded), ObjectNew: repo(v1alpha1.SourceTypeEmbedded)}))
+
+	p = SourceTypePredicate(nil, sourceType)
+	assert.True(t, p.Create(event.CreateEvent{Object: repo(v1alpha1.SourceTypeLocal)}))
+
+	assert.NoError(t, ValidateSourceTypes([]string{v1alpha1.SourceTypeLocal, v1alpha1.SourceTypeEmbedded}))
+	assert.Error(t, ValidateSourceTypes([]string{"git"}))
+}
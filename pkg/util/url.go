@@ -19,12 +19,17 @@ const (
 	RepoUrlDelimiter = "//"
 	SCPDelimiter     = ":"
 	UserDelimiter    = "@"
+	GitPrefix        = "git::"
+
+	// used when the url does not specify a scheme. e.g. github.com/owner/repo//path
+	defaultScheme = "https"
 
 	defaultTimeout        = time.Second * 27
 	defaultCloneSubmodule = true
 
 	errMsgUrlUnsupported = "url must have // after the repository url. example: https://github.com/kubernetes-sigs/kustomize//examples"
 	errMsgUrlColon       = "first path segment in URL cannot contain colon"
+	errMsgUrlNoHost      = "url must contain a host name. example: github.com/kubernetes-sigs/kustomize//examples"
 )
 
 type KustomizeRemote struct {
@@ -122,10 +127,12 @@ func (g *KustomizeRemote) parseQuery() error {
 }
 
 func (g *KustomizeRemote) parse() error {
-	parsed, err := url.Parse(g.raw)
+	// kustomize allows go-getter style prefix. e.g. git::https://github.com/owner/repo//path
+	raw := strings.TrimPrefix(g.raw, GitPrefix)
+	parsed, err := url.Parse(raw)
 	if err != nil {
 		if strings.Contains(err.Error(), errMsgUrlColon) {
-			return g.parseSCPStyle()
+			return g.parseSCPStyle(raw)
 		}
 		return err
 	}
@@ -136,37 +143,47 @@ func (g *KustomizeRemote) parse() error {
 		g.Password = p
 	}
 
+	path := parsed.Path
+	if g.Scheme == "" && g.Host == "" {
+		// example github.com/owner/repo//path
+		host, rest, _ := strings.Cut(path, "/")
+		if !isHostName(host) {
+			return fmt.Errorf(errMsgUrlNoHost)
+		}
+		g.Scheme, g.Host, path = defaultScheme, host, rest
+	}
+
 	err = g.parseQuery()
 	if err != nil {
 		return fmt.Errorf("parsing query parameters in package url: %s: %w", g.raw, err)
 	}
 
-	return g.parsePath(parsed.Path)
+	return g.parsePath(path)
 }
 
-func (g *KustomizeRemote) parseSCPStyle() error {
+func (g *KustomizeRemote) parseSCPStyle(raw string) error {
 	// example git@github.com:owner/repo
-	cIndex := strings.Index(g.raw, SCPDelimiter)
+	cIndex := strings.Index(raw, SCPDelimiter)
 	if cIndex == -1 {
 		return fmt.Errorf("not a valid SCP style URL")
 	}
 
-	uIndex := strings.Index(g.raw[:cIndex], UserDelimiter)
+	uIndex := strings.Index(raw[:cIndex], UserDelimiter)
 	if uIndex != -1 {
-		g.User = g.raw[:uIndex]
+		g.User = raw[:uIndex]
 	}
-	g.Host = g.raw[uIndex+1 : cIndex]
+	g.Host = raw[uIndex+1 : cIndex]
 	err := g.parseQuery()
 	if err != nil {
 		return fmt.Errorf("parsing query parameters in package url: %s: %w", g.raw, err)
 	}
 
-	pathEnd := len(g.raw)
-	qIndex := strings.Index(g.raw, "?")
+	pathEnd := len(raw)
+	qIndex := strings.Index(raw, "?")
 	if qIndex != -1 {
 		pathEnd = qIndex
 	}
-	return g.parsePath(g.raw[cIndex+1 : pathEnd])
+	return g.parsePath(raw[cIndex+1 : pathEnd])
 }
 
 func (g *KustomizeRemote) parsePath(path string) error {
@@ -181,6 +198,11 @@ func (g *KustomizeRemote) parsePath(path string) error {
 	return nil
 }
 
+// host names must contain a dot to be distinguished from local directories. e.g. github.com
+func isHostName(s string) bool {
+	return strings.Contains(s, ".") && !strings.HasPrefix(s, ".") && !strings.HasSuffix(s, ".")
+}
+
 func NewKustomizeRemote(uri string) (*KustomizeRemote, error) {
 	r := &KustomizeRemote{raw: uri}
 	return r, r.parse()
@@ -60,6 +60,41 @@ func TestURLParse(t *testing.T) {
 				timeout:   1 * time.Second,
 			},
 		},
+		{
+			input: "github.com/owner/repo//examples/dev?ref=main",
+			expect: expect{
+				cloneUrl:  "https://github.com/owner/repo",
+				path:      "examples/dev",
+				ref:       "main",
+				submodule: true,
+				timeout:   defaultTimeout,
+			},
+		},
+		{
+			input: "git::https://gitlab.com/group/sub/repo//pkg",
+			expect: expect{
+				cloneUrl:  "https://gitlab.com/group/sub/repo",
+				path:      "pkg",
+				submodule: true,
+				timeout:   defaultTimeout,
+			},
+		},
+		{
+			input: "ssh://git@bitbucket.org:2222/owner/repo//examples?ref=v1",
+			expect: expect{
+				cloneUrl:  "ssh://git@bitbucket.org:2222/owner/repo",
+				path:      "examples",
+				ref:       "v1",
+				submodule: true,
+				timeout:   defaultTimeout,
+			},
+		},
+		{
+			input: "examples/basic//package1",
+			expect: expect{
+				err: true,
+			},
+		},
 	}
 
 	for i := range cases {
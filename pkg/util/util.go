@@ -84,7 +84,15 @@ func ApplyAnnotation(ctx context.Context, kubeClient client.Client, obj client.O
 }
 
 func GeneratePassword() (string, error) {
-	passChars := make([]string, passwordLength)
+	return GeneratePasswordWithLength(passwordLength)
+}
+
+// GeneratePasswordWithLength returns a random password of the given length. It contains at least three digits and three special characters.
+func GeneratePasswordWithLength(length int) (string, error) {
+	if length < numDigits+numSpecialChars {
+		return "", fmt.Errorf("password length must be at least %d", numDigits+numSpecialChars)
+	}
+	passChars := make([]string, 0, length)
 	validChars := fmt.Sprintf("%s%s%s", chars, digits, specialChars)
 
 	for i := 0; i < numSpecialChars; i++ {
@@ -103,7 +111,7 @@ func GeneratePassword() (string, error) {
 		passChars = append(passChars, c)
 	}
 
-	for i := 0; i < passwordLength-numDigits-numSpecialChars; i++ {
+	for i := 0; i < length-numDigits-numSpecialChars; i++ {
 		c, err := getRandElement(validChars)
 		if err != nil {
 			return "", err
@@ -42,3 +42,18 @@ func TestGeneratePassword(t *testing.T) {
 		}
 	}
 }
+
+func TestGeneratePasswordWithLength(t *testing.T) {
+	p, err := GeneratePasswordWithLength(32)
+	if err != nil {
+		t.Fatalf("error generating password: %v", err)
+	}
+	if len(p) != 32 {
+		t.Fatalf("password length incorrect")
+	}
+
+	_, err = GeneratePasswordWithLength(numDigits + numSpecialChars - 1)
+	if err == nil {
+		t.Fatalf("expected error for short password")
+	}
+}